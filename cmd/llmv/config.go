@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aj-seven/llmverse/internal/config"
+)
+
+const configUsage = "usage: llmv config <show [--secrets] | validate>"
+
+// runConfigCommand handles `llmv config <subcommand>`. It loads the
+// config itself, so validate can report problems that stop llmv from
+// starting without changing anything on disk.
+func runConfigCommand(opts config.Options, args []string) error {
+	if len(args) == 0 {
+		return errors.New(configUsage)
+	}
+
+	switch args[0] {
+	case "show":
+		showSecrets := len(args) == 2 && args[1] == "--secrets"
+		if len(args) > 2 || (len(args) == 2 && !showSecrets) {
+			return errors.New(configUsage)
+		}
+		cfg, err := config.Load(opts)
+		if err != nil {
+			return err
+		}
+		return showConfig(cfg, showSecrets)
+
+	case "validate":
+		// Validating must not create, migrate or back up the file.
+		opts.ReadOnly = true
+		cfg, err := config.Load(opts)
+		if err != nil {
+			return err
+		}
+		switch v := cfg.FileVersion(); {
+		case v == 0:
+			fmt.Printf("%s: OK (no file; llmv creates it with the defaults on the next start)\n", cfg.Path())
+		case v < config.CurrentVersion:
+			fmt.Printf("%s: OK (version %d; migration pending: llmv migrates it to version %d on the next start)\n",
+				cfg.Path(), v, config.CurrentVersion)
+		default:
+			fmt.Printf("%s: OK (version %d)\n", cfg.Path(), v)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown config command: %s", args[0])
+	}
+}
+
+// showConfig prints the effective config, one key per line, with the
+// layer each value came from. Header values are hidden unless showSecrets
+// is set.
+func showConfig(cfg *config.Config, showSecrets bool) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, s := range cfg.Settings(showSecrets) {
+		value := s.Value
+		if value == "" {
+			value = `""`
+		}
+		value = strings.ReplaceAll(value, "\n", `\n`)
+		fmt.Fprintf(w, "%s\t%s\t# %s\n", s.Key, value, s.Source)
+	}
+	return w.Flush()
+}
+
+// setFlags collects repeated --set key=value flags.
+type setFlags map[string]string
+
+func (s setFlags) String() string { return "" }
+
+func (s setFlags) Set(v string) error {
+	key, value, ok := strings.Cut(v, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", v)
+	}
+	s[key] = value
+	return nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/aj-seven/llmverse/internal/config"
+	"github.com/aj-seven/llmverse/internal/history"
+	"github.com/aj-seven/llmverse/internal/ui"
+
+	"golang.org/x/term"
+)
+
+const maxPassphraseAttempts = 3
+
+// runHistoryCommand handles `llmv history <subcommand>`.
+func runHistoryCommand(cfg *config.Config, fs *history.FileStorage, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: llmv history <encrypt|decrypt>")
+	}
+
+	switch args[0] {
+	case "encrypt":
+		return encryptHistory(cfg, fs)
+	case "decrypt":
+		return decryptHistory(cfg, fs)
+	default:
+		return fmt.Errorf("unknown history command: %s", args[0])
+	}
+}
+
+// encryptHistory encrypts every plain history in place and turns on
+// encryption in the config.
+func encryptHistory(cfg *config.Config, fs *history.FileStorage) error {
+	pass, err := cliPassphrase(!history.IsEncrypted(fs.Dir()))
+	if err != nil {
+		return err
+	}
+
+	key, err := history.DeriveKey(fs.Dir(), pass)
+	if err != nil {
+		return err
+	}
+	enc, err := history.NewEncryptedStorage(fs, key)
+	if err != nil {
+		return err
+	}
+
+	histories, err := fs.GetHistories()
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, h := range histories {
+		if h.Sealed != "" {
+			if len(h.Tags) == 0 && h.Folder == "" && h.Persona == "" && h.Collection == "" {
+				continue
+			}
+			// Sealed before tags, folders, personas and collections
+			// were; seal those too.
+			if h, err = enc.GetHistory(h.ID); err != nil {
+				return err
+			}
+		}
+		if err := enc.SaveHistory(h, true); err != nil {
+			return fmt.Errorf("failed to encrypt history %s: %w", h.ID, err)
+		}
+		count++
+	}
+
+	if err := cfg.Update(func(c *config.Config) {
+		c.Storage.History.Encrypt = true
+	}); err != nil {
+		return err
+	}
+
+	fmt.Printf("Encrypted %d chat(s) in %s\n", count, fs.Dir())
+	fmt.Println("Model names, hosts and timestamps of chats stay readable.")
+	return nil
+}
+
+// decryptHistory decrypts every history in place and turns off
+// encryption in the config.
+func decryptHistory(cfg *config.Config, fs *history.FileStorage) error {
+	if !history.IsEncrypted(fs.Dir()) {
+		return errors.New("history is not encrypted")
+	}
+
+	pass, err := cliPassphrase(false)
+	if err != nil {
+		return err
+	}
+
+	key, err := history.DeriveKey(fs.Dir(), pass)
+	if err != nil {
+		return err
+	}
+	enc, err := history.NewEncryptedStorage(fs, key)
+	if err != nil {
+		return err
+	}
+
+	histories, err := enc.GetHistories()
+	if err != nil {
+		return err
+	}
+	// The key is removed below, so chats it cannot open would be lost.
+	if unreadable := enc.Unreadable(); len(unreadable) > 0 {
+		return fmt.Errorf("cannot decrypt %d chat(s), nothing was changed:\n%w",
+			len(unreadable), errors.Join(unreadable...))
+	}
+
+	for _, h := range histories {
+		if err := fs.SaveHistory(h, true); err != nil {
+			return fmt.Errorf("failed to decrypt history %s: %w", h.ID, err)
+		}
+	}
+
+	if err := history.RemoveKey(fs.Dir()); err != nil {
+		return err
+	}
+	if err := cfg.Update(func(c *config.Config) {
+		c.Storage.History.Encrypt = false
+	}); err != nil {
+		return err
+	}
+
+	fmt.Printf("Decrypted %d chat(s) in %s\n", len(histories), fs.Dir())
+	return nil
+}
+
+// unlockStorage wraps fs in an EncryptedStorage, taking the passphrase
+// from the environment or prompting for it once per session.
+func unlockStorage(fs *history.FileStorage) (history.Storage, error) {
+	if pass := os.Getenv(history.PassphraseEnv); pass != "" {
+		key, err := history.DeriveKey(fs.Dir(), pass)
+		if err != nil {
+			return nil, err
+		}
+		return history.NewEncryptedStorage(fs, key)
+	}
+
+	isNew := !history.IsEncrypted(fs.Dir())
+	errMsg := ""
+	for attempt := 0; attempt < maxPassphraseAttempts; attempt++ {
+		pass, err := ui.PromptPassphrase(errMsg, isNew)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := history.DeriveKey(fs.Dir(), pass)
+		if errors.Is(err, history.ErrBadPassphrase) {
+			errMsg = "Incorrect passphrase, try again."
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return history.NewEncryptedStorage(fs, key)
+	}
+
+	return nil, history.ErrBadPassphrase
+}
+
+// cliPassphrase reads the passphrase from the environment, falling back
+// to an interactive terminal prompt.
+func cliPassphrase(confirm bool) (string, error) {
+	if pass := os.Getenv(history.PassphraseEnv); pass != "" {
+		return pass, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("set %s to provide the history passphrase", history.PassphraseEnv)
+	}
+
+	fmt.Fprint(os.Stderr, "History passphrase: ")
+	pass, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+
+	if confirm {
+		fmt.Fprint(os.Stderr, "Repeat passphrase: ")
+		again, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		if string(again) != string(pass) {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+
+	if len(pass) == 0 {
+		return "", errors.New("empty passphrase")
+	}
+	return string(pass), nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aj-seven/llmverse/internal/config"
+	"github.com/aj-seven/llmverse/internal/history"
+	"github.com/aj-seven/llmverse/pkg/chat"
+)
+
+func TestEncryptDecryptHistory(t *testing.T) {
+	cfg, fs := setupHistory(t, "one", "two")
+
+	if err := encryptHistory(cfg, fs); err != nil {
+		t.Fatalf("encryptHistory() error = %v", err)
+	}
+	assertStored(t, fs, true)
+	assertEncryptSetting(t, cfg, true)
+
+	// Encrypting again leaves the sealed chats alone.
+	if err := encryptHistory(cfg, fs); err != nil {
+		t.Fatalf("encryptHistory() again error = %v", err)
+	}
+	assertStored(t, fs, true)
+
+	if err := decryptHistory(cfg, fs); err != nil {
+		t.Fatalf("decryptHistory() error = %v", err)
+	}
+	assertStored(t, fs, false)
+	assertEncryptSetting(t, cfg, false)
+	if history.IsEncrypted(fs.Dir()) {
+		t.Error("key file left after decrypting")
+	}
+}
+
+func TestDecryptHistoryKeepsUnreadableChats(t *testing.T) {
+	cfg, fs := setupHistory(t, "good")
+	if err := encryptHistory(cfg, fs); err != nil {
+		t.Fatal(err)
+	}
+
+	broken := history.History{
+		ID:        "broken",
+		Sealed:    "not base64!",
+		CreatedAt: time.Now(),
+	}
+	if err := fs.SaveHistory(broken, true); err != nil {
+		t.Fatal(err)
+	}
+
+	err := decryptHistory(cfg, fs)
+	if err == nil || !strings.Contains(err.Error(), "cannot decrypt 1 chat(s)") {
+		t.Fatalf("decryptHistory() error = %v, want it to refuse", err)
+	}
+	assertStored(t, fs, true)
+	if !history.IsEncrypted(fs.Dir()) {
+		t.Error("key file removed although a chat could not be decrypted")
+	}
+}
+
+func TestDecryptHistoryWrongPassphrase(t *testing.T) {
+	cfg, fs := setupHistory(t, "chat")
+	if err := encryptHistory(cfg, fs); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(history.PassphraseEnv, "wrong")
+	if err := decryptHistory(cfg, fs); !errors.Is(err, history.ErrBadPassphrase) {
+		t.Fatalf("decryptHistory() error = %v, want ErrBadPassphrase", err)
+	}
+	assertStored(t, fs, true)
+}
+
+// setupHistory creates a config and plain chats with the given IDs in a
+// temporary home directory, and sets the passphrase.
+func setupHistory(t *testing.T, ids ...string) (*config.Config, *history.FileStorage) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("OLLAMA_HOST", "")
+	t.Setenv(history.PassphraseEnv, "passphrase")
+
+	path := filepath.Join(home, "config.yaml")
+	if err := os.WriteFile(path, []byte("version: 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(config.Options{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs, err := history.NewFileStorage(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range ids {
+		if err := fs.SaveHistory(history.History{
+			ID:       id,
+			Title:    "title of " + id,
+			Messages: []chat.Message{{Role: "user", Content: "hello"}},
+			Tags:     []string{"tag"},
+		}, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cfg, fs
+}
+
+// assertStored checks whether every stored chat is sealed.
+func assertStored(t *testing.T, fs *history.FileStorage, sealed bool) {
+	t.Helper()
+	histories, err := fs.GetHistories()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range histories {
+		if (h.Sealed != "") != sealed {
+			t.Errorf("chat %s sealed = %v, want %v", h.ID, h.Sealed != "", sealed)
+		}
+		if !sealed && (h.Title != "title of "+h.ID || len(h.Tags) != 1) {
+			t.Errorf("chat %s decrypted to %+v", h.ID, h)
+		}
+	}
+}
+
+func assertEncryptSetting(t *testing.T, cfg *config.Config, want bool) {
+	t.Helper()
+	reloaded, err := config.Load(config.Options{Path: cfg.Path()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.Storage.History.Encrypt != want {
+		t.Errorf("storage.history.encrypt = %v, want %v", reloaded.Storage.History.Encrypt, want)
+	}
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"github.com/aj-seven/llmverse/internal/config"
 	"github.com/aj-seven/llmverse/internal/history"
 	aihub "github.com/aj-seven/llmverse/internal/providers/ollama"
+	"github.com/aj-seven/llmverse/internal/state"
 	"github.com/aj-seven/llmverse/internal/ui"
 	"os"
 
@@ -12,37 +14,118 @@ import (
 )
 
 func main() {
-	var host string
-	flag.StringVar(&host, "host", "", "Ollama host address")
+	if err := run(); err != nil {
+		exit(err)
+	}
+}
+
+// run starts llmv. Errors are returned rather than exiting on the spot,
+// so deferred cleanup such as releasing the chat leases still happens.
+func run() error {
+	var (
+		host       string
+		configPath string
+		resume     string
+		cont       bool
+		settings   = setFlags{}
+	)
+	flag.StringVar(&host, "host", "", "Ollama host address or name of a configured host")
+	flag.StringVar(&configPath, "config", "", "Config file to use instead of ~/.llmv/config.yaml")
+	flag.Var(settings, "set", "Override a config value, e.g. --set rag.top_k=8 (repeatable)")
+	flag.BoolVar(&cont, "continue", false, "Reopen the chats of the last session")
+	flag.StringVar(&resume, "resume", "", "Open the chat with this ID (or ID prefix)")
 	flag.Parse()
 
 	// Load configuration
-	cfg, err := config.LoadOrNew(host)
+	if host != "" {
+		settings["host"] = host
+	}
+	opts := config.Options{Path: configPath, Flags: settings}
+
+	// The config command loads the config itself to report its problems.
+	if flag.Arg(0) == "config" {
+		return runConfigCommand(opts, flag.Args()[1:])
+	}
+
+	cfg, err := config.Load(opts)
 	if err != nil {
-		exit(err)
+		return err
 	}
 
 	// Initialize history storage
 	fileStorage, err := history.NewFileStorage(cfg)
 	if err != nil {
-		exit(err)
+		return err
+	}
+
+	// Subcommands
+	if args := flag.Args(); len(args) > 0 {
+		switch args[0] {
+		case "history":
+			return runHistoryCommand(cfg, fileStorage, args[1:])
+		case "rag":
+			return runRagCommand(cfg, args[1:])
+		default:
+			return errors.New("unknown command: " + args[0])
+		}
+	}
+
+	// A directory with sealed chats needs the passphrase even if the
+	// setting is off or overridden; without it they would be saved back
+	// empty.
+	var storage history.Storage = fileStorage
+	if cfg.Storage.History.Encrypt || history.IsEncrypted(fileStorage.Dir()) {
+		storage, err = unlockStorage(fileStorage)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Initialize history manager
-	historyManager := history.NewManager(fileStorage)
+	historyManager := history.NewManager(storage)
 	defer historyManager.Close()
 
-	// Load available models
-	models, err := aihub.GetModelsDetailed(cfg)
-	if err != nil {
-		exit(err)
+	// Enforce retention rules before the history is shown
+	retention := cfg.Storage.History.Retention
+	if _, err := historyManager.ApplyRetention(history.RetentionPolicy{
+		TrashDays:          retention.TrashDays,
+		ArchiveAfterMonths: retention.ArchiveAfterMonths,
+		MaxSizeBytes:       int64(retention.MaxSizeMB) * 1024 * 1024,
+	}); err != nil {
+		return err
 	}
-	if len(models) == 0 {
-		exitString("no Ollama models found (is Ollama running?)")
+	if enc, ok := storage.(*history.EncryptedStorage); ok {
+		for _, err := range enc.Unreadable() {
+			os.Stderr.WriteString("warning: skipping chat: " + err.Error() + "\n")
+		}
+	}
+
+	// Load the models of all hosts. Without a reachable server llmv
+	// starts offline and keeps retrying in the background.
+	hosts := aihub.GetModelsAllHosts(cfg)
+
+	// Load UI state such as favourite models. It is only a cache of UI
+	// choices, so llmv starts without it rather than not at all.
+	appState, err := state.Open()
+	if err != nil {
+		os.Stderr.WriteString("warning: " + err.Error() + "; starting with empty UI state\n")
+		if appState == nil {
+			appState = state.NewMemory()
+		}
 	}
 
 	// Create application model
-	m := ui.New(models, historyManager, cfg)
+	m := ui.New(hosts, historyManager, appState, cfg)
+
+	// Reopen earlier chats
+	switch {
+	case resume != "":
+		if err := m.Resume(resume); err != nil {
+			return err
+		}
+	case cont || cfg.Session.Restore:
+		m.Continue()
+	}
 
 	// Start Bubble Tea program
 	p := tea.NewProgram(
@@ -52,8 +135,14 @@ func main() {
 	)
 
 	if err := p.Start(); err != nil {
-		exit(err)
+		return err
+	}
+
+	// Not fatal: exiting here would skip saving the open chats.
+	if err := m.SaveSession(); err != nil {
+		os.Stderr.WriteString("failed to save session: " + err.Error() + "\n")
 	}
+	return nil
 }
 
 // Helper functions
@@ -62,8 +151,3 @@ func exit(err error) {
 	os.Stderr.WriteString(err.Error() + "\n")
 	os.Exit(1)
 }
-
-func exitString(msg string) {
-	os.Stderr.WriteString(msg + "\n")
-	os.Exit(1)
-}
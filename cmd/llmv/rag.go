@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aj-seven/llmverse/internal/config"
+	aihub "github.com/aj-seven/llmverse/internal/providers/ollama"
+	"github.com/aj-seven/llmverse/internal/rag"
+)
+
+const ragUsage = "usage: llmv rag <index NAME DIR | list | rm NAME | search NAME QUERY>"
+
+// runRagCommand handles `llmv rag <subcommand>`.
+func runRagCommand(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(ragUsage)
+	}
+
+	switch args[0] {
+	case "index":
+		if len(args) != 3 {
+			return errors.New(ragUsage)
+		}
+		return indexCollection(cfg, args[1], args[2])
+
+	case "list", "ls":
+		return listCollections()
+
+	case "rm":
+		if len(args) != 2 {
+			return errors.New(ragUsage)
+		}
+		if err := rag.Remove(args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("Removed collection %s\n", args[1])
+		return nil
+
+	case "search":
+		if len(args) < 3 {
+			return errors.New(ragUsage)
+		}
+		return searchCollection(cfg, args[1], strings.Join(args[2:], " "))
+
+	default:
+		return fmt.Errorf("unknown rag command: %s", args[0])
+	}
+}
+
+func indexCollection(cfg *config.Config, name, dir string) error {
+	opts := rag.Options{
+		Model:     cfg.RAG.EmbeddingModel,
+		ChunkSize: cfg.RAG.ChunkSize,
+	}
+
+	c, err := rag.Index(name, dir, opts, embedFunc(cfg), func(done, total int) {
+		fmt.Fprintf(os.Stderr, "\rEmbedding %d/%d chunks", done, total)
+	})
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Indexed %d file(s), %d chunk(s) into %s\n", len(c.Files), len(c.Chunks), c.Name)
+	return nil
+}
+
+func listCollections() error {
+	infos, err := rag.List()
+	if err != nil {
+		return err
+	}
+	if len(infos) == 0 {
+		fmt.Println("No collections. Create one with: llmv rag index NAME DIR")
+		return nil
+	}
+
+	for _, c := range infos {
+		fmt.Printf("%-20s %5d files %6d chunks  %-18s %s  %s\n",
+			c.Name, c.Files, c.Chunks, c.Model,
+			c.UpdatedAt.Format("2006-01-02 15:04"), c.Root)
+	}
+	return nil
+}
+
+func searchCollection(cfg *config.Config, name, query string) error {
+	c, err := rag.Load(name)
+	if err != nil {
+		return err
+	}
+
+	vectors, err := aihub.Embed(c.Model, []string{query}, cfg)
+	if err != nil {
+		return err
+	}
+
+	for i, r := range c.Search(vectors[0], cfg.RAG.TopK) {
+		fmt.Printf("[%d] %s:%d (%.3f)\n%s\n\n", i+1, r.Path, r.Line, r.Score, r.Text)
+	}
+	return nil
+}
+
+func embedFunc(cfg *config.Config) rag.EmbedFunc {
+	return func(model string, texts []string) ([][]float32, error) {
+		return aihub.Embed(model, texts, cfg)
+	}
+}
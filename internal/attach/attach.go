@@ -0,0 +1,218 @@
+// Package attach resolves @path mentions in chat input into file
+// attachments.
+package attach
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aj-seven/llmverse/pkg/chat"
+)
+
+const (
+	// MentionPrefix starts a file mention in the chat input.
+	MentionPrefix = "@"
+
+	// MaxFileSize is the largest single file that can be attached.
+	MaxFileSize = 256 * 1024
+	// MaxTotalSize caps the combined size of all files in one message.
+	MaxTotalSize = 1024 * 1024
+	// MaxFiles caps how many files a directory or glob may expand to.
+	MaxFiles = 50
+
+	sniffLen = 8000
+)
+
+var (
+	ErrTooLarge     = errors.New("attachments exceed the size limit")
+	ErrTooManyFiles = errors.New("too many files attached")
+)
+
+// skipDirs are never descended into when a directory is attached or
+// paths are indexed for completion.
+var skipDirs = map[string]bool{
+	".git":         true,
+	".hg":          true,
+	".svn":         true,
+	"node_modules": true,
+	"vendor":       true,
+	"__pycache__":  true,
+	".venv":        true,
+	"dist":         true,
+	"build":        true,
+	"target":       true,
+}
+
+// IgnoredDir reports whether a directory is skipped when walking a tree,
+// e.g. version control metadata and dependency folders.
+func IgnoredDir(name string) bool {
+	return skipDirs[name] || (strings.HasPrefix(name, ".") && name != "." && name != "..")
+}
+
+// IsBinary reports whether data looks like a binary file.
+func IsBinary(data []byte) bool {
+	return bytes.IndexByte(data[:min(len(data), sniffLen)], 0) >= 0
+}
+
+// Mentions returns the @path tokens in input. A mention starts at the
+// beginning of the input or after whitespace, so e-mail addresses are
+// left alone.
+func Mentions(input string) []string {
+	var out []string
+	for _, field := range strings.Fields(input) {
+		if p, ok := strings.CutPrefix(field, MentionPrefix); ok && p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Resolve reads the files mentioned in input, relative to dir. Mentions
+// that match no file are left as plain text. Directories attach the text
+// files inside them and globs attach every match.
+func Resolve(input, dir string) ([]chat.Attachment, error) {
+	var (
+		atts  []chat.Attachment
+		seen  = map[string]bool{}
+		total int
+	)
+
+	for _, mention := range Mentions(input) {
+		paths, err := expand(dir, mention)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range paths {
+			if seen[p] {
+				continue
+			}
+			seen[p] = true
+
+			a, ok, err := readFile(dir, p)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+
+			total += len(a.Content)
+			if total > MaxTotalSize {
+				return nil, fmt.Errorf("%w of %d KB", ErrTooLarge, MaxTotalSize/1024)
+			}
+			if len(atts) == MaxFiles {
+				return nil, fmt.Errorf("%w (max %d)", ErrTooManyFiles, MaxFiles)
+			}
+			atts = append(atts, a)
+		}
+	}
+
+	return atts, nil
+}
+
+// Helpers
+
+// expand turns a mention into the files it refers to.
+func expand(dir, mention string) ([]string, error) {
+	path := resolvePath(dir, mention)
+
+	if strings.ContainsAny(mention, "*?[") {
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, fmt.Errorf("bad pattern @%s: %w", mention, err)
+		}
+		var files []string
+		for _, m := range matches {
+			if info, err := os.Stat(m); err == nil && info.Mode().IsRegular() {
+				files = append(files, m)
+			}
+		}
+		return files, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if p != path && IgnoredDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() && !strings.HasPrefix(d.Name(), ".") {
+			files = append(files, p)
+		}
+		if len(files) > MaxFiles {
+			return fmt.Errorf("%w: @%s has more than %d files", ErrTooManyFiles, mention, MaxFiles)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// readFile loads a text file as an attachment. Binary files are skipped.
+func readFile(dir, path string) (chat.Attachment, bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return chat.Attachment{}, false, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if info.Size() > MaxFileSize {
+		return chat.Attachment{}, false, fmt.Errorf("%s is larger than %d KB", displayPath(dir, path), MaxFileSize/1024)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return chat.Attachment{}, false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if IsBinary(data) {
+		return chat.Attachment{}, false, nil
+	}
+
+	return chat.Attachment{
+		Path:     displayPath(dir, path),
+		Language: Language(path),
+		Content:  string(data),
+	}, true, nil
+}
+
+func resolvePath(dir, p string) string {
+	if strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, p[2:])
+		}
+	}
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(dir, p)
+}
+
+// displayPath shortens path to be relative to dir where possible.
+func displayPath(dir, path string) string {
+	if rel, err := filepath.Rel(dir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
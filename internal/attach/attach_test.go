@@ -0,0 +1,163 @@
+package attach
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		input   string
+		want    []string
+		wantErr error
+		// wantMsg is part of the error message when wantErr is nil.
+		wantMsg string
+	}{
+		{
+			name:  "file",
+			files: map[string]string{"main.go": "package main"},
+			input: "explain @main.go please",
+			want:  []string{"main.go"},
+		},
+		{
+			name:  "missing file left as text",
+			input: "mail me@example.com about @nothing",
+		},
+		{
+			name:  "mentioned twice",
+			files: map[string]string{"a.txt": "a"},
+			input: "@a.txt and @a.txt again",
+			want:  []string{"a.txt"},
+		},
+		{
+			name:  "binary file skipped",
+			files: map[string]string{"a.txt": "a", "b.bin": "b\x00b"},
+			input: "@a.txt @b.bin",
+			want:  []string{"a.txt"},
+		},
+		{
+			name: "directory",
+			files: map[string]string{
+				"src/a.go":        "a",
+				"src/sub/b.go":    "b",
+				"src/.hidden":     "h",
+				"src/.git/config": "g",
+				"src/vendor/v.go": "v",
+			},
+			input: "@src",
+			want:  []string{"src/a.go", "src/sub/b.go"},
+		},
+		{
+			name:    "file too large",
+			files:   map[string]string{"big.txt": strings.Repeat("x", MaxFileSize+1)},
+			input:   "@big.txt",
+			wantMsg: "big.txt is larger than 256 KB",
+		},
+		{
+			name:  "file at the size limit",
+			files: map[string]string{"big.txt": strings.Repeat("x", MaxFileSize)},
+			input: "@big.txt",
+			want:  []string{"big.txt"},
+		},
+		{
+			name:    "total too large",
+			files:   manyFiles("f", 5, strings.Repeat("x", MaxFileSize-1)),
+			input:   "@f*",
+			wantErr: ErrTooLarge,
+		},
+		{
+			name:  "glob at the count limit",
+			files: manyFiles("f", MaxFiles, "x"),
+			input: "@f*",
+			want:  slices.Sorted(maps.Keys(manyFiles("f", MaxFiles, "x"))),
+		},
+		{
+			name:    "glob over the count limit",
+			files:   manyFiles("f", MaxFiles+1, "x"),
+			input:   "@f*",
+			wantErr: ErrTooManyFiles,
+		},
+		{
+			name:    "directory over the count limit",
+			files:   manyFiles("dir/f", MaxFiles+1, "x"),
+			input:   "@dir",
+			wantErr: ErrTooManyFiles,
+		},
+		{
+			name:    "mentions over the count limit together",
+			files:   merge(manyFiles("a", 30, "x"), manyFiles("b", 30, "x")),
+			input:   "@a* @b*",
+			wantErr: ErrTooManyFiles,
+		},
+		{
+			name:    "bad pattern",
+			input:   "@[",
+			wantMsg: "bad pattern @[",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			atts, err := Resolve(tt.input, dir)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Resolve() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			case tt.wantMsg != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantMsg) {
+					t.Fatalf("Resolve() error = %v, want %q", err, tt.wantMsg)
+				}
+				return
+			case err != nil:
+				t.Fatalf("Resolve() error = %v", err)
+			}
+
+			var got []string
+			for _, a := range atts {
+				got = append(got, a.Path)
+				if a.Content != tt.files[a.Path] {
+					t.Errorf("content of %s = %q, want %q", a.Path, a.Content, tt.files[a.Path])
+				}
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Resolve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// manyFiles returns n files named prefix0, prefix1, ... with content.
+func manyFiles(prefix string, n int, content string) map[string]string {
+	files := map[string]string{}
+	for i := range n {
+		files[fmt.Sprintf("%s%d", prefix, i)] = content
+	}
+	return files
+}
+
+func merge(a, b map[string]string) map[string]string {
+	maps.Copy(a, b)
+	return a
+}
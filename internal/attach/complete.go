@@ -0,0 +1,148 @@
+package attach
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const (
+	// maxIndexed caps how many paths are indexed for completion, so
+	// starting llmv in a huge tree stays fast.
+	maxIndexed = 20000
+	indexTTL   = 10 * time.Second
+)
+
+// Match is a path suggested for a partial mention.
+type Match struct {
+	Path  string // relative to the index root, slash separated
+	IsDir bool
+	score int
+}
+
+// PathIndex lists the files below a directory for fuzzy completion of
+// mentions. The listing is refreshed lazily once it is older than a few
+// seconds.
+type PathIndex struct {
+	root string
+
+	mu      sync.Mutex
+	entries []Match
+	builtAt time.Time
+}
+
+// NewPathIndex creates an index of the paths below root.
+func NewPathIndex(root string) *PathIndex {
+	return &PathIndex{root: root}
+}
+
+// Complete returns up to limit paths that fuzzy-match query, best first.
+// The characters of query must appear in order in the path; matches at
+// the start of path segments and consecutive matches rank higher.
+func (ix *PathIndex) Complete(query string, limit int) []Match {
+	entries := ix.list()
+
+	var out []Match
+	for _, e := range entries {
+		score, ok := fuzzyScore(e.Path, query)
+		if !ok {
+			continue
+		}
+		e.score = score
+		out = append(out, e)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].score != out[j].score {
+			return out[i].score > out[j].score
+		}
+		return len(out[i].Path) < len(out[j].Path)
+	})
+
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// Helpers
+
+func (ix *PathIndex) list() []Match {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	if ix.entries != nil && time.Since(ix.builtAt) < indexTTL {
+		return ix.entries
+	}
+
+	entries := []Match{}
+	_ = filepath.WalkDir(ix.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == ix.root {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") || (d.IsDir() && skipDirs[d.Name()]) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if len(entries) >= maxIndexed {
+			return filepath.SkipAll
+		}
+
+		rel, err := filepath.Rel(ix.root, p)
+		if err != nil {
+			return nil
+		}
+		entries = append(entries, Match{Path: filepath.ToSlash(rel), IsDir: d.IsDir()})
+		return nil
+	})
+
+	ix.entries = entries
+	ix.builtAt = time.Now()
+	return entries
+}
+
+// fuzzyScore matches query as a case-insensitive subsequence of path.
+func fuzzyScore(path, query string) (int, bool) {
+	if query == "" {
+		// Without a query, suggest the top level only.
+		return -strings.Count(path, "/") * 10, !strings.Contains(path, "/")
+	}
+
+	orig := []rune(path)
+	p := []rune(strings.ToLower(path))
+	q := []rune(strings.ToLower(query))
+
+	score, qi, prev := 0, 0, -2
+	for i := 0; i < len(p) && qi < len(q); i++ {
+		if p[i] != q[qi] {
+			continue
+		}
+		switch {
+		case i == prev+1:
+			score += 5
+		case i == 0 || p[i-1] == '/' || p[i-1] == '_' || p[i-1] == '-' || p[i-1] == '.':
+			score += 3
+		case len(orig) == len(p) && unicode.IsUpper(orig[i]):
+			score += 2
+		default:
+			score++
+		}
+		prev = i
+		qi++
+	}
+	if qi < len(q) {
+		return 0, false
+	}
+
+	// Prefer matches in the file name over matches in directories.
+	if strings.Contains(strings.ToLower(filepath.Base(path)), strings.ToLower(filepath.Base(query))) {
+		score += 10
+	}
+	return score - len(p)/10, true
+}
@@ -0,0 +1,98 @@
+package attach
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// languages maps file extensions to Markdown code block languages.
+var languages = map[string]string{
+	".go":    "go",
+	".py":    "python",
+	".js":    "javascript",
+	".jsx":   "jsx",
+	".ts":    "typescript",
+	".tsx":   "tsx",
+	".rs":    "rust",
+	".c":     "c",
+	".h":     "c",
+	".cc":    "cpp",
+	".cpp":   "cpp",
+	".hpp":   "cpp",
+	".java":  "java",
+	".kt":    "kotlin",
+	".swift": "swift",
+	".rb":    "ruby",
+	".php":   "php",
+	".cs":    "csharp",
+	".lua":   "lua",
+	".sh":    "bash",
+	".bash":  "bash",
+	".zsh":   "zsh",
+	".ps1":   "powershell",
+	".sql":   "sql",
+	".html":  "html",
+	".css":   "css",
+	".scss":  "scss",
+	".json":  "json",
+	".yaml":  "yaml",
+	".yml":   "yaml",
+	".toml":  "toml",
+	".xml":   "xml",
+	".md":    "markdown",
+	".proto": "protobuf",
+	".tf":    "hcl",
+	".vim":   "vim",
+	".dart":  "dart",
+}
+
+// names maps well-known file names without a useful extension.
+var names = map[string]string{
+	"Makefile":   "makefile",
+	"Dockerfile": "dockerfile",
+	"go.mod":     "go",
+}
+
+// Language guesses the code block language of a file from its name.
+func Language(path string) string {
+	base := filepath.Base(path)
+	if lang, ok := names[base]; ok {
+		return lang
+	}
+	return languages[strings.ToLower(filepath.Ext(base))]
+}
+
+// extensions picks the file extension of languages several extensions map
+// to, and of aliases often used in code blocks.
+var extensions = map[string]string{
+	"golang":     ".go",
+	"py":         ".py",
+	"js":         ".js",
+	"ts":         ".ts",
+	"cpp":        ".cpp",
+	"c++":        ".cpp",
+	"sh":         ".sh",
+	"shell":      ".sh",
+	"bash":       ".sh",
+	"console":    ".sh",
+	"yml":        ".yml",
+	"dockerfile": ".dockerfile",
+	"makefile":   ".mk",
+}
+
+// Extension returns a file extension for a code block language, or "" if
+// the language is unknown.
+func Extension(language string) string {
+	language = strings.ToLower(language)
+	if ext, ok := extensions[language]; ok {
+		return ext
+	}
+
+	best := ""
+	for ext, lang := range languages {
+		if lang == language && (best == "" || ext < best) {
+			best = ext
+		}
+	}
+	return best
+}
@@ -0,0 +1,39 @@
+// Package clipboard copies text to the clipboard, including over SSH.
+package clipboard
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/atotto/clipboard"
+	"golang.org/x/term"
+)
+
+// Write copies text to the clipboard. It sets the local system clipboard
+// when there is one, and also asks the terminal to set its clipboard with
+// the OSC 52 escape sequence, which works over SSH and inside tmux. It
+// fails only if neither could be attempted.
+func Write(text string) error {
+	localErr := clipboard.WriteAll(text)
+
+	if !term.IsTerminal(int(os.Stdout.Fd())) {
+		return localErr
+	}
+	if err := writeOSC52(os.Stdout, text); err != nil && localErr != nil {
+		return localErr
+	}
+	return nil
+}
+
+// writeOSC52 writes the escape sequence that sets the terminal clipboard,
+// wrapped for tmux to pass it on to the outer terminal.
+func writeOSC52(w io.Writer, text string) error {
+	seq := fmt.Sprintf("\x1b]52;c;%s\x07", base64.StdEncoding.EncodeToString([]byte(text)))
+	if os.Getenv("TMUX") != "" {
+		seq = "\x1bPtmux;\x1b" + seq + "\x1b\\"
+	}
+	_, err := io.WriteString(w, seq)
+	return err
+}
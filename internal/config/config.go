@@ -1,9 +1,13 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
+
+	"github.com/aj-seven/llmverse/internal/filelock"
 
 	"gopkg.in/yaml.v3"
 )
@@ -11,112 +15,459 @@ import (
 const (
 	configDirName  = "llmv"
 	configFileName = "config.yaml"
+
+	lockTimeout = 2 * time.Second
 )
 
+var errConfigExists = errors.New("config file already exists")
+
+// Persona is a named system prompt that can be picked for a chat.
+type Persona struct {
+	Name   string `yaml:"name"`
+	Prompt string `yaml:"prompt"`
+}
+
+// Endpoint is a named Ollama server, e.g. a GPU box on the network.
+type Endpoint struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	// HTTP replaces the top-level HTTP settings for this server.
+	HTTP *HTTP `yaml:"http,omitempty"`
+}
+
+// HTTP configures the client used for requests to Ollama, e.g. to reach a
+// server behind an authenticating reverse proxy.
+type HTTP struct {
+	// Headers are added to every request.
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// TokenEnv names the environment variable holding a bearer token for
+	// the Authorization header, so the token stays out of the file.
+	TokenEnv string `yaml:"token_env,omitempty"`
+	// CertFile and KeyFile are a client certificate for mutual TLS.
+	CertFile string `yaml:"cert_file,omitempty"`
+	KeyFile  string `yaml:"key_file,omitempty"`
+	// CAFile is a PEM bundle of certificate authorities trusted besides
+	// the system ones.
+	CAFile string `yaml:"ca_file,omitempty"`
+	// Proxy is the URL of the proxy to use instead of the one from the
+	// HTTPS_PROXY/HTTP_PROXY environment.
+	Proxy string `yaml:"proxy,omitempty"`
+	// ConnectTimeout bounds connecting, including the TLS handshake.
+	// ResponseTimeout bounds the wait for a response to start, which
+	// includes loading the model. Zero means the defaults.
+	ConnectTimeout  time.Duration `yaml:"connect_timeout,omitempty"`
+	ResponseTimeout time.Duration `yaml:"response_timeout,omitempty"`
+}
+
+// DefaultEndpointName names Host in the endpoint list when it is not one
+// of the configured hosts.
+const DefaultEndpointName = "default"
+
 type Config struct {
+	// Version is the format of the config file; see CurrentVersion.
+	Version int    `yaml:"version"`
 	Host    string `yaml:"host"`
+	// Hosts are further Ollama servers whose models are offered next to
+	// those of Host.
+	Hosts []Endpoint `yaml:"hosts,omitempty"`
+	// HTTP configures the requests to all hosts.
+	HTTP    HTTP `yaml:"http,omitempty"`
 	Storage struct {
 		History struct {
 			Path string `yaml:"path"`
+			// Encrypt seals the titles, messages, prompts, drafts, tags,
+			// folders, personas and collections of chats with a
+			// passphrase. Models, hosts, model options and timestamps
+			// stay readable. Set it with `llmv history encrypt`.
+			Encrypt   bool `yaml:"encrypt"`
+			Retention struct {
+				TrashDays          int `yaml:"trash_days"`
+				ArchiveAfterMonths int `yaml:"archive_after_months"`
+				MaxSizeMB          int `yaml:"max_size_mb"`
+			} `yaml:"retention"`
 		} `yaml:"history"`
 	} `yaml:"storage"`
 	Assistant struct {
+		// Message is the default system prompt for new chats.
 		Message string `yaml:"message"`
-	} `yaml:"system"`
+	} `yaml:"assistant"`
+	Personas []Persona `yaml:"personas"`
 	Theme struct {
-		Markdown string  `yaml:"markdown"`
+		Markdown string `yaml:"markdown"`
 	} `yaml:"theme"`
+	Titles struct {
+		// Generate asks a model for a short title after the first exchange.
+		Generate bool `yaml:"generate"`
+		// Model used for titles, as "model" on the default host or
+		// "model@host"; the chat's own model when empty.
+		Model string `yaml:"model"`
+	} `yaml:"titles"`
+	RAG struct {
+		// EmbeddingModel is the Ollama model used to index and query
+		// document collections.
+		EmbeddingModel string `yaml:"embedding_model"`
+		// TopK is how many chunks are added to each question.
+		TopK int `yaml:"top_k"`
+		// ChunkSize is the approximate chunk length in characters.
+		ChunkSize int `yaml:"chunk_size"`
+	} `yaml:"rag"`
+	Retry struct {
+		// Attempts is how often a request is sent to a model before its
+		// fallbacks are tried; 1 turns retrying off, 0 means 3.
+		Attempts int `yaml:"attempts"`
+		// Backoff is the wait before the first retry, doubled for each
+		// further one; 0 means one second.
+		Backoff time.Duration `yaml:"backoff"`
+	} `yaml:"retry"`
+	// Fallbacks lists, per model, the models tried in turn when it keeps
+	// failing. Keys and entries are "model" or "model@host"; entries
+	// without a host use the failing model's host.
+	Fallbacks map[string][]string `yaml:"fallbacks,omitempty"`
+	Session   struct {
+		// Restore reopens the chats of the last session on startup, like
+		// the --continue flag.
+		Restore bool `yaml:"restore"`
+	} `yaml:"session"`
+
+	// path is the config file; sources records where each value came
+	// from and positions where it is in the file, by key.
+	path      string
+	sources   map[string]string
+	positions map[string]string
+	// fileVersion is the version of the file before it was migrated, or
+	// 0 if there is none.
+	fileVersion int
 }
 
-func LoadOrNew(cliHost string) (*Config, error) {
-	configPath, err := getConfigPath()
+// Load resolves the config from its layers: defaults, then the config
+// file, then LLMV_* environment variables, then command line flags. The
+// default config file is created on first run unless opts.ReadOnly is set.
+func Load(opts Options) (*Config, error) {
+	configPath := opts.Path
+	// A default file that does not exist yet is an empty file layer when
+	// it may not be created.
+	missing := false
+	if configPath == "" {
+		var err error
+		if configPath, err = getConfigPath(); err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(configPath); os.IsNotExist(err) {
+			if opts.ReadOnly {
+				missing = true
+			} else if err := createDefaultConfig(configPath); err != nil && !errors.Is(err, errConfigExists) {
+				return nil, err
+			}
+		}
+	}
+
+	cfg, err := defaultConfig()
 	if err != nil {
 		return nil, err
 	}
+	cfg.path = configPath
+	cfg.sources = map[string]string{}
+	for _, f := range cfg.fields() {
+		cfg.sources[f.key] = SourceDefault
+	}
 
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		cfg, err := createDefaultConfig(configPath)
-		if err != nil {
-			return nil, err
+	var data []byte
+	if !missing {
+		if data, err = os.ReadFile(configPath); err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
 		}
-		if cliHost != "" {
-			cfg.Host = cliHost
+		if data, err = cfg.readVersion(configPath, data, opts.ReadOnly); err != nil {
+			return nil, err
 		}
-		return cfg, nil
+	}
+	if err := cfg.applyFile(data); err != nil {
+		return nil, err
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.applyFlags(opts.Flags); err != nil {
+		return nil, err
 	}
 
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+	// The host may be given as the name of a configured host.
+	if e, ok := cfg.Endpoint(cfg.Host); ok && e.Name != DefaultEndpointName {
+		cfg.Host = e.URL
 	}
 
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	if problems := cfg.validate(); len(problems) > 0 {
+		return nil, &ValidationError{Path: configPath, Problems: problems}
 	}
 
-	if cliHost != "" {
-		cfg.Host = cliHost
+	return cfg, nil
+}
+
+// readVersion records the version of the file and returns its contents
+// migrated to CurrentVersion. Unless readOnly is set, the migrated file
+// is written back.
+func (c *Config) readVersion(path string, data []byte, readOnly bool) ([]byte, error) {
+	doc, version, err := parseVersion(path, data)
+	if err != nil {
+		return nil, err
+	}
+	c.fileVersion = version
+	switch {
+	case version == CurrentVersion:
+		return data, nil
+	case readOnly:
+		// A missing version key is not added, so problems are reported at
+		// the lines of the file on disk.
+		root := doc.Content[0]
+		upgrade(root, version)
+		if mappingKey(root, "version") != nil {
+			setVersion(root, CurrentVersion)
+		}
+		migrated, err := yaml.Marshal(&doc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal config: %w", err)
+		}
+		return migrated, nil
+	default:
+		return migrate(path, data)
 	}
+}
 
-	return &cfg, nil
+// FileVersion returns the version of the config file as it was found,
+// before migrating it, or 0 if there was no file. A version below
+// CurrentVersion after a read-only load means a migration is pending.
+func (c *Config) FileVersion() int {
+	return c.fileVersion
 }
 
 func Save(cfg *Config) error {
-	configPath, err := getConfigPath()
+	configPath, err := cfg.filePath()
 	if err != nil {
 		return err
 	}
 
-	data, err := yaml.Marshal(cfg)
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	lock, err := filelock.Acquire(configPath, lockTimeout)
 	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+		return err
+	}
+	defer lock.Release()
+
+	return writeConfig(configPath, cfg)
+}
+
+// Update applies fn to the config file on disk and to c, holding the config
+// lock for the whole read-modify-write. Changes made by other llmv instances
+// since c was loaded are preserved instead of being clobbered, and runtime
+// overrides held in c (such as -host or LLMV_* variables) never leak
+// into the file.
+func (c *Config) Update(fn func(*Config)) error {
+	configPath, err := c.filePath()
+	if err != nil {
+		return err
 	}
 
 	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	if err := os.WriteFile(configPath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write config file: %w", err)
+	lock, err := filelock.Acquire(configPath, lockTimeout)
+	if err != nil {
+		return err
 	}
+	defer lock.Release()
 
+	// A missing file is an empty file layer: the defaults.
+	onDisk, err := defaultConfig()
+	if err != nil {
+		return err
+	}
+	if data, err := os.ReadFile(configPath); err == nil {
+		if err := yaml.Unmarshal(data, onDisk); err != nil {
+			return fmt.Errorf("failed to unmarshal config: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	fn(onDisk)
+	if err := writeConfig(configPath, onDisk); err != nil {
+		return err
+	}
+
+	fn(c)
 	return nil
 }
 
+// Endpoints returns the Ollama servers to use: Host first, then the
+// other configured hosts.
+func (c *Config) Endpoints() []Endpoint {
+	endpoints := []Endpoint{c.DefaultEndpoint()}
+	for _, e := range c.Hosts {
+		if e.URL != c.Host {
+			endpoints = append(endpoints, e)
+		}
+	}
+	return endpoints
+}
+
+// DefaultEndpoint returns the endpoint of Host, named after the configured
+// host with the same URL if there is one.
+func (c *Config) DefaultEndpoint() Endpoint {
+	for _, e := range c.Hosts {
+		if e.URL == c.Host {
+			return e
+		}
+	}
+	return Endpoint{Name: DefaultEndpointName, URL: c.Host}
+}
+
+// Endpoint looks up an endpoint by name.
+func (c *Config) Endpoint(name string) (Endpoint, bool) {
+	for _, e := range c.Endpoints() {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return Endpoint{}, false
+}
+
+// ForHost returns a copy of c that sends requests to the named endpoint,
+// with the endpoint's HTTP settings if it has any. Unknown names,
+// including "", give c itself.
+func (c *Config) ForHost(name string) *Config {
+	if c == nil {
+		return nil
+	}
+	e, ok := c.Endpoint(name)
+	if !ok || (e.URL == c.Host && e.HTTP == nil) {
+		return c
+	}
+	cp := *c
+	cp.Host = e.URL
+	if e.HTTP != nil {
+		cp.HTTP = *e.HTTP
+	}
+	return &cp
+}
+
 func (c *Config) SetSystemMessage(msg string) error {
-	c.Assistant.Message = msg
-	return Save(c)
+	return c.Update(func(cfg *Config) {
+		cfg.Assistant.Message = msg
+	})
+}
+
+// FindPersona looks up a persona by name.
+func (c *Config) FindPersona(name string) (Persona, bool) {
+	for _, p := range c.Personas {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Persona{}, false
 }
 
-func createDefaultConfig(path string) (*Config, error) {
+// SavePersona adds p, or replaces the persona currently named oldName.
+func (c *Config) SavePersona(oldName string, p Persona) error {
+	return c.Update(func(cfg *Config) {
+		for i := range cfg.Personas {
+			if cfg.Personas[i].Name == oldName {
+				cfg.Personas[i] = p
+				return
+			}
+		}
+		cfg.Personas = append(cfg.Personas, p)
+	})
+}
+
+// DeletePersona removes the persona with the given name.
+func (c *Config) DeletePersona(name string) error {
+	return c.Update(func(cfg *Config) {
+		for i := range cfg.Personas {
+			if cfg.Personas[i].Name == name {
+				cfg.Personas = append(cfg.Personas[:i], cfg.Personas[i+1:]...)
+				return
+			}
+		}
+	})
+}
+
+func writeConfig(path string, cfg *Config) error {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	if err := filelock.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	return nil
+}
+
+// defaultConfig returns the values used where the config file and the
+// overrides leave a setting out.
+func defaultConfig() (*Config, error) {
 	userHomeDir, err := os.UserHomeDir()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user home dir: %w", err)
 	}
 
-	historyPath := filepath.Join(userHomeDir, "."+configDirName, "history")
-
-	cfg := &Config{}
-	cfg.Storage.History.Path = historyPath
+	cfg := &Config{Version: CurrentVersion}
+	cfg.Storage.History.Path = filepath.Join(userHomeDir, "."+configDirName, "history")
+	cfg.Storage.History.Retention.TrashDays = 30
 	cfg.Host = "http://localhost:11434"
 	cfg.Assistant.Message = ""
 	cfg.Theme.Markdown = "dark"
+	cfg.RAG.EmbeddingModel = "nomic-embed-text"
+	cfg.RAG.TopK = 4
+	cfg.RAG.ChunkSize = 1000
+	cfg.Retry.Attempts = 3
+	cfg.Retry.Backoff = time.Second
+	return cfg, nil
+}
 
-	data, err := yaml.Marshal(cfg)
+func createDefaultConfig(path string) error {
+	cfg, err := defaultConfig()
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal default config: %w", err)
+		return err
 	}
 
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-		return nil, fmt.Errorf("failed to create config directory: %w", err)
+		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	if err := os.WriteFile(path, data, 0644); err != nil {
-		return nil, fmt.Errorf("failed to write default config file: %w", err)
+	lock, err := filelock.Acquire(path, lockTimeout)
+	if err != nil {
+		return err
 	}
+	defer lock.Release()
 
-	return cfg, nil
+	// Another instance may have created the file while we waited.
+	if _, err := os.Stat(path); err == nil {
+		return errConfigExists
+	}
+
+	if err := writeConfig(path, cfg); err != nil {
+		return fmt.Errorf("failed to write default config file: %w", err)
+	}
+
+	return nil
+}
+
+// Path returns the config file c was loaded from.
+func (c *Config) Path() string {
+	return c.path
+}
+
+// filePath returns the file c was loaded from, or the default one.
+func (c *Config) filePath() (string, error) {
+	if c.path != "" {
+		return c.path, nil
+	}
+	return getConfigPath()
 }
 
 func getConfigPath() (string, error) {
@@ -0,0 +1,272 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		env   map[string]string
+		flags map[string]string
+		// want checks the loaded config.
+		want func(t *testing.T, cfg *Config)
+		// wantSources are the sources of some keys; "file" stands for the
+		// file with its path.
+		wantSources map[string]string
+	}{
+		{
+			name: "defaults",
+			file: "version: 2\n",
+			want: func(t *testing.T, cfg *Config) {
+				if cfg.Host != "http://localhost:11434" || cfg.RAG.TopK != 4 || cfg.Retry.Backoff != time.Second {
+					t.Errorf("got host %q, top_k %d, backoff %v; want the defaults", cfg.Host, cfg.RAG.TopK, cfg.Retry.Backoff)
+				}
+			},
+			wantSources: map[string]string{"host": SourceDefault, "rag.top_k": SourceDefault},
+		},
+		{
+			name: "file over defaults",
+			file: "version: 2\nhost: http://gpu:11434\nrag:\n  top_k: 8\n",
+			want: func(t *testing.T, cfg *Config) {
+				if cfg.Host != "http://gpu:11434" || cfg.RAG.TopK != 8 || cfg.RAG.ChunkSize != 1000 {
+					t.Errorf("got host %q, top_k %d, chunk_size %d", cfg.Host, cfg.RAG.TopK, cfg.RAG.ChunkSize)
+				}
+			},
+			wantSources: map[string]string{"host": "file", "rag.top_k": "file", "rag.chunk_size": SourceDefault},
+		},
+		{
+			name: "env over file",
+			file: "version: 2\nrag:\n  top_k: 8\n",
+			env:  map[string]string{"LLMV_RAG_TOP_K": "2", "LLMV_RETRY_BACKOFF": "250ms"},
+			want: func(t *testing.T, cfg *Config) {
+				if cfg.RAG.TopK != 2 || cfg.Retry.Backoff != 250*time.Millisecond {
+					t.Errorf("got top_k %d, backoff %v", cfg.RAG.TopK, cfg.Retry.Backoff)
+				}
+			},
+			wantSources: map[string]string{"rag.top_k": "env LLMV_RAG_TOP_K"},
+		},
+		{
+			name: "OLLAMA_HOST",
+			file: "version: 2\n",
+			env:  map[string]string{"OLLAMA_HOST": "gpu"},
+			want: func(t *testing.T, cfg *Config) {
+				if cfg.Host != "http://gpu:11434" {
+					t.Errorf("got host %q", cfg.Host)
+				}
+			},
+			wantSources: map[string]string{"host": "env OLLAMA_HOST"},
+		},
+		{
+			name:  "flags over env",
+			file:  "version: 2\n",
+			env:   map[string]string{"LLMV_RAG_TOP_K": "2"},
+			flags: map[string]string{"rag.top_k": "6", "fallbacks": "{big: [small]}"},
+			want: func(t *testing.T, cfg *Config) {
+				if cfg.RAG.TopK != 6 || len(cfg.Fallbacks["big"]) != 1 || cfg.Fallbacks["big"][0] != "small" {
+					t.Errorf("got top_k %d, fallbacks %v", cfg.RAG.TopK, cfg.Fallbacks)
+				}
+			},
+			wantSources: map[string]string{"rag.top_k": SourceFlag, "fallbacks": SourceFlag},
+		},
+		{
+			name:  "named host",
+			file:  "version: 2\nhosts:\n  - name: gpu\n    url: http://gpu:11434\n",
+			flags: map[string]string{"host": "gpu"},
+			want: func(t *testing.T, cfg *Config) {
+				if cfg.Host != "http://gpu:11434" {
+					t.Errorf("got host %q", cfg.Host)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := setupConfig(t, tt.file)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			cfg, err := Load(Options{Path: path, Flags: tt.flags})
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			tt.want(t, cfg)
+
+			sources := map[string]string{}
+			for _, s := range cfg.Settings(false) {
+				sources[s.Key] = s.Source
+			}
+			for key, want := range tt.wantSources {
+				if want == "file" {
+					want = SourceFile + " " + path
+				}
+				if sources[key] != want {
+					t.Errorf("source of %s = %q, want %q", key, sources[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadOverrideErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		flags   map[string]string
+		wantErr string
+	}{
+		{
+			name:    "bad env value",
+			env:     map[string]string{"LLMV_RAG_TOP_K": "many"},
+			wantErr: `LLMV_RAG_TOP_K: invalid value "many" for rag.top_k`,
+		},
+		{
+			name:    "unknown flag key",
+			flags:   map[string]string{"rag.topk": "3"},
+			wantErr: `unknown config key "rag.topk"`,
+		},
+		{
+			name:    "invalid flag value",
+			flags:   map[string]string{"retry.attempts": "-1"},
+			wantErr: "flag: retry.attempts: must not be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := setupConfig(t, "version: 2\n")
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			_, err := Load(Options{Path: path, Flags: tt.flags})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadCreatesDefaultConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("OLLAMA_HOST", "")
+
+	cfg, err := Load(Options{})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if want := filepath.Join(home, "."+configDirName, "config.yaml"); cfg.Path() != want {
+		t.Errorf("Path() = %q, want %q", cfg.Path(), want)
+	}
+	if _, err := os.Stat(cfg.Path()); err != nil {
+		t.Errorf("config file not created: %v", err)
+	}
+
+	// The created file must load as it is.
+	if _, err := Load(Options{}); err != nil {
+		t.Errorf("Load() of the created file error = %v", err)
+	}
+}
+
+func TestSettingsRedactsHeaders(t *testing.T) {
+	path := setupConfig(t, `version: 2
+http:
+  headers:
+    Authorization: Bearer secret
+hosts:
+  - name: gpu
+    url: http://gpu:11434
+    http:
+      headers:
+        X-Token: secret
+`)
+	cfg, err := Load(Options{Path: path})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	for _, showSecrets := range []bool{false, true} {
+		var shown string
+		for _, s := range cfg.Settings(showSecrets) {
+			shown += s.Value + "\n"
+		}
+		if got := strings.Contains(shown, "secret"); got != showSecrets {
+			t.Errorf("Settings(%v) shows secrets: %v\n%s", showSecrets, got, shown)
+		}
+	}
+	if cfg.HTTP.Headers["Authorization"] != "Bearer secret" || cfg.Hosts[0].HTTP.Headers["X-Token"] != "secret" {
+		t.Error("Settings(false) changed the config")
+	}
+}
+
+// setupConfig points HOME at a temporary directory, clears OLLAMA_HOST and
+// writes a config file there.
+func setupConfig(t *testing.T, content string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("OLLAMA_HOST", "")
+
+	path := filepath.Join(home, "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestUpdateKeepsOverridesOutOfFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		exists bool
+	}{
+		{"existing file", true},
+		{"missing file", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := setupConfig(t, "version: 2\nrag:\n  chunk_size: 500\n")
+			t.Setenv("LLMV_RAG_TOP_K", "2")
+
+			cfg, err := Load(Options{Path: path, Flags: map[string]string{"host": "http://gpu:11434"}})
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if !tt.exists {
+				if err := os.Remove(path); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := cfg.SetSystemMessage("be brief"); err != nil {
+				t.Fatalf("SetSystemMessage() error = %v", err)
+			}
+			if cfg.Assistant.Message != "be brief" || cfg.RAG.TopK != 2 || cfg.Host != "http://gpu:11434" {
+				t.Errorf("in-memory config lost its overrides or the change: %+v", cfg)
+			}
+
+			os.Unsetenv("LLMV_RAG_TOP_K")
+			saved, err := Load(Options{Path: path})
+			if err != nil {
+				t.Fatalf("Load() of the saved file error = %v", err)
+			}
+			wantChunk := 1000
+			if tt.exists {
+				wantChunk = 500
+			}
+			if saved.Assistant.Message != "be brief" || saved.RAG.TopK != 4 ||
+				saved.Host != "http://localhost:11434" || saved.RAG.ChunkSize != wantChunk {
+				t.Errorf("saved file has message %q, top_k %d, host %q, chunk_size %d",
+					saved.Assistant.Message, saved.RAG.TopK, saved.Host, saved.RAG.ChunkSize)
+			}
+		})
+	}
+}
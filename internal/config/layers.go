@@ -0,0 +1,295 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Layers
+//
+// A setting is addressed by its dotted YAML key, e.g. "rag.top_k". Each
+// layer may set it: the defaults, the config file, the environment
+// variable LLMV_RAG_TOP_K, and the command line (--set rag.top_k=8).
+// Later layers win. Values are written as in the config file; lists and
+// maps use YAML flow syntax, e.g. LLMV_FALLBACKS='{big: [small]}'.
+
+// Sources of config values.
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+const envPrefix = "LLMV_"
+
+// Options locate the config file and carry the command line overrides.
+type Options struct {
+	// Path is the config file to use instead of ~/.llmv/config.yaml.
+	Path string
+	// Flags are values set on the command line, by key.
+	Flags map[string]string
+	// ReadOnly loads the config without writing anything: a missing
+	// default file is not created and an old file is migrated in memory
+	// only. FileVersion tells whether a migration is pending.
+	ReadOnly bool
+}
+
+// Setting is the value of one key of the effective config and the layer
+// it came from, e.g. "env LLMV_HOST".
+type Setting struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+// field is a setting of a config: its key and the value it is stored in.
+type field struct {
+	key   string
+	value reflect.Value
+}
+
+// fields lists the settings of c in file order. Sections become key
+// prefixes; lists and maps are settings of their own.
+func (c *Config) fields() []field {
+	var out []field
+	var walk func(prefix string, v reflect.Value)
+	walk = func(prefix string, v reflect.Value) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			name, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+			if !sf.IsExported() || name == "" || name == "-" {
+				continue
+			}
+			key := prefix + name
+			if key == "version" {
+				// The file format, not a setting.
+				continue
+			}
+			if fv := v.Field(i); fv.Kind() == reflect.Struct {
+				walk(key+".", fv)
+			} else {
+				out = append(out, field{key: key, value: fv})
+			}
+		}
+	}
+	walk("", reflect.ValueOf(c).Elem())
+	return out
+}
+
+// set parses raw into the setting with the given key.
+func (c *Config) set(key, raw, source string) error {
+	for _, f := range c.fields() {
+		if f.key != key {
+			continue
+		}
+		if f.value.Kind() == reflect.String {
+			f.value.SetString(raw)
+		} else {
+			v := reflect.New(f.value.Type())
+			if err := yaml.Unmarshal([]byte(raw), v.Interface()); err != nil {
+				return fmt.Errorf("invalid value %q for %s: %w", raw, key, err)
+			}
+			f.value.Set(v.Elem())
+		}
+		c.sources[key] = source
+		return nil
+	}
+	return fmt.Errorf("unknown config key %q", key)
+}
+
+// applyFile reads the config file over the defaults. Keys the file leaves
+// out keep their default.
+// Unknown keys and values of the wrong type are errors.
+func (c *Config) applyFile(data []byte) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return syntaxError(c.path, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+
+	var problems []Problem
+	checkNode(doc.Content[0], reflect.TypeFor[Config](), "", &problems)
+	if len(problems) > 0 {
+		return &ValidationError{Path: c.path, Problems: problems}
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("%s: %s", c.path, strings.TrimPrefix(err.Error(), "yaml: "))
+	}
+
+	c.positions = map[string]string{}
+	keyPositions("", doc.Content[0], c.positions)
+	for key := range c.positions {
+		if _, ok := c.sources[key]; ok {
+			c.sources[key] = SourceFile
+		}
+	}
+	return nil
+}
+
+// applyEnv applies OLLAMA_HOST, as understood by the ollama CLI, and the
+// LLMV_* variables.
+func (c *Config) applyEnv() error {
+	if host := os.Getenv("OLLAMA_HOST"); host != "" {
+		c.Host = ollamaHostURL(host)
+		c.sources["host"] = SourceEnv + " OLLAMA_HOST"
+	}
+
+	for _, f := range c.fields() {
+		name := EnvName(f.key)
+		raw, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := c.set(f.key, raw, SourceEnv+" "+name); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// applyFlags applies the values set on the command line.
+func (c *Config) applyFlags(flags map[string]string) error {
+	for key, raw := range flags {
+		if err := c.set(key, raw, SourceFlag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Settings lists every setting of the effective config with its source.
+// Header values, which often hold tokens, are shown as *** unless
+// showSecrets is set.
+func (c *Config) Settings(showSecrets bool) []Setting {
+	var out []Setting
+	for _, f := range c.fields() {
+		source := c.sources[f.key]
+		if source == SourceFile {
+			source += " " + c.path
+		}
+		value := f.value
+		if !showSecrets {
+			value = redacted(f.key, value)
+		}
+		out = append(out, Setting{Key: f.key, Value: formatValue(value), Source: source})
+	}
+	return out
+}
+
+const redactedValue = "***"
+
+// redacted returns a copy of a setting with its header values hidden.
+func redacted(key string, v reflect.Value) reflect.Value {
+	switch key {
+	case "http.headers":
+		return reflect.ValueOf(redactHeaders(v.Interface().(map[string]string)))
+	case "hosts":
+		hosts := append([]Endpoint(nil), v.Interface().([]Endpoint)...)
+		for i, e := range hosts {
+			if e.HTTP != nil {
+				h := *e.HTTP
+				h.Headers = redactHeaders(h.Headers)
+				hosts[i].HTTP = &h
+			}
+		}
+		return reflect.ValueOf(hosts)
+	}
+	return v
+}
+
+func redactHeaders(headers map[string]string) map[string]string {
+	if len(headers) == 0 {
+		return headers
+	}
+	out := make(map[string]string, len(headers))
+	for name := range headers {
+		out[name] = redactedValue
+	}
+	return out
+}
+
+// EnvName returns the environment variable overriding key, e.g.
+// LLMV_RAG_TOP_K for rag.top_k.
+func EnvName(key string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// keyPositions records where the keys of a YAML mapping are, by dotted
+// key, descending into nested mappings and lists of them.
+func keyPositions(prefix string, n *yaml.Node, out map[string]string) {
+	if n.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key := prefix + n.Content[i].Value
+		out[key] = position(n.Content[i])
+		if v := n.Content[i+1]; v.Kind == yaml.SequenceNode {
+			for j, item := range v.Content {
+				out[fmt.Sprintf("%s[%d]", key, j)] = position(item)
+				keyPositions(fmt.Sprintf("%s[%d].", key, j), item, out)
+			}
+		} else {
+			keyPositions(key+".", v, out)
+		}
+	}
+}
+
+// formatValue renders a setting for display: scalars as they are, lists
+// and maps in YAML flow syntax.
+func formatValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		if v.Len() == 0 && v.Kind() == reflect.Map {
+			return "{}"
+		} else if v.Len() == 0 {
+			return "[]"
+		}
+		var n yaml.Node
+		if err := n.Encode(v.Interface()); err != nil {
+			return fmt.Sprint(v.Interface())
+		}
+		setFlow(&n)
+		out, err := yaml.Marshal(&n)
+		if err != nil {
+			return fmt.Sprint(v.Interface())
+		}
+		return strings.TrimSpace(string(out))
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func setFlow(n *yaml.Node) {
+	n.Style |= yaml.FlowStyle
+	for _, child := range n.Content {
+		setFlow(child)
+	}
+}
+
+// ollamaHostURL turns OLLAMA_HOST, which may leave out the scheme and
+// port, into a URL.
+func ollamaHostURL(host string) string {
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	u, err := url.Parse(host)
+	if err != nil || u.Host == "" {
+		return host
+	}
+	if u.Port() == "" {
+		u.Host += ":11434"
+	}
+	return strings.TrimSuffix(u.String(), "/")
+}
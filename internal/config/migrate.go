@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/aj-seven/llmverse/internal/filelock"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the config file format written by this version of
+// llmv. Files without a version predate versioning and are version 1.
+const CurrentVersion = 2
+
+// migrations[i] upgrades a file from version i+1 to version i+2. They
+// edit the YAML document rather than a Config, so comments survive.
+var migrations = []func(root *yaml.Node){
+	// 2: the default system prompt moves from system: to assistant:,
+	// after the field it is loaded into.
+	func(root *yaml.Node) {
+		if key := mappingKey(root, "system"); key != nil && mappingKey(root, "assistant") == nil {
+			key.Value = "assistant"
+		}
+	},
+}
+
+// migrate upgrades the config file at path to CurrentVersion and returns
+// its new contents. The old file is kept next to it, e.g. as
+// config.yaml.v1.bak.
+func migrate(path string, data []byte) ([]byte, error) {
+	_, version, err := parseVersion(path, data)
+	if err != nil || version == CurrentVersion {
+		return data, err
+	}
+
+	lock, err := filelock.Acquire(path, lockTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	// Another instance may have migrated the file while we waited.
+	if data, err = os.ReadFile(path); err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	doc, version, err := parseVersion(path, data)
+	if err != nil || version == CurrentVersion {
+		return data, err
+	}
+
+	root := doc.Content[0]
+	upgrade(root, version)
+	setVersion(root, CurrentVersion)
+
+	migrated, err := yaml.Marshal(&doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	backup := fmt.Sprintf("%s.v%d.bak", path, version)
+	if err := filelock.WriteFile(backup, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to back up config file: %w", err)
+	}
+	if err := filelock.WriteFile(path, migrated, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Migrated %s to version %d; the old file is %s\n", path, CurrentVersion, backup)
+	return migrated, nil
+}
+
+// upgrade runs the migrations from version on the root mapping of the
+// file. It leaves the version key to the caller.
+func upgrade(root *yaml.Node, version int) {
+	for v := version; v < CurrentVersion; v++ {
+		migrations[v-1](root)
+	}
+}
+
+// parseVersion parses the config file and reads its version. Empty files
+// are taken to be current.
+func parseVersion(path string, data []byte) (yaml.Node, int, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return doc, 0, syntaxError(path, err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return doc, CurrentVersion, nil
+	}
+
+	key := mappingKey(doc.Content[0], "version")
+	if key == nil {
+		return doc, 1, nil
+	}
+	value := mappingValue(doc.Content[0], key)
+	version, err := strconv.Atoi(value.Value)
+	if err != nil || version < 1 {
+		return doc, 0, &ValidationError{Path: path, Problems: []Problem{{
+			Where: position(value),
+			Key:   "version",
+			Msg:   fmt.Sprintf("expected a version number, got %s", describeNode(value)),
+		}}}
+	}
+	if version > CurrentVersion {
+		return doc, 0, fmt.Errorf("invalid config %s: version %d is newer than this llmv supports (%d); upgrade llmv",
+			path, version, CurrentVersion)
+	}
+	return doc, version, nil
+}
+
+// setVersion sets the version key, adding it at the top if it is missing.
+func setVersion(root *yaml.Node, version int) {
+	if key := mappingKey(root, "version"); key != nil {
+		value := mappingValue(root, key)
+		value.Kind, value.Tag, value.Value = yaml.ScalarNode, "!!int", strconv.Itoa(version)
+		return
+	}
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"}
+	if len(root.Content) > 0 {
+		// Keep a comment at the top of the file there.
+		key.HeadComment, root.Content[0].HeadComment = root.Content[0].HeadComment, ""
+	}
+	root.Content = append([]*yaml.Node{
+		key,
+		{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(version)},
+	}, root.Content...)
+}
+
+// mappingKey returns the key node of name in a YAML mapping, or nil.
+func mappingKey(n *yaml.Node, name string) *yaml.Node {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == name {
+			return n.Content[i]
+		}
+	}
+	return nil
+}
+
+// mappingValue returns the value node belonging to key.
+func mappingValue(n *yaml.Node, key *yaml.Node) *yaml.Node {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i] == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
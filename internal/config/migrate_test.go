@@ -0,0 +1,181 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		// want is the file after Load; empty means unchanged.
+		want        string
+		wantBackup  bool
+		wantMessage string
+		wantErr     string
+	}{
+		{
+			name:        "current",
+			file:        "version: 2\nassistant:\n  message: be brief\n",
+			wantMessage: "be brief",
+		},
+		{
+			name: "empty",
+			file: "",
+		},
+		{
+			name:        "unversioned",
+			file:        "# my settings\nhost: http://gpu:11434\nsystem:\n  message: be brief # short answers\n",
+			want:        "# my settings\nversion: 2\nhost: http://gpu:11434\nassistant:\n    message: be brief # short answers\n",
+			wantBackup:  true,
+			wantMessage: "be brief",
+		},
+		{
+			name:        "version 1",
+			file:        "version: 1\nsystem:\n  message: be brief\n",
+			want:        "version: 2\nassistant:\n    message: be brief\n",
+			wantBackup:  true,
+			wantMessage: "be brief",
+		},
+		{
+			name:       "version 1 with both keys",
+			file:       "version: 1\nsystem:\n  message: old\nassistant:\n  message: new\n",
+			want:       "version: 2\nsystem:\n    message: old\nassistant:\n    message: new\n",
+			wantBackup: true,
+			wantErr:    `line 2, column 1: system: unknown key`,
+		},
+		{
+			name:    "newer version",
+			file:    "version: 3\n",
+			wantErr: "version 3 is newer than this llmv supports (2); upgrade llmv",
+		},
+		{
+			name:    "bad version",
+			file:    "version: 0\n",
+			wantErr: `version: expected a version number, got "0"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := setupConfig(t, tt.file)
+
+			cfg, err := Load(Options{Path: path})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			} else if cfg.Assistant.Message != tt.wantMessage {
+				t.Errorf("assistant.message = %q, want %q", cfg.Assistant.Message, tt.wantMessage)
+			}
+
+			want := tt.want
+			if want == "" {
+				want = tt.file
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != want {
+				t.Errorf("file after Load =\n%s\nwant\n%s", got, want)
+			}
+
+			backup, err := os.ReadFile(path + ".v1.bak")
+			if tt.wantBackup != (err == nil) {
+				t.Fatalf("backup exists = %v, want %v", err == nil, tt.wantBackup)
+			}
+			if err == nil && string(backup) != tt.file {
+				t.Errorf("backup =\n%s\nwant the original file\n%s", backup, tt.file)
+			}
+		})
+	}
+}
+
+func TestLoadReadOnly(t *testing.T) {
+	tests := []struct {
+		name            string
+		file            string
+		wantFileVersion int
+		wantMessage     string
+		wantErr         string
+	}{
+		{
+			name:            "current",
+			file:            "version: 2\nassistant:\n  message: be brief\n",
+			wantFileVersion: 2,
+			wantMessage:     "be brief",
+		},
+		{
+			name:            "unversioned",
+			file:            "# my settings\nsystem:\n  message: be brief\n",
+			wantFileVersion: 1,
+			wantMessage:     "be brief",
+		},
+		{
+			name:            "version 1",
+			file:            "version: 1\nsystem:\n  message: be brief\n",
+			wantFileVersion: 1,
+			wantMessage:     "be brief",
+		},
+		{
+			// Problems point at the lines of the unmigrated file.
+			name:    "unversioned with both keys",
+			file:    "system:\n  message: old\nassistant:\n  message: new\n",
+			wantErr: `line 1, column 1: system: unknown key`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := setupConfig(t, tt.file)
+
+			cfg, err := Load(Options{Path: path, ReadOnly: true})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			} else {
+				if cfg.FileVersion() != tt.wantFileVersion {
+					t.Errorf("FileVersion() = %d, want %d", cfg.FileVersion(), tt.wantFileVersion)
+				}
+				if cfg.Assistant.Message != tt.wantMessage {
+					t.Errorf("assistant.message = %q, want %q", cfg.Assistant.Message, tt.wantMessage)
+				}
+			}
+
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.file {
+				t.Errorf("file after Load =\n%s\nwant it unchanged\n%s", got, tt.file)
+			}
+			if _, err := os.Stat(path + ".v1.bak"); err == nil {
+				t.Error("backup written by a read-only Load")
+			}
+		})
+	}
+}
+
+func TestLoadReadOnlyMissingDefault(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("OLLAMA_HOST", "")
+
+	cfg, err := Load(Options{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.FileVersion() != 0 {
+		t.Errorf("FileVersion() = %d, want 0", cfg.FileVersion())
+	}
+	if _, err := os.Stat(cfg.Path()); !os.IsNotExist(err) {
+		t.Errorf("config file created by a read-only Load: %v", err)
+	}
+}
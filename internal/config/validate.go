@@ -0,0 +1,303 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationError lists the problems found in a config.
+type ValidationError struct {
+	Path     string
+	Problems []Problem
+}
+
+// Problem is a bad setting: where it was set, e.g. "line 3, column 5"
+// or "env LLMV_HOST", its key and what is wrong with it.
+type Problem struct {
+	Where string
+	Key   string
+	Msg   string
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid config %s:", e.Path)
+	for _, p := range e.Problems {
+		fmt.Fprintf(&b, "\n  %s: %s: %s", p.Where, p.Key, p.Msg)
+	}
+	return b.String()
+}
+
+// syntaxError reports a config file that is not valid YAML.
+func syntaxError(path string, err error) error {
+	return fmt.Errorf("invalid config %s: %s", path, strings.TrimPrefix(err.Error(), "yaml: "))
+}
+
+// File checks
+
+// checkNode checks a YAML node against the type it is decoded into:
+// mappings may only use the keys of the struct and values must decode.
+func checkNode(n *yaml.Node, t reflect.Type, key string, problems *[]Problem) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	if n.Tag == "!!null" {
+		return
+	}
+
+	switch {
+	case t.Kind() == reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			*problems = append(*problems, typeProblem(n, t, key))
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			ft, ok := fields[k.Value]
+			if !ok {
+				msg := "unknown key"
+				if s := suggest(k.Value, fields); s != "" {
+					msg += fmt.Sprintf(", did you mean %q?", s)
+				}
+				*problems = append(*problems, Problem{Where: position(k), Key: joinKey(key, k.Value), Msg: msg})
+				continue
+			}
+			checkNode(v, ft, joinKey(key, k.Value), problems)
+		}
+
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Struct:
+		if n.Kind != yaml.SequenceNode {
+			*problems = append(*problems, typeProblem(n, t, key))
+			return
+		}
+		for i, item := range n.Content {
+			checkNode(item, t.Elem(), fmt.Sprintf("%s[%d]", key, i), problems)
+		}
+
+	default:
+		if err := n.Decode(reflect.New(t).Interface()); err != nil {
+			*problems = append(*problems, typeProblem(n, t, key))
+		}
+	}
+}
+
+func typeProblem(n *yaml.Node, t reflect.Type, key string) Problem {
+	return Problem{
+		Where: position(n),
+		Key:   key,
+		Msg:   fmt.Sprintf("expected %s, got %s", describeType(t), describeNode(n)),
+	}
+}
+
+// yamlFields returns the types of the fields of a struct by YAML key.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+		if sf.IsExported() && name != "" && name != "-" {
+			fields[name] = sf.Type
+		}
+	}
+	return fields
+}
+
+func describeType(t reflect.Type) string {
+	switch {
+	case t == reflect.TypeFor[time.Duration]():
+		return "a duration such as 30s or 1m"
+	case t.Kind() == reflect.Bool:
+		return "true or false"
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return "a whole number"
+	case t.Kind() == reflect.String:
+		return "text"
+	case t.Kind() == reflect.Slice:
+		return "a list"
+	case t.Kind() == reflect.Map, t.Kind() == reflect.Struct:
+		return "a mapping of keys to values"
+	}
+	return t.String()
+}
+
+func describeNode(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	}
+	return fmt.Sprintf("%q", n.Value)
+}
+
+func position(n *yaml.Node) string {
+	return fmt.Sprintf("line %d, column %d", n.Line, n.Column)
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// suggest returns the known key closest to a misspelt one, if any is
+// close enough.
+func suggest(key string, fields map[string]reflect.Type) string {
+	best, bestDist := "", 3
+	for name := range fields {
+		if d := editDistance(strings.ToLower(key), name); d < bestDist || (d == bestDist && name < best) {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// Settings checks
+
+// validate checks the values of the effective config.
+func (c *Config) validate() []Problem {
+	var problems []Problem
+	add := func(key, format string, args ...any) {
+		problems = append(problems, Problem{Where: c.where(key), Key: key, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	if !validURL(c.Host) {
+		add("host", "expected an http:// or https:// URL or the name of a configured host, got %q", c.Host)
+	}
+	c.validateHTTP("http", c.HTTP, add)
+
+	names := map[string]bool{}
+	for i, e := range c.Hosts {
+		key := fmt.Sprintf("hosts[%d]", i)
+		switch {
+		case e.Name == "":
+			add(key+".name", "missing host name")
+		case names[e.Name]:
+			add(key+".name", "duplicate host name %q", e.Name)
+		}
+		names[e.Name] = true
+		if !validURL(e.URL) {
+			add(key+".url", "expected an http:// or https:// URL, got %q", e.URL)
+		}
+		if e.HTTP != nil {
+			c.validateHTTP(key+".http", *e.HTTP, add)
+		}
+	}
+
+	names = map[string]bool{}
+	for i, p := range c.Personas {
+		key := fmt.Sprintf("personas[%d].name", i)
+		switch {
+		case p.Name == "":
+			add(key, "missing persona name")
+		case names[p.Name]:
+			add(key, "duplicate persona name %q", p.Name)
+		}
+		names[p.Name] = true
+	}
+
+	for _, n := range []struct {
+		key   string
+		value int64
+	}{
+		{"storage.history.retention.trash_days", int64(c.Storage.History.Retention.TrashDays)},
+		{"storage.history.retention.archive_after_months", int64(c.Storage.History.Retention.ArchiveAfterMonths)},
+		{"storage.history.retention.max_size_mb", int64(c.Storage.History.Retention.MaxSizeMB)},
+		{"rag.top_k", int64(c.RAG.TopK)},
+		{"rag.chunk_size", int64(c.RAG.ChunkSize)},
+		{"retry.attempts", int64(c.Retry.Attempts)},
+		{"retry.backoff", int64(c.Retry.Backoff)},
+	} {
+		if n.value < 0 {
+			add(n.key, "must not be negative")
+		}
+	}
+
+	for _, model := range slices.Sorted(maps.Keys(c.Fallbacks)) {
+		if slices.Contains(c.Fallbacks[model], "") {
+			add("fallbacks", "empty fallback for %q", model)
+		}
+	}
+
+	return problems
+}
+
+func (c *Config) validateHTTP(key string, h HTTP, add func(key, format string, args ...any)) {
+	if h.Proxy != "" {
+		if u, err := url.Parse(h.Proxy); err != nil || u.Host == "" {
+			add(key+".proxy", "expected a proxy URL such as http://proxy:3128, got %q", h.Proxy)
+		}
+	}
+	if (h.CertFile == "") != (h.KeyFile == "") {
+		add(key, "cert_file and key_file must be set together")
+	}
+	if h.ConnectTimeout < 0 || h.ResponseTimeout < 0 {
+		add(key, "timeouts must not be negative")
+	}
+}
+
+// where returns where key was set: its position in the file, or the
+// layer that set it. Keys missing from the file, like the name of a list
+// item, are placed at the nearest enclosing key.
+func (c *Config) where(key string) string {
+	field, _, _ := strings.Cut(key, "[")
+	source := c.sources[field]
+	if source == SourceFile || source == "" {
+		for k := key; k != ""; k = parentKey(k) {
+			if pos, ok := c.positions[k]; ok {
+				return pos
+			}
+		}
+	}
+	if source != "" {
+		return source
+	}
+	return SourceDefault
+}
+
+// parentKey strips the last part of a key: "hosts[1].url" becomes
+// "hosts[1]", which becomes "hosts".
+func parentKey(key string) string {
+	if i := strings.LastIndexAny(key, ".["); i >= 0 {
+		return key[:i]
+	}
+	return ""
+}
+
+func validURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
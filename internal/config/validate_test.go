@@ -0,0 +1,131 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		flags map[string]string
+		// wantProblems are the lines listed under the error heading.
+		wantProblems []string
+	}{
+		{
+			name: "misspelt key",
+			file: "version: 2\nrag:\n  topk: 3\n",
+			wantProblems: []string{
+				`line 3, column 3: rag.topk: unknown key, did you mean "top_k"?`,
+			},
+		},
+		{
+			name: "unknown key",
+			file: "version: 2\ncolour: red\n",
+			wantProblems: []string{
+				"line 2, column 1: colour: unknown key",
+			},
+		},
+		{
+			name: "wrong types",
+			file: "version: 2\nrag:\n  top_k: many\nretry:\n  backoff: soon\npersonas: none\n",
+			wantProblems: []string{
+				`line 3, column 10: rag.top_k: expected a whole number, got "many"`,
+				`line 5, column 12: retry.backoff: expected a duration such as 30s or 1m, got "soon"`,
+				`line 6, column 11: personas: expected a list, got "none"`,
+			},
+		},
+		{
+			name: "bad values",
+			file: `version: 2
+host: localhost
+hosts:
+  - name: gpu
+    url: http://gpu:11434
+  - name: gpu
+    url: gpu
+personas:
+  - prompt: be brief
+http:
+  cert_file: client.pem
+  proxy: proxy
+rag:
+  top_k: -1
+`,
+			wantProblems: []string{
+				`line 2, column 1: host: expected an http:// or https:// URL or the name of a configured host, got "localhost"`,
+				`line 12, column 3: http.proxy: expected a proxy URL such as http://proxy:3128, got "proxy"`,
+				"line 10, column 1: http: cert_file and key_file must be set together",
+				`line 6, column 5: hosts[1].name: duplicate host name "gpu"`,
+				`line 7, column 5: hosts[1].url: expected an http:// or https:// URL, got "gpu"`,
+				"line 9, column 5: personas[0].name: missing persona name",
+				"line 14, column 3: rag.top_k: must not be negative",
+			},
+		},
+		{
+			name:  "bad flag value",
+			file:  "version: 2\n",
+			flags: map[string]string{"fallbacks": "{big: ['']}"},
+			wantProblems: []string{
+				`flag: fallbacks: empty fallback for "big"`,
+			},
+		},
+		{
+			name: "bad version",
+			file: "version: two\n",
+			wantProblems: []string{
+				`line 1, column 10: version: expected a version number, got "two"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := setupConfig(t, tt.file)
+
+			_, err := Load(Options{Path: path, Flags: tt.flags})
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Load() error = %v, want a ValidationError", err)
+			}
+
+			want := "invalid config " + path + ":\n  " + strings.Join(tt.wantProblems, "\n  ")
+			if err.Error() != want {
+				t.Errorf("Load() error =\n%s\nwant\n%s", err, want)
+			}
+		})
+	}
+}
+
+func TestLoadSyntaxError(t *testing.T) {
+	path := setupConfig(t, "version: 2\nrag: [\n")
+
+	_, err := Load(Options{Path: path})
+	if err == nil || !strings.HasPrefix(err.Error(), "invalid config "+path+": ") {
+		t.Errorf("Load() error = %v, want a syntax error", err)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	fields := yamlFields(reflect.TypeFor[Config]())
+
+	tests := []struct {
+		key, want string
+	}{
+		{"hots", "hosts"},
+		{"hostt", "host"},
+		{"Host", "host"},
+		{"persona", "personas"},
+		{"fallback", "fallbacks"},
+		{"colour", ""},
+	}
+
+	for _, tt := range tests {
+		if got := suggest(tt.key, fields); got != tt.want {
+			t.Errorf("suggest(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
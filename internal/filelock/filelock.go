@@ -0,0 +1,107 @@
+package filelock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	lockSuffix   = ".lock"
+	breakSuffix  = ".break"
+	pollInterval = 10 * time.Millisecond
+
+	// staleAfter is how old a lock file may get before it is assumed to be
+	// left behind by a crashed process and broken.
+	staleAfter = 10 * time.Second
+)
+
+// ErrTimeout is returned when a lock could not be acquired in time.
+var ErrTimeout = errors.New("timed out waiting for file lock")
+
+// Lock is an advisory, cross-process lock guarding a single path.
+// It is implemented with an exclusive sibling "<path>.lock" file so it
+// behaves the same on every platform llmv is built for.
+type Lock struct {
+	path string
+}
+
+// Acquire blocks until the lock for path is held or timeout expires.
+func Acquire(path string, timeout time.Duration) (*Lock, error) {
+	lockPath := path + lockSuffix
+	deadline := time.Now().Add(timeout)
+
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, _ = f.WriteString(strconv.Itoa(os.Getpid()))
+			f.Close()
+			return &Lock{path: lockPath}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+
+		// Break locks left behind by a crashed instance.
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > staleAfter {
+			breakStale(lockPath, info)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w: %s", ErrTimeout, path)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// breakStale removes the lock file if it is still the stale one described
+// by stale. Waiters breaking a lock take turns through a second
+// "<path>.lock.break" file, so one of them cannot remove the fresh lock
+// another took right after breaking the stale one.
+func breakStale(lockPath string, stale os.FileInfo) {
+	breakPath := lockPath + breakSuffix
+	f, err := os.OpenFile(breakPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		// Someone else is breaking the lock, or crashed while doing so.
+		if info, err := os.Stat(breakPath); err == nil && time.Since(info.ModTime()) > staleAfter {
+			_ = os.Remove(breakPath)
+		}
+		time.Sleep(pollInterval)
+		return
+	}
+	f.Close()
+	defer os.Remove(breakPath)
+
+	info, err := os.Stat(lockPath)
+	if err == nil && os.SameFile(info, stale) && info.ModTime().Equal(stale.ModTime()) {
+		_ = os.Remove(lockPath)
+	}
+}
+
+// Release drops the lock. It is safe to call on a nil Lock.
+func (l *Lock) Release() error {
+	if l == nil {
+		return nil
+	}
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove lock file: %w", err)
+	}
+	return nil
+}
+
+// WriteFile writes data to a temporary file next to path and renames it
+// into place, so concurrent readers never observe a half-written file.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	tmp := fmt.Sprintf("%s.tmp-%d", path, os.Getpid())
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package filelock
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAcquire(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup prepares the lock file before Acquire is called.
+		setup   func(t *testing.T, lockPath string)
+		wantErr error
+	}{
+		{
+			name:  "free",
+			setup: func(*testing.T, string) {},
+		},
+		{
+			name: "held",
+			setup: func(t *testing.T, lockPath string) {
+				writeLock(t, lockPath, time.Now())
+			},
+			wantErr: ErrTimeout,
+		},
+		{
+			name: "stale",
+			setup: func(t *testing.T, lockPath string) {
+				writeLock(t, lockPath, time.Now().Add(-2*staleAfter))
+			},
+		},
+		{
+			name: "stale with abandoned breaker",
+			setup: func(t *testing.T, lockPath string) {
+				writeLock(t, lockPath, time.Now().Add(-2*staleAfter))
+				writeLock(t, lockPath+breakSuffix, time.Now().Add(-2*staleAfter))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "file")
+			tt.setup(t, path+lockSuffix)
+
+			lock, err := Acquire(path, 50*time.Millisecond)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Acquire() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if err := lock.Release(); err != nil {
+				t.Fatalf("Release() error = %v", err)
+			}
+			if _, err := os.Stat(path + lockSuffix); !os.IsNotExist(err) {
+				t.Errorf("lock file still exists after Release: %v", err)
+			}
+		})
+	}
+}
+
+func TestBreakStaleKeepsFreshLock(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "file") + lockSuffix
+	writeLock(t, lockPath, time.Now().Add(-2*staleAfter))
+	stale, err := os.Stat(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another waiter broke the stale lock and took a fresh one since.
+	if err := os.Remove(lockPath); err != nil {
+		t.Fatal(err)
+	}
+	writeLock(t, lockPath, time.Now())
+
+	breakStale(lockPath, stale)
+	if _, err := os.Stat(lockPath); err != nil {
+		t.Fatalf("fresh lock was removed: %v", err)
+	}
+	if _, err := os.Stat(lockPath + breakSuffix); !os.IsNotExist(err) {
+		t.Errorf("break file left behind: %v", err)
+	}
+}
+
+func TestAcquireExcludesConcurrentHolders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	writeLock(t, path+lockSuffix, time.Now().Add(-2*staleAfter))
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		holders int
+		maxSeen int
+	)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lock, err := Acquire(path, 5*time.Second)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			holders++
+			maxSeen = max(maxSeen, holders)
+			mu.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+
+			mu.Lock()
+			holders--
+			mu.Unlock()
+			lock.Release()
+		}()
+	}
+	wg.Wait()
+
+	if maxSeen != 1 {
+		t.Errorf("%d holders at once, want 1", maxSeen)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	for _, data := range []string{"first", "second"} {
+		if err := WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatalf("WriteFile(%q) error = %v", data, err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != data {
+			t.Errorf("file = %q, want %q", got, data)
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory has %d entries, want only the file", len(entries))
+	}
+}
+
+// writeLock creates a lock file with the given modification time.
+func writeLock(t *testing.T, path string, mtime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,339 @@
+package history
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/aj-seven/llmverse/pkg/chat"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// PassphraseEnv is the environment variable the passphrase for an
+	// encrypted history directory is read from.
+	PassphraseEnv = "LLMV_HISTORY_PASSPHRASE"
+
+	keyFileName = ".llmv-key.json"
+	keyCheck    = "llmverse"
+
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+	keyLen  = 32
+)
+
+// ErrBadPassphrase is returned when a passphrase does not match the one
+// the history directory was encrypted with.
+var ErrBadPassphrase = errors.New("incorrect history passphrase")
+
+// keyFile holds the parameters needed to derive the history key from a
+// passphrase, plus a sealed known value used to verify the passphrase.
+type keyFile struct {
+	Salt  []byte `json:"salt"`
+	N     int    `json:"n"`
+	R     int    `json:"r"`
+	P     int    `json:"p"`
+	Check string `json:"check"`
+}
+
+// sealedPayload is the part of a History that gets encrypted. The ID,
+// model, host, model options, timestamps, pin and revision stay in the
+// clear so the history list and revision checks keep working without
+// decrypting every file.
+type sealedPayload struct {
+	Title        string         `json:"title"`
+	Messages     []chat.Message `json:"messages"`
+	SystemPrompt string         `json:"system_prompt,omitempty"`
+	Draft        string         `json:"draft,omitempty"`
+	Persona      string         `json:"persona,omitempty"`
+	Collection   string         `json:"collection,omitempty"`
+	Tags         []string       `json:"tags,omitempty"`
+	Folder       string         `json:"folder,omitempty"`
+}
+
+// EncryptedStorage wraps another Storage and encrypts the content and
+// organization of every history (see sealedPayload) with AES-GCM before
+// they reach it.
+type EncryptedStorage struct {
+	inner Storage
+	aead  cipher.AEAD
+
+	mu         sync.Mutex
+	unreadable []error
+}
+
+// NewEncryptedStorage creates an encrypting wrapper around inner using a
+// key obtained from DeriveKey.
+func NewEncryptedStorage(inner Storage, key []byte) (*EncryptedStorage, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptedStorage{inner: inner, aead: aead}, nil
+}
+
+// DeriveKey derives the history key for dir from passphrase with scrypt.
+// The salt is created on first use and stored in dir, so every instance
+// derives the same key from the same passphrase.
+func DeriveKey(dir, passphrase string) ([]byte, error) {
+	path := filepath.Join(dir, keyFileName)
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return createKeyFile(path, passphrase)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	var kf keyFile
+	if err := json.Unmarshal(data, &kf); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal key file: %w", err)
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), kf.Salt, kf.N, kf.R, kf.P, keyLen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if plain, err := open(aead, kf.Check); err != nil || string(plain) != keyCheck {
+		return nil, ErrBadPassphrase
+	}
+
+	return key, nil
+}
+
+// IsEncrypted reports whether dir has been set up for encryption.
+func IsEncrypted(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, keyFileName))
+	return err == nil
+}
+
+// RemoveKey deletes the key file from dir once nothing is encrypted with
+// it anymore.
+func RemoveKey(dir string) error {
+	if err := os.Remove(filepath.Join(dir, keyFileName)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove key file: %w", err)
+	}
+	return nil
+}
+
+// SaveHistory encrypts and saves a history.
+func (s *EncryptedStorage) SaveHistory(h History, force bool) error {
+	if h.IsEmpty() {
+		return nil
+	}
+
+	sealed, err := s.seal(h)
+	if err != nil {
+		return err
+	}
+	return s.inner.SaveHistory(sealed, force)
+}
+
+// GetHistory loads and decrypts a single history.
+func (s *EncryptedStorage) GetHistory(id string) (History, error) {
+	h, err := s.inner.GetHistory(id)
+	if err != nil {
+		return History{}, err
+	}
+	return s.unseal(h)
+}
+
+// GetHistories loads and decrypts all histories. Histories that cannot be
+// decrypted, e.g. because they are corrupt or were sealed with another
+// key, are left out and reported by Unreadable.
+func (s *EncryptedStorage) GetHistories() ([]History, error) {
+	histories, err := s.inner.GetHistories()
+	if err != nil {
+		return nil, err
+	}
+
+	var unsealed []History
+	var unreadable []error
+	for _, h := range histories {
+		h, err := s.unseal(h)
+		if err != nil {
+			unreadable = append(unreadable, err)
+			continue
+		}
+		unsealed = append(unsealed, h)
+	}
+
+	s.mu.Lock()
+	s.unreadable = unreadable
+	s.mu.Unlock()
+	return unsealed, nil
+}
+
+// Unreadable returns the errors of the histories the last GetHistories
+// left out.
+func (s *EncryptedStorage) Unreadable() []error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]error(nil), s.unreadable...)
+}
+
+// DeleteHistory removes a history.
+func (s *EncryptedStorage) DeleteHistory(id string) error {
+	return s.inner.DeleteHistory(id)
+}
+
+// AcquireLease forwards to the wrapped storage.
+func (s *EncryptedStorage) AcquireLease(id string, force bool) error {
+	return s.inner.AcquireLease(id, force)
+}
+
+// ReleaseLease forwards to the wrapped storage.
+func (s *EncryptedStorage) ReleaseLease(id string) error {
+	return s.inner.ReleaseLease(id)
+}
+
+// Helpers
+
+func (s *EncryptedStorage) seal(h History) (History, error) {
+	data, err := json.Marshal(sealedPayload{
+		Title:        h.Title,
+		Messages:     h.Messages,
+		SystemPrompt: h.SystemPrompt,
+		Draft:        h.Draft,
+		Persona:      h.Persona,
+		Collection:   h.Collection,
+		Tags:         h.Tags,
+		Folder:       h.Folder,
+	})
+	if err != nil {
+		return History{}, fmt.Errorf("failed to marshal history: %w", err)
+	}
+
+	h.Sealed, err = seal(s.aead, data)
+	if err != nil {
+		return History{}, err
+	}
+	h.Title = ""
+	h.Messages = nil
+	h.SystemPrompt = ""
+	h.Draft = ""
+	h.Persona = ""
+	h.Collection = ""
+	h.Tags = nil
+	h.Folder = ""
+	return h, nil
+}
+
+// unseal decrypts h. Histories that were never encrypted are returned as
+// they are, so a directory can be migrated gradually.
+func (s *EncryptedStorage) unseal(h History) (History, error) {
+	if h.Sealed == "" {
+		return h, nil
+	}
+
+	data, err := open(s.aead, h.Sealed)
+	if err != nil {
+		return History{}, fmt.Errorf("failed to decrypt history %s: %w", h.ID, err)
+	}
+
+	var p sealedPayload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return History{}, fmt.Errorf("failed to unmarshal history %s: %w", h.ID, err)
+	}
+
+	h.Title = p.Title
+	h.Messages = p.Messages
+	h.SystemPrompt = p.SystemPrompt
+	h.Draft = p.Draft
+	// Histories sealed before these fields were encrypted keep them in
+	// the clear.
+	if p.Persona != "" {
+		h.Persona = p.Persona
+	}
+	if p.Collection != "" {
+		h.Collection = p.Collection
+	}
+	if len(p.Tags) > 0 {
+		h.Tags = p.Tags
+	}
+	if p.Folder != "" {
+		h.Folder = p.Folder
+	}
+	h.Sealed = ""
+	return h, nil
+}
+
+func createKeyFile(path, passphrase string) ([]byte, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, keyLen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	check, err := seal(aead, []byte(keyCheck))
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(keyFile{
+		Salt:  salt,
+		N:     scryptN,
+		R:     scryptR,
+		P:     scryptP,
+		Check: check,
+	}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal key file: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write key file: %w", err)
+	}
+	return key, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func seal(aead cipher.AEAD, plain []byte) (string, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, plain, nil)), nil
+}
+
+func open(aead cipher.AEAD, sealed string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
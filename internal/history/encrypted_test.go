@@ -0,0 +1,194 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aj-seven/llmverse/pkg/chat"
+)
+
+func TestDeriveKey(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dir := newTestStorage(t).Dir()
+
+	first, err := DeriveKey(dir, "correct horse")
+	if err != nil {
+		t.Fatalf("DeriveKey() on a new dir error = %v", err)
+	}
+	if !IsEncrypted(dir) {
+		t.Fatal("IsEncrypted() = false after the key was created")
+	}
+
+	tests := []struct {
+		passphrase string
+		wantErr    error
+	}{
+		{"correct horse", nil},
+		{"correct horse ", ErrBadPassphrase},
+		{"", ErrBadPassphrase},
+	}
+
+	for _, tt := range tests {
+		key, err := DeriveKey(dir, tt.passphrase)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("DeriveKey(%q) error = %v, want %v", tt.passphrase, err, tt.wantErr)
+		}
+		if err == nil && !slices.Equal(key, first) {
+			t.Errorf("DeriveKey(%q) derived another key", tt.passphrase)
+		}
+	}
+}
+
+func TestEncryptedStorageRoundTrip(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fs, enc := newTestEncryptedStorage(t)
+
+	h := History{
+		ID:           "chat",
+		Title:        "secret title",
+		Model:        "llama3",
+		Messages:     []chat.Message{{Role: "user", Content: "secret question"}},
+		CreatedAt:    time.Now().Truncate(time.Second),
+		UpdatedAt:    time.Now().Truncate(time.Second),
+		SystemPrompt: "secret prompt",
+		Persona:      "secret persona",
+		Collection:   "secret collection",
+		Draft:        "secret draft",
+		Tags:         []string{"secret-tag"},
+		Folder:       "secret/folder",
+	}
+	if err := enc.SaveHistory(h, true); err != nil {
+		t.Fatalf("SaveHistory() error = %v", err)
+	}
+
+	data, err := os.ReadFile(fs.getHistoryFilePath(h.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("stored file contains plain text:\n%s", data)
+	}
+
+	got, err := enc.GetHistory(h.ID)
+	if err != nil {
+		t.Fatalf("GetHistory() error = %v", err)
+	}
+	assertSameContent(t, got, h)
+
+	all, err := enc.GetHistories()
+	if err != nil || len(all) != 1 {
+		t.Fatalf("GetHistories() = %d histories, %v; want 1", len(all), err)
+	}
+	assertSameContent(t, all[0], h)
+}
+
+func TestEncryptedStorageMixedDir(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fs, enc := newTestEncryptedStorage(t)
+
+	foreign, err := NewEncryptedStorage(fs, make([]byte, keyLen))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saves := []struct {
+		id      string
+		storage Storage
+	}{
+		{"plain", fs},
+		{"sealed", enc},
+		{"foreign", foreign},
+	}
+	for _, s := range saves {
+		if err := s.storage.SaveHistory(testHistory(s.id), true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	corrupt := testHistory("corrupt")
+	corrupt.Sealed = "not base64!"
+	if err := fs.SaveHistory(corrupt, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Histories sealed before tags were encrypted keep their plain tags.
+	old := testHistory("old")
+	payload, err := json.Marshal(map[string]any{"title": old.Title, "messages": old.Messages})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old.Sealed, err = seal(enc.aead, payload); err != nil {
+		t.Fatal(err)
+	}
+	old.Title, old.Messages = "", nil
+	if err := fs.SaveHistory(old, true); err != nil {
+		t.Fatal(err)
+	}
+
+	histories, err := enc.GetHistories()
+	if err != nil {
+		t.Fatalf("GetHistories() error = %v", err)
+	}
+	var ids []string
+	for _, h := range histories {
+		ids = append(ids, h.ID)
+		assertSameContent(t, h, testHistory(h.ID))
+	}
+	slices.Sort(ids)
+	if want := []string{"old", "plain", "sealed"}; !slices.Equal(ids, want) {
+		t.Errorf("GetHistories() = %v, want %v", ids, want)
+	}
+
+	var unreadable []string
+	for _, err := range enc.Unreadable() {
+		unreadable = append(unreadable, err.Error())
+	}
+	slices.Sort(unreadable)
+	if len(unreadable) != 2 || !strings.Contains(unreadable[0], "corrupt") ||
+		!strings.Contains(unreadable[1], "foreign") {
+		t.Errorf("Unreadable() = %q, want the corrupt and foreign histories", unreadable)
+	}
+}
+
+// newTestEncryptedStorage returns a FileStorage under the test's HOME and
+// an EncryptedStorage wrapping it.
+func newTestEncryptedStorage(t *testing.T) (*FileStorage, *EncryptedStorage) {
+	t.Helper()
+	fs := newTestStorage(t)
+	key, err := DeriveKey(fs.Dir(), "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := NewEncryptedStorage(fs, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fs, enc
+}
+
+func testHistory(id string) History {
+	return History{
+		ID:        id,
+		Title:     "title of " + id,
+		Messages:  []chat.Message{{Role: "user", Content: "hello from " + id}},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Tags:      []string{"tag-" + id},
+	}
+}
+
+func assertSameContent(t *testing.T, got, want History) {
+	t.Helper()
+	if got.Title != want.Title || !slices.EqualFunc(got.Messages, want.Messages, func(a, b chat.Message) bool {
+		return a.Role == b.Role && a.Content == b.Content
+	}) || got.SystemPrompt != want.SystemPrompt || got.Persona != want.Persona ||
+		got.Collection != want.Collection || got.Draft != want.Draft ||
+		!slices.Equal(got.Tags, want.Tags) || got.Folder != want.Folder || got.Sealed != "" {
+		t.Errorf("history %s = %+v, want %+v", want.ID, got, want)
+	}
+}
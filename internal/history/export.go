@@ -0,0 +1,50 @@
+package history
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExportMarkdown renders a history as a Markdown document.
+func ExportMarkdown(h History) string {
+	var b strings.Builder
+
+	title := h.Title
+	if strings.TrimSpace(title) == "" {
+		title = "Chat"
+	}
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	fmt.Fprintf(&b, "- Model: %s\n", h.Model)
+	if h.Host != "" {
+		fmt.Fprintf(&b, "- Host: %s\n", h.Host)
+	}
+	fmt.Fprintf(&b, "- Created: %s\n", h.CreatedAt.Format("2006-01-02 15:04"))
+	fmt.Fprintf(&b, "- Updated: %s\n", h.UpdatedAt.Format("2006-01-02 15:04"))
+	if h.Persona != "" {
+		fmt.Fprintf(&b, "- Persona: %s\n", h.Persona)
+	}
+	b.WriteString("\n")
+
+	if h.SystemPrompt != "" {
+		fmt.Fprintf(&b, "## System\n\n%s\n\n", h.SystemPrompt)
+	}
+
+	for _, msg := range h.Messages {
+		role := "You"
+		if msg.Role == "assistant" {
+			role = h.Model
+			if msg.Model != "" {
+				role = msg.Model
+			}
+		}
+		fmt.Fprintf(&b, "## %s\n\n%s\n\n", role, strings.TrimSpace(msg.Content))
+		for _, a := range msg.Attachments {
+			fmt.Fprintf(&b, "- Attached: `%s`\n", a.Path)
+		}
+		if len(msg.Attachments) > 0 {
+			b.WriteString("\n")
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,117 @@
+package history
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+const filterDateLayout = "2006-01-02"
+
+// Filter narrows down a history listing. It is parsed from a query such
+// as "tag:work folder:api model:llama host:gpu since:2025-01-01 timeout",
+// where bare words must all appear in the title.
+type Filter struct {
+	Tag    string
+	Folder string
+	Model  string
+	Host   string
+	Since  time.Time
+	Until  time.Time
+	Words  []string
+}
+
+// ParseFilter parses a filter query. Unknown keys and malformed dates are
+// treated as plain words.
+func ParseFilter(query string) Filter {
+	var f Filter
+
+	for _, field := range strings.Fields(query) {
+		key, value, ok := strings.Cut(field, ":")
+		if !ok || value == "" {
+			f.Words = append(f.Words, strings.ToLower(field))
+			continue
+		}
+
+		key = strings.ToLower(key)
+		switch key {
+		case "tag":
+			f.Tag = strings.ToLower(value)
+		case "folder":
+			f.Folder = strings.ToLower(value)
+		case "model":
+			f.Model = strings.ToLower(value)
+		case "host":
+			f.Host = strings.ToLower(value)
+		case "since", "until":
+			t, err := time.ParseInLocation(filterDateLayout, value, time.Local)
+			if err != nil {
+				f.Words = append(f.Words, strings.ToLower(field))
+				continue
+			}
+			if key == "since" {
+				f.Since = t
+			} else {
+				// Inclusive of the whole day.
+				f.Until = t.AddDate(0, 0, 1)
+			}
+		default:
+			f.Words = append(f.Words, strings.ToLower(field))
+		}
+	}
+
+	return f
+}
+
+// IsZero reports whether the filter matches everything.
+func (f Filter) IsZero() bool {
+	return f.Tag == "" && f.Folder == "" && f.Model == "" && f.Host == "" &&
+		f.Since.IsZero() && f.Until.IsZero() && len(f.Words) == 0
+}
+
+// Match reports whether h satisfies every part of the filter.
+func (f Filter) Match(h History) bool {
+	if f.Tag != "" && !slices.ContainsFunc(h.Tags, func(t string) bool {
+		return strings.EqualFold(t, f.Tag)
+	}) {
+		return false
+	}
+	if f.Folder != "" && !strings.Contains(strings.ToLower(h.Folder), f.Folder) {
+		return false
+	}
+	if f.Model != "" && !strings.Contains(strings.ToLower(h.Model), f.Model) {
+		return false
+	}
+	if f.Host != "" && !strings.Contains(strings.ToLower(h.Host), f.Host) {
+		return false
+	}
+	if !f.Since.IsZero() && h.UpdatedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !h.UpdatedAt.Before(f.Until) {
+		return false
+	}
+
+	title := strings.ToLower(h.Title)
+	for _, w := range f.Words {
+		if !strings.Contains(title, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseTags splits a comma or space separated list of tags, dropping
+// duplicates and empty entries.
+func ParseTags(s string) []string {
+	var tags []string
+	for _, t := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' '
+	}) {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "#")
+		if t != "" && !slices.Contains(tags, t) {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
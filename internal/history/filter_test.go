@@ -0,0 +1,105 @@
+package history
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseFilter(t *testing.T) {
+	day := func(s string) time.Time {
+		d, err := time.ParseInLocation(filterDateLayout, s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	tests := []struct {
+		query string
+		want  Filter
+	}{
+		{"", Filter{}},
+		{"Timeout Bug", Filter{Words: []string{"timeout", "bug"}}},
+		{"tag:Work folder:API model:Llama host:GPU", Filter{Tag: "work", Folder: "api", Model: "llama", Host: "gpu"}},
+		{"since:2025-01-01 until:2025-01-31", Filter{Since: day("2025-01-01"), Until: day("2025-02-01")}},
+		{"Since:2025-01-01 UNTIL:2025-01-31", Filter{Since: day("2025-01-01"), Until: day("2025-02-01")}},
+		{"since:yesterday", Filter{Words: []string{"since:yesterday"}}},
+		{"color:red tag:", Filter{Words: []string{"color:red", "tag:"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got := ParseFilter(tt.query)
+			if got.Tag != tt.want.Tag || got.Folder != tt.want.Folder || got.Model != tt.want.Model ||
+				got.Host != tt.want.Host || !got.Since.Equal(tt.want.Since) || !got.Until.Equal(tt.want.Until) ||
+				!slices.Equal(got.Words, tt.want.Words) {
+				t.Errorf("ParseFilter(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+			if got.IsZero() != (tt.query == "") {
+				t.Errorf("ParseFilter(%q).IsZero() = %v", tt.query, got.IsZero())
+			}
+		})
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	h := History{
+		Title:     "Fix the HTTP timeout",
+		Model:     "llama3.2:latest",
+		Host:      "gpu-box",
+		Tags:      []string{"Work", "go"},
+		Folder:    "projects/api",
+		UpdatedAt: time.Date(2025, 3, 15, 12, 0, 0, 0, time.Local),
+	}
+
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"", true},
+		{"timeout", true},
+		{"http fix", true},
+		{"timeout retry", false},
+		{"tag:work", true},
+		{"tag:wor", false},
+		{"folder:api", true},
+		{"folder:web", false},
+		{"model:llama", true},
+		{"model:mistral", false},
+		{"host:gpu", true},
+		{"host:cpu", false},
+		{"since:2025-03-15", true},
+		{"since:2025-03-16", false},
+		{"until:2025-03-15", true},
+		{"until:2025-03-14", false},
+		{"tag:go folder:api timeout", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := ParseFilter(tt.query).Match(h); got != tt.want {
+				t.Errorf("ParseFilter(%q).Match() = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"work", []string{"work"}},
+		{"work, go  #api", []string{"work", "go", "api"}},
+		{"go,go,#go", []string{"go"}},
+		{" , # ,", nil},
+	}
+
+	for _, tt := range tests {
+		if got := ParseTags(tt.in); !slices.Equal(got, tt.want) {
+			t.Errorf("ParseTags(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
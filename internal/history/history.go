@@ -1,11 +1,22 @@
 package history
 
 import (
+	"errors"
 	"time"
 
 	"github.com/aj-seven/llmverse/pkg/chat"
 )
 
+var (
+	// ErrConflict is returned by SaveHistory when the history was modified
+	// on disk (usually by another llmv instance) since it was loaded.
+	ErrConflict = errors.New("history was modified by another instance")
+
+	// ErrLeased is returned when a history is already open in another
+	// llmv instance.
+	ErrLeased = errors.New("history is open in another instance")
+)
+
 // History represents a single chat history.
 type History struct {
 	ID        string         `json:"id"`
@@ -14,12 +25,86 @@ type History struct {
 	Messages  []chat.Message `json:"messages"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
+
+	// SystemPrompt is sent before the messages of this chat. Persona names
+	// the library entry it came from, if any.
+	SystemPrompt string `json:"system_prompt,omitempty"`
+	Persona      string `json:"persona,omitempty"`
+
+	// Options are model parameters (e.g. temperature) sent with every
+	// request of this chat.
+	Options map[string]any `json:"options,omitempty"`
+
+	// Collection names the document collection questions in this chat
+	// are answered from.
+	Collection string `json:"collection,omitempty"`
+
+	// Host names the Ollama endpoint serving this chat; the default host
+	// when empty.
+	Host string `json:"host,omitempty"`
+
+	// Draft is the unsent text left in the chat input.
+	Draft string `json:"draft,omitempty"`
+
+	// Tags, Folder and Pinned organize the history list.
+	Tags   []string `json:"tags,omitempty"`
+	Folder string   `json:"folder,omitempty"`
+	Pinned bool     `json:"pinned,omitempty"`
+
+	// Revision is incremented on every save and used to detect concurrent
+	// modifications from other instances.
+	Revision int `json:"revision"`
+
+	// Sealed holds the encrypted title and messages when the history is
+	// stored through an EncryptedStorage.
+	Sealed string `json:"sealed,omitempty"`
+
+	// TrashedAt is set while the chat sits in the trash.
+	TrashedAt *time.Time `json:"trashed_at,omitempty"`
+	// ArchivedAt is set while the chat is archived.
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+
+	// Size is the stored size in bytes, filled in by the storage on load.
+	Size int64 `json:"-"`
+}
+
+// Scope selects which histories a listing returns.
+type Scope int
+
+const (
+	ScopeActive Scope = iota
+	ScopeArchived
+	ScopeTrashed
+)
+
+// Scope reports which listing the history belongs to.
+func (h History) Scope() Scope {
+	switch {
+	case h.TrashedAt != nil:
+		return ScopeTrashed
+	case h.ArchivedAt != nil:
+		return ScopeArchived
+	default:
+		return ScopeActive
+	}
+}
+
+// IsEmpty reports whether the history has nothing worth persisting.
+func (h History) IsEmpty() bool {
+	return len(h.Messages) == 0 && h.Draft == "" && h.Sealed == ""
 }
 
 // Storage defines the interface for history storage operations.
 type Storage interface {
-	SaveHistory(history History) error
+	// SaveHistory persists h. It returns ErrConflict if the stored copy has
+	// a different revision than h, unless force is set.
+	SaveHistory(history History, force bool) error
 	GetHistory(id string) (History, error)
 	GetHistories() ([]History, error)
 	DeleteHistory(id string) error
+
+	// AcquireLease marks a history as open by this instance. It returns
+	// ErrLeased if another live instance holds it, unless force is set.
+	AcquireLease(id string, force bool) error
+	ReleaseLease(id string) error
 }
@@ -1,6 +1,9 @@
 package history
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,72 +19,193 @@ type Manager struct {
 	storage Storage
 	mu      sync.RWMutex
 
+	// tabs holds the open histories in tab order. currentHistory is the
+	// active one and always one of them.
+	tabs           []*History
 	currentHistory *History
+
+	stopHeartbeat chan struct{}
+	closeOnce     sync.Once
 }
 
 // NewManager creates a new history manager.
 func NewManager(storage Storage) *Manager {
-	return &Manager{
-		storage: storage,
+	m := &Manager{
+		storage:       storage,
+		stopHeartbeat: make(chan struct{}),
 	}
+	go m.heartbeat()
+	return m
 }
 
-// Close persists the current chat session to disk.
+// Close persists all open chat sessions to disk and releases their leases.
 func (m *Manager) Close() {
 	if m == nil {
 		return
 	}
-	m.SaveCurrent()
+	m.closeOnce.Do(func() {
+		close(m.stopHeartbeat)
+	})
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, h := range m.tabs {
+		m.leave(h)
+	}
 }
 
 // SaveCurrent explicitly saves the current history to storage if it exists.
-func (m *Manager) SaveCurrent() {
+// It returns ErrConflict if another instance saved the same history since
+// it was loaded; the caller decides whether to overwrite or reload.
+func (m *Manager) SaveCurrent() error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	return m.save(m.currentHistory, false)
+}
 
-	if m.currentHistory == nil || len(m.currentHistory.Messages) == 0 {
-		return
-	}
-	_ = m.storage.SaveHistory(*m.currentHistory)
+// SaveHistory saves the open history with the given ID, like SaveCurrent.
+func (m *Manager) SaveHistory(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.save(m.tab(id), false)
 }
 
-// NewHistory creates a new, empty chat history.
-// It saves the previously active history before creating a new one.
-func (m *Manager) NewHistory(model string) *History {
-	m.SaveCurrent() // Save the old history first.
+// ForceSaveHistory saves the open history with the given ID, overwriting
+// any changes made by other instances.
+func (m *Manager) ForceSaveHistory(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.save(m.tab(id), true)
+}
 
+// ReloadHistory discards in-memory changes to the open history with the
+// given ID and reloads it from storage.
+func (m *Manager) ReloadHistory(id string) (*History, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	h := &History{
-		ID:        uuid.New().String(),
-		Model:     model,
-		Messages:  []chat.Message{},
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+	i := m.tabIndex(id)
+	if i < 0 {
+		return nil, nil
 	}
 
+	h, err := m.storage.GetHistory(id)
+	if err != nil {
+		return nil, err
+	}
+
+	m.setTab(i, &h)
+	return &h, nil
+}
+
+// NewHistory creates a new, empty chat history in place of the current
+// one. It saves the previously active history before creating a new one.
+func (m *Manager) NewHistory(model string) *History {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.leave(m.currentHistory) // Save the old history first.
+
+	h := m.newHistory(model)
+	m.setTab(m.tabIndex(m.currentID()), h)
+	return h
+}
+
+// NewTab creates a new, empty chat history next to the current one and
+// makes it current. The other open histories stay open.
+func (m *Manager) NewTab(model string) *History {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h := m.newHistory(model)
+	i := m.tabIndex(m.currentID()) + 1
+	m.tabs = append(m.tabs[:i], append([]*History{h}, m.tabs[i:]...)...)
 	m.currentHistory = h
-	// Immediately save the new empty history to ensure it's listed.
-	_ = m.storage.SaveHistory(*m.currentHistory)
 	return h
 }
 
-// LoadHistory loads a history from storage and sets it as the current one.
-// It saves the previously active history before loading a new one.
-func (m *Manager) LoadHistory(id string) (*History, error) {
-	m.SaveCurrent() // Save the old history first.
+// Tabs returns the open histories in tab order.
+func (m *Manager) Tabs() []*History {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]*History(nil), m.tabs...)
+}
+
+// Tab returns the open history with the given ID, or nil if it is not
+// open.
+func (m *Manager) Tab(id string) *History {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.tab(id)
+}
+
+// SwitchTab makes the open history with the given ID current. It reports
+// false if no such history is open.
+func (m *Manager) SwitchTab(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h := m.tab(id)
+	if h == nil {
+		return false
+	}
+	m.currentHistory = h
+	return true
+}
+
+// CloseTab saves and closes the open history with the given ID. If it was
+// current, its neighbour becomes current; closing the last one opens a new
+// empty history.
+func (m *Manager) CloseTab(id string) *History {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.tabIndex(id)
+	if i < 0 {
+		return m.currentHistory
+	}
+
+	closed := m.tabs[i]
+	m.leave(closed)
+	m.tabs = append(m.tabs[:i], m.tabs[i+1:]...)
+
+	if len(m.tabs) == 0 {
+		h := m.newHistory(closed.Model)
+		m.tabs = []*History{h}
+		m.currentHistory = h
+	} else if m.currentHistory == closed {
+		m.currentHistory = m.tabs[min(i, len(m.tabs)-1)]
+	}
+	return m.currentHistory
+}
 
+// LoadHistory loads a history from storage and sets it as the current one.
+// It returns ErrLeased if the history is open in another instance, unless
+// force is set. The previously active history is saved first. A history
+// that is already open in a tab is switched to instead.
+func (m *Manager) LoadHistory(id string, force bool) (*History, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if h := m.tab(id); h != nil {
+		m.currentHistory = h
+		return h, nil
+	}
+
+	if err := m.storage.AcquireLease(id, force); err != nil {
+		return nil, err
+	}
+
 	h, err := m.storage.GetHistory(id)
 	if err != nil {
+		_ = m.storage.ReleaseLease(id)
 		return nil, err
 	}
 
-	m.currentHistory = &h
-	return m.currentHistory, nil
+	m.leave(m.currentHistory) // Save the old history first.
+
+	m.setTab(m.tabIndex(m.currentID()), &h)
+	return &h, nil
 }
 
 // GetCurrentHistory returns a pointer to the current in-memory history.
@@ -91,15 +215,107 @@ func (m *Manager) GetCurrentHistory() *History {
 	return m.currentHistory
 }
 
-// GetAllHistories returns all histories from the storage.
+// GetAllHistories returns all active histories from the storage, leaving
+// out archived and trashed ones.
 func (m *Manager) GetAllHistories() ([]History, error) {
-	// Ensure the current session is saved so it appears in the list.
-	m.SaveCurrent()
-	return m.storage.GetHistories()
+	return m.GetHistories(ScopeActive)
 }
 
-// DeleteHistory removes a history from storage. If it's the current
-// history, a new empty one is created.
+// GetHistories returns the stored histories in the given scope.
+func (m *Manager) GetHistories(scope Scope) ([]History, error) {
+	// Ensure the open sessions are saved so they appear in the list.
+	m.mu.Lock()
+	for _, h := range m.tabs {
+		_ = m.save(h, false)
+	}
+	m.mu.Unlock()
+
+	all, err := m.storage.GetHistories()
+	if err != nil {
+		return nil, err
+	}
+
+	histories := all[:0]
+	for _, h := range all {
+		if h.Scope() == scope {
+			histories = append(histories, h)
+		}
+	}
+	return histories, nil
+}
+
+// ResolveID returns the ID of the stored history whose ID is id or starts
+// with it.
+func (m *Manager) ResolveID(id string) (string, error) {
+	all, err := m.storage.GetHistories()
+	if err != nil {
+		return "", err
+	}
+
+	var found []string
+	for _, h := range all {
+		if h.ID == id {
+			return id, nil
+		}
+		if strings.HasPrefix(h.ID, id) {
+			found = append(found, h.ID)
+		}
+	}
+
+	switch {
+	case id == "" || len(found) == 0:
+		return "", fmt.Errorf("no chat with ID %q", id)
+	case len(found) > 1:
+		return "", fmt.Errorf("chat ID %q is ambiguous (%d matches)", id, len(found))
+	}
+	return found[0], nil
+}
+
+// EditHistory applies fn to a stored history and saves it. If the history
+// is open the in-memory copy is edited instead.
+func (m *Manager) EditHistory(id string, fn func(*History)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.editHistory(id, fn)
+}
+
+// TrashHistory moves a history to the trash. If it's open, a new empty one
+// takes its place.
+func (m *Manager) TrashHistory(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if err := m.editHistory(id, func(h *History) {
+		h.TrashedAt = &now
+	}); err != nil {
+		return err
+	}
+
+	if i := m.tabIndex(id); i >= 0 {
+		m.replace(i)
+	}
+	return nil
+}
+
+// RestoreHistory moves a history out of the trash or archive.
+func (m *Manager) RestoreHistory(id string) error {
+	return m.EditHistory(id, func(h *History) {
+		h.TrashedAt = nil
+		h.ArchivedAt = nil
+	})
+}
+
+// ArchiveHistory hides a history from the main list without deleting it.
+func (m *Manager) ArchiveHistory(id string) error {
+	now := time.Now()
+	return m.EditHistory(id, func(h *History) {
+		h.ArchivedAt = &now
+	})
+}
+
+// DeleteHistory removes a history from storage. If it's open, a new empty
+// one takes its place.
 func (m *Manager) DeleteHistory(id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -109,60 +325,331 @@ func (m *Manager) DeleteHistory(id string) error {
 		return err
 	}
 
-	if m.currentHistory != nil && m.currentHistory.ID == id {
-		// If we deleted the current chat, start a new one.
-		h := &History{
-			ID:        uuid.New().String(),
-			Model:     m.currentHistory.Model, // Keep the same model
-			Messages:  []chat.Message{},
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-		}
-		m.currentHistory = h
+	if i := m.tabIndex(id); i >= 0 {
+		// If we deleted an open chat, start a new one.
+		m.replace(i)
 	}
 	return nil
 }
 
-// AddUserMessage adds a user message to the current history and prepares
-// an empty response from the assistant.
-func (m *Manager) AddUserMessage(content string) {
+// SetSystemPrompt sets the system prompt of the current history. persona
+// names the library entry the prompt came from, or is empty for a custom
+// prompt.
+func (m *Manager) SetSystemPrompt(persona, prompt string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	if m.currentHistory == nil {
 		return
 	}
+	m.currentHistory.Persona = persona
+	m.currentHistory.SystemPrompt = prompt
+}
 
-	// If this is the first user message, set it as the title.
-	if len(m.currentHistory.Messages) == 0 {
-		m.currentHistory.Title = content
+// SetOption sets a model option for the current history. A nil value
+// removes the option.
+func (m *Manager) SetOption(key string, value any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.currentHistory == nil {
+		return
 	}
+	if value == nil {
+		delete(m.currentHistory.Options, key)
+		return
+	}
+	if m.currentHistory.Options == nil {
+		m.currentHistory.Options = map[string]any{}
+	}
+	m.currentHistory.Options[key] = value
+}
+
+// SetCollection sets the document collection of the current history. An
+// empty name turns retrieval off.
+func (m *Manager) SetCollection(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.currentHistory == nil {
+		return
+	}
+	m.currentHistory.Collection = name
+}
 
-	m.currentHistory.Messages = append(m.currentHistory.Messages, chat.Message{
-		Role:    "user",
-		Content: content,
+// SetHost sets the Ollama endpoint serving the current history.
+func (m *Manager) SetHost(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.currentHistory == nil {
+		return
+	}
+	m.currentHistory.Host = name
+}
+
+// SetLastUserSources stores the retrieved sources on the last user
+// message of the open history with the given ID.
+func (m *Manager) SetLastUserSources(id string, sources []chat.Source) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h := m.tab(id)
+	if h == nil {
+		return
+	}
+	for i := len(h.Messages) - 1; i >= 0; i-- {
+		if h.Messages[i].Role == "user" {
+			h.Messages[i].Sources = sources
+			return
+		}
+	}
+}
+
+// SetLastAssistantModel records the model answering the last assistant
+// message of the open history with the given ID.
+func (m *Manager) SetLastAssistantModel(id, model, host string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h := m.tab(id)
+	if h == nil || len(h.Messages) == 0 {
+		return
+	}
+	if last := &h.Messages[len(h.Messages)-1]; last.Role == "assistant" {
+		last.Model, last.Host = model, host
+	}
+}
+
+// ResetLastAssistantMessage clears the last assistant response of the open
+// history with the given ID so it can be generated again. It reports
+// whether there was one to reset.
+func (m *Manager) ResetLastAssistantMessage(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h := m.tab(id)
+	if h == nil || len(h.Messages) == 0 {
+		return false
+	}
+
+	lastIndex := len(h.Messages) - 1
+	if h.Messages[lastIndex].Role != "assistant" {
+		return false
+	}
+	h.Messages[lastIndex].Content = ""
+	h.Messages[lastIndex].Model, h.Messages[lastIndex].Host = "", ""
+	h.UpdatedAt = time.Now()
+	return true
+}
+
+// AddUserMessage adds a user message, with any attached files, to the open
+// history with the given ID and prepares an empty response from the
+// assistant.
+func (m *Manager) AddUserMessage(id, content string, attachments ...chat.Attachment) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h := m.tab(id)
+	if h == nil {
+		return
+	}
+
+	// If this is the first user message, use its first line as the title.
+	if len(h.Messages) == 0 {
+		h.Title = TitleFromPrompt(content)
+	}
+
+	h.Messages = append(h.Messages, chat.Message{
+		Role:        "user",
+		Content:     content,
+		Attachments: attachments,
 	})
 	// Add a placeholder for the assistant's response.
-	m.currentHistory.Messages = append(m.currentHistory.Messages, chat.Message{
+	h.Messages = append(h.Messages, chat.Message{
 		Role:    "assistant",
 		Content: "",
 	})
-	m.currentHistory.UpdatedAt = time.Now()
+	h.UpdatedAt = time.Now()
 }
 
-// UpdateAssistantMessage updates the content of the last assistant message.
-// This is used for streaming responses.
-func (m *Manager) UpdateAssistantMessage(chunk string) {
+// UpdateAssistantMessage appends to the content of the last assistant
+// message of the open history with the given ID. This is used for
+// streaming responses, which may arrive for histories in background tabs.
+func (m *Manager) UpdateAssistantMessage(id, chunk string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if m.currentHistory == nil || len(m.currentHistory.Messages) == 0 {
+	h := m.tab(id)
+	if h == nil || len(h.Messages) == 0 {
 		return
 	}
 
-	lastIndex := len(m.currentHistory.Messages) - 1
-	if m.currentHistory.Messages[lastIndex].Role == "assistant" {
-		m.currentHistory.Messages[lastIndex].Content += chunk
-		m.currentHistory.UpdatedAt = time.Now()
+	lastIndex := len(h.Messages) - 1
+	if h.Messages[lastIndex].Role == "assistant" {
+		h.Messages[lastIndex].Content += chunk
+		h.UpdatedAt = time.Now()
+	}
+}
+
+// SetDraft records the unsent input of the open history with the given ID.
+// It is written out with the next save, including the one on close.
+func (m *Manager) SetDraft(id, draft string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if h := m.tab(id); h != nil {
+		h.Draft = draft
+	}
+}
+
+// editHistory is EditHistory without locking. The caller must hold m.mu.
+func (m *Manager) editHistory(id string, fn func(*History)) error {
+	if h := m.tab(id); h != nil {
+		fn(h)
+		return m.save(h, false)
+	}
+
+	h, err := m.storage.GetHistory(id)
+	if err != nil {
+		return err
+	}
+	fn(&h)
+	return m.storage.SaveHistory(h, false)
+}
+
+// newHistory creates an empty history and leases it. It is not opened in
+// a tab yet. The caller must hold m.mu.
+func (m *Manager) newHistory(model string) *History {
+	h := &History{
+		ID:        uuid.New().String(),
+		Model:     model,
+		Messages:  []chat.Message{},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	_ = m.storage.AcquireLease(h.ID, true)
+	return h
+}
+
+// tab returns the open history with the given ID, or nil. The caller must
+// hold m.mu.
+func (m *Manager) tab(id string) *History {
+	if i := m.tabIndex(id); i >= 0 {
+		return m.tabs[i]
+	}
+	return nil
+}
+
+// tabIndex returns the position of the open history with the given ID, or
+// -1. The caller must hold m.mu.
+func (m *Manager) tabIndex(id string) int {
+	for i, h := range m.tabs {
+		if h.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// currentID returns the ID of the current history, or "". The caller must
+// hold m.mu.
+func (m *Manager) currentID() string {
+	if m.currentHistory == nil {
+		return ""
+	}
+	return m.currentHistory.ID
+}
+
+// setTab puts h in tab i, or in a new tab if i is out of range, keeping
+// it current if the history it replaces was. The caller must hold m.mu.
+func (m *Manager) setTab(i int, h *History) {
+	if i < 0 || i >= len(m.tabs) {
+		m.tabs = append(m.tabs, h)
+		m.currentHistory = h
+		return
+	}
+	if m.tabs[i] == m.currentHistory {
+		m.currentHistory = h
+	}
+	m.tabs[i] = h
+}
+
+// replace swaps the open history in tab i, which no longer exists in the
+// active list, for a new empty one. The caller must hold m.mu.
+func (m *Manager) replace(i int) {
+	old := m.tabs[i]
+	_ = m.storage.ReleaseLease(old.ID)
+	m.setTab(i, m.newHistory(old.Model)) // Keep the same model
+}
+
+// save writes h and mirrors the revision bump done by the storage. A chat
+// that was only saved for its draft is removed again once the draft is
+// cleared. The caller must hold m.mu.
+func (m *Manager) save(h *History, force bool) error {
+	if h == nil {
+		return nil
+	}
+	if h.IsEmpty() {
+		if h.Revision > 0 {
+			_ = m.storage.DeleteHistory(h.ID)
+			h.Revision = 0
+		}
+		return nil
+	}
+	if err := m.storage.SaveHistory(*h, force); err != nil {
+		return err
+	}
+	h.Revision++
+	return nil
+}
+
+// leave saves h before it is closed or replaced and releases its lease.
+// If another instance changed it in the meantime, the local copy is kept
+// as a separate chat rather than being lost or overwriting theirs. The
+// caller must hold m.mu.
+func (m *Manager) leave(h *History) {
+	if h == nil {
+		return
+	}
+
+	if err := m.save(h, false); errors.Is(err, ErrConflict) {
+		conflicted := *h
+		conflicted.ID = uuid.New().String()
+		conflicted.Title = strings.TrimSpace(conflicted.Title + " (conflicted copy)")
+		conflicted.Revision = 0
+		_ = m.storage.SaveHistory(conflicted, true)
+	}
+	_ = m.storage.ReleaseLease(h.ID)
+}
+
+// heartbeat keeps the leases on the open histories fresh so other
+// instances keep treating them as open.
+func (m *Manager) heartbeat() {
+	ticker := time.NewTicker(LeaseTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stopHeartbeat:
+			return
+		case <-ticker.C:
+			m.mu.RLock()
+			for _, h := range m.tabs {
+				_ = m.storage.AcquireLease(h.ID, false)
+			}
+			m.mu.RUnlock()
+		}
+	}
+}
+
+// TitleFromPrompt returns the first non-empty line of a prompt, the title
+// a chat gets until it is renamed or a title is generated.
+func TitleFromPrompt(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
 	}
+	return ""
 }
@@ -0,0 +1,112 @@
+package history
+
+import (
+	"sort"
+	"time"
+)
+
+// RetentionPolicy limits how long and how much history is kept.
+// Zero values disable the corresponding rule.
+type RetentionPolicy struct {
+	// TrashDays purges trashed chats this many days after deletion.
+	TrashDays int
+	// ArchiveAfterMonths archives chats not updated for this many months.
+	// Pinned chats are never archived.
+	ArchiveAfterMonths int
+	// MaxSizeBytes caps the total stored size of all histories. When it is
+	// exceeded, trashed chats and then archived chats are purged, oldest
+	// first. Active chats are never removed automatically.
+	MaxSizeBytes int64
+}
+
+// RetentionResult summarizes what ApplyRetention changed.
+type RetentionResult struct {
+	Purged   int
+	Archived int
+}
+
+// ApplyRetention enforces p on the stored histories. Histories open here
+// or in another instance are never touched.
+func (m *Manager) ApplyRetention(p RetentionPolicy) (RetentionResult, error) {
+	var res RetentionResult
+
+	histories, err := m.storage.GetHistories()
+	if err != nil {
+		return res, err
+	}
+
+	open := map[string]bool{}
+	for _, h := range m.Tabs() {
+		open[h.ID] = true
+	}
+
+	now := time.Now()
+	var kept []History
+	var total int64
+
+	for _, h := range histories {
+		if open[h.ID] {
+			total += h.Size
+			continue
+		}
+
+		switch h.Scope() {
+		case ScopeTrashed:
+			if p.TrashDays > 0 && now.Sub(*h.TrashedAt) > time.Duration(p.TrashDays)*24*time.Hour {
+				if err := m.withLease(h.ID, func() error { return m.DeleteHistory(h.ID) }); err == nil {
+					res.Purged++
+					continue
+				}
+			}
+
+		case ScopeActive:
+			if p.ArchiveAfterMonths > 0 && !h.Pinned && h.UpdatedAt.Before(now.AddDate(0, -p.ArchiveAfterMonths, 0)) {
+				if err := m.withLease(h.ID, func() error { return m.ArchiveHistory(h.ID) }); err == nil {
+					res.Archived++
+					archived := now
+					h.ArchivedAt = &archived
+				}
+			}
+		}
+
+		kept = append(kept, h)
+		total += h.Size
+	}
+
+	if p.MaxSizeBytes <= 0 || total <= p.MaxSizeBytes {
+		return res, nil
+	}
+
+	// Over the size cap: purge trash first, then the archive, each oldest
+	// first.
+	sort.SliceStable(kept, func(i, j int) bool {
+		si, sj := kept[i].Scope(), kept[j].Scope()
+		if si != sj {
+			return si > sj
+		}
+		return kept[i].UpdatedAt.Before(kept[j].UpdatedAt)
+	})
+
+	for _, h := range kept {
+		if total <= p.MaxSizeBytes || h.Scope() == ScopeActive {
+			break
+		}
+		if err := m.withLease(h.ID, func() error { return m.DeleteHistory(h.ID) }); err != nil {
+			continue
+		}
+		total -= h.Size
+		res.Purged++
+	}
+
+	return res, nil
+}
+
+// withLease runs fn while holding the lease on a history, so chats open in
+// another instance are skipped with ErrLeased.
+func (m *Manager) withLease(id string, fn func() error) error {
+	if err := m.storage.AcquireLease(id, false); err != nil {
+		return err
+	}
+	defer m.storage.ReleaseLease(id)
+	return fn()
+}
@@ -0,0 +1,223 @@
+package history
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aj-seven/llmverse/pkg/chat"
+)
+
+func TestApplyRetention(t *testing.T) {
+	now := time.Now()
+	daysAgo := func(d int) *time.Time {
+		t := now.AddDate(0, 0, -d)
+		return &t
+	}
+
+	tests := []struct {
+		name      string
+		histories []History
+		// leased names histories open in another instance.
+		leased []string
+		// policy builds the policy from the stored size of each history.
+		policy       func(size map[string]int64) RetentionPolicy
+		want         RetentionResult
+		wantKept     []string
+		wantArchived []string
+	}{
+		{
+			name: "disabled",
+			histories: []History{
+				{ID: "old", UpdatedAt: now.AddDate(-5, 0, 0)},
+				{ID: "trashed", UpdatedAt: now, TrashedAt: daysAgo(365)},
+			},
+			policy:   func(map[string]int64) RetentionPolicy { return RetentionPolicy{} },
+			wantKept: []string{"old", "trashed"},
+		},
+		{
+			name: "purge old trash",
+			histories: []History{
+				{ID: "recent", UpdatedAt: now, TrashedAt: daysAgo(2)},
+				{ID: "expired", UpdatedAt: now, TrashedAt: daysAgo(40)},
+			},
+			policy: func(map[string]int64) RetentionPolicy {
+				return RetentionPolicy{TrashDays: 30}
+			},
+			want:     RetentionResult{Purged: 1},
+			wantKept: []string{"recent"},
+		},
+		{
+			name: "archive stale chats",
+			histories: []History{
+				{ID: "fresh", UpdatedAt: now.AddDate(0, -1, 0)},
+				{ID: "stale", UpdatedAt: now.AddDate(0, -7, 0)},
+				{ID: "pinned", UpdatedAt: now.AddDate(0, -7, 0), Pinned: true},
+			},
+			policy: func(map[string]int64) RetentionPolicy {
+				return RetentionPolicy{ArchiveAfterMonths: 6}
+			},
+			want:         RetentionResult{Archived: 1},
+			wantKept:     []string{"fresh", "stale", "pinned"},
+			wantArchived: []string{"stale"},
+		},
+		{
+			name: "skip chats leased elsewhere",
+			histories: []History{
+				{ID: "stale", UpdatedAt: now.AddDate(0, -7, 0)},
+				{ID: "expired", UpdatedAt: now, TrashedAt: daysAgo(40)},
+			},
+			leased: []string{"stale", "expired"},
+			policy: func(map[string]int64) RetentionPolicy {
+				return RetentionPolicy{TrashDays: 30, ArchiveAfterMonths: 6}
+			},
+			wantKept: []string{"stale", "expired"},
+		},
+		{
+			name: "size cap purges oldest trash first",
+			histories: []History{
+				{ID: "active", UpdatedAt: now.AddDate(0, 0, -10)},
+				{ID: "archived", UpdatedAt: now.AddDate(0, 0, -9), ArchivedAt: daysAgo(1)},
+				{ID: "trash-old", UpdatedAt: now.AddDate(0, 0, -8), TrashedAt: daysAgo(1)},
+				{ID: "trash-new", UpdatedAt: now.AddDate(0, 0, -7), TrashedAt: daysAgo(1)},
+			},
+			policy: func(size map[string]int64) RetentionPolicy {
+				return RetentionPolicy{MaxSizeBytes: size["active"] + size["archived"] + size["trash-new"]}
+			},
+			want:         RetentionResult{Purged: 1},
+			wantKept:     []string{"active", "archived", "trash-new"},
+			wantArchived: []string{"archived"},
+		},
+		{
+			name: "size cap never purges active chats",
+			histories: []History{
+				{ID: "active", UpdatedAt: now.AddDate(0, 0, -10)},
+				{ID: "archived", UpdatedAt: now.AddDate(0, 0, -9), ArchivedAt: daysAgo(1)},
+				{ID: "trashed", UpdatedAt: now.AddDate(0, 0, -8), TrashedAt: daysAgo(1)},
+			},
+			policy: func(map[string]int64) RetentionPolicy {
+				return RetentionPolicy{MaxSizeBytes: 1}
+			},
+			want:     RetentionResult{Purged: 2},
+			wantKept: []string{"active"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			storage := newTestStorage(t)
+			for _, h := range tt.histories {
+				h.Messages = []chat.Message{{Role: "user", Content: "hello " + h.ID}}
+				h.CreatedAt = h.UpdatedAt
+				if err := storage.SaveHistory(h, true); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			other := newTestStorage(t)
+			for _, id := range tt.leased {
+				if err := other.AcquireLease(id, false); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			stored, err := storage.GetHistories()
+			if err != nil {
+				t.Fatal(err)
+			}
+			size := map[string]int64{}
+			for _, h := range stored {
+				size[h.ID] = h.Size
+			}
+
+			m := NewManager(storage)
+			defer m.Close()
+
+			got, err := m.ApplyRetention(tt.policy(size))
+			if err != nil {
+				t.Fatalf("ApplyRetention() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ApplyRetention() = %+v, want %+v", got, tt.want)
+			}
+
+			remaining, err := storage.GetHistories()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var kept, archived []string
+			for _, h := range remaining {
+				kept = append(kept, h.ID)
+				if h.Scope() == ScopeArchived {
+					archived = append(archived, h.ID)
+				}
+			}
+			slices.Sort(kept)
+			slices.Sort(archived)
+			slices.Sort(tt.wantKept)
+			slices.Sort(tt.wantArchived)
+			if !slices.Equal(kept, tt.wantKept) {
+				t.Errorf("kept %v, want %v", kept, tt.wantKept)
+			}
+			if !slices.Equal(archived, tt.wantArchived) {
+				t.Errorf("archived %v, want %v", archived, tt.wantArchived)
+			}
+		})
+	}
+}
+
+func TestMetadataEditsKeepUpdatedAt(t *testing.T) {
+	updated := time.Now().AddDate(0, -1, 0).Truncate(time.Second)
+	edits := []struct {
+		name string
+		edit func(m *Manager, id string) error
+	}{
+		{"trash", (*Manager).TrashHistory},
+		{"archive", (*Manager).ArchiveHistory},
+		{"rename", func(m *Manager, id string) error {
+			return m.EditHistory(id, func(h *History) { h.Title = "renamed" })
+		}},
+	}
+
+	for _, tt := range edits {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			storage := newTestStorage(t)
+			h := History{
+				ID:        "chat",
+				Messages:  []chat.Message{{Role: "user", Content: "hello"}},
+				CreatedAt: updated,
+				UpdatedAt: updated,
+			}
+			if err := storage.SaveHistory(h, true); err != nil {
+				t.Fatal(err)
+			}
+
+			m := NewManager(storage)
+			defer m.Close()
+			if err := tt.edit(m, h.ID); err != nil {
+				t.Fatalf("edit error = %v", err)
+			}
+
+			got, err := storage.GetHistory(h.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.UpdatedAt.Equal(updated) {
+				t.Errorf("UpdatedAt = %v, want %v", got.UpdatedAt, updated)
+			}
+		})
+	}
+}
+
+// newTestStorage returns a FileStorage under the test's HOME. Each call
+// acts as a separate llmv instance sharing the history directory.
+func newTestStorage(t *testing.T) *FileStorage {
+	t.Helper()
+	s, err := NewFileStorage(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
@@ -11,18 +11,32 @@ import (
 	"time"
 
 	"github.com/aj-seven/llmverse/internal/config"
+	"github.com/aj-seven/llmverse/internal/filelock"
 	"github.com/google/uuid"
 )
 
 const (
 	appDirName     = "llmv"
 	historyDirName = "history"
+
+	lockTimeout = 2 * time.Second
+
+	// LeaseTTL is how long a lease stays valid without being refreshed.
+	LeaseTTL = 2 * time.Minute
 )
 
 // FileStorage implements the Storage interface using JSON files.
 type FileStorage struct {
 	dataDir string
-	cfg *config.Config
+	cfg     *config.Config
+
+	// instanceID identifies this process in lease files.
+	instanceID string
+}
+
+type lease struct {
+	Instance string `json:"instance"`
+	PID      int    `json:"pid"`
 }
 
 // NewFileStorage creates in:
@@ -40,16 +54,17 @@ func NewFileStorage(cfg *config.Config) (*FileStorage, error) {
 	}
 
 	return &FileStorage{
-		dataDir: dataDir,
-		cfg:     cfg,
+		dataDir:    dataDir,
+		cfg:        cfg,
+		instanceID: uuid.New().String(),
 	}, nil
 }
 
-// SaveHistory saves a single chat history to disk.
+// SaveHistory saves a single chat history to disk, bumping its revision.
 // It will not save histories with no messages.
-func (s *FileStorage) SaveHistory(h History) error {
+func (s *FileStorage) SaveHistory(h History, force bool) error {
 	// Do not save sessions that have no messages.
-	if len(h.Messages) == 0 {
+	if h.IsEmpty() {
 		return nil
 	}
 
@@ -59,15 +74,34 @@ func (s *FileStorage) SaveHistory(h History) error {
 	if h.CreatedAt.IsZero() {
 		h.CreatedAt = time.Now()
 	}
-	h.UpdatedAt = time.Now()
+	// UpdatedAt follows the messages, which the manager stamps; trashing,
+	// tagging or renaming a chat does not make it recent.
+	if h.UpdatedAt.IsZero() {
+		h.UpdatedAt = h.CreatedAt
+	}
+
+	filePath := s.getHistoryFilePath(h.ID)
+
+	lock, err := filelock.Acquire(filePath, lockTimeout)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	if !force {
+		stored, err := s.GetHistory(h.ID)
+		if err == nil && stored.Revision != h.Revision {
+			return ErrConflict
+		}
+	}
+	h.Revision++
 
 	data, err := json.MarshalIndent(h, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal history: %w", err)
 	}
 
-	filePath := s.getHistoryFilePath(h.ID)
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
+	if err := filelock.WriteFile(filePath, data, 0644); err != nil {
 		return fmt.Errorf("failed to write history file: %w", err)
 	}
 
@@ -90,6 +124,7 @@ func (s *FileStorage) GetHistory(id string) (History, error) {
 	if err := json.Unmarshal(data, &h); err != nil {
 		return History{}, fmt.Errorf("failed to unmarshal history: %w", err)
 	}
+	h.Size = int64(len(data))
 
 	return h, nil
 }
@@ -120,9 +155,10 @@ func (s *FileStorage) GetHistories() ([]History, error) {
 		if err := json.Unmarshal(data, &h); err != nil {
 			return fmt.Errorf("failed to unmarshal history file %s: %w", d.Name(), err)
 		}
+		h.Size = int64(len(data))
 
 		// Skip empty histories defensively
-		if len(h.Messages) == 0 {
+		if h.IsEmpty() {
 			return nil
 		}
 
@@ -151,10 +187,75 @@ func (s *FileStorage) DeleteHistory(id string) error {
 		}
 		return fmt.Errorf("failed to delete history file: %w", err)
 	}
+	_ = os.Remove(s.getLeaseFilePath(id))
 
 	return nil
 }
 
+// AcquireLease records this instance as the one editing a history.
+// Leases held by another instance are honoured until they go LeaseTTL
+// without a refresh, which covers instances that crashed.
+func (s *FileStorage) AcquireLease(id string, force bool) error {
+	leasePath := s.getLeaseFilePath(id)
+
+	lock, err := filelock.Acquire(leasePath, lockTimeout)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	if !force {
+		if info, err := os.Stat(leasePath); err == nil && time.Since(info.ModTime()) < LeaseTTL {
+			var l lease
+			if data, err := os.ReadFile(leasePath); err == nil && json.Unmarshal(data, &l) == nil &&
+				l.Instance != s.instanceID {
+				return ErrLeased
+			}
+		}
+	}
+
+	data, err := json.Marshal(lease{Instance: s.instanceID, PID: os.Getpid()})
+	if err != nil {
+		return fmt.Errorf("failed to marshal lease: %w", err)
+	}
+	if err := filelock.WriteFile(leasePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write lease file: %w", err)
+	}
+	return nil
+}
+
+// ReleaseLease drops this instance's lease on a history, if it holds one.
+func (s *FileStorage) ReleaseLease(id string) error {
+	leasePath := s.getLeaseFilePath(id)
+
+	lock, err := filelock.Acquire(leasePath, lockTimeout)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	var l lease
+	data, err := os.ReadFile(leasePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read lease file: %w", err)
+	}
+	if json.Unmarshal(data, &l) == nil && l.Instance != s.instanceID {
+		return nil
+	}
+	if err := os.Remove(leasePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove lease file: %w", err)
+	}
+	return nil
+}
+
+// Dir returns the directory histories are stored in.
+func (s *FileStorage) Dir() string {
+	return s.historyDir()
+}
+
 // getHistoryFilePath returns the full path to a history file.
 func (s *FileStorage) getHistoryFilePath(id string) string {
 	if s != nil && s.cfg != nil && s.historyDir() != "" {
@@ -166,6 +267,11 @@ func (s *FileStorage) getHistoryFilePath(id string) string {
 	return filepath.Join(s.historyDir(), fmt.Sprintf("history_%s.json", id))
 }
 
+// getLeaseFilePath returns the full path to a history's lease file.
+func (s *FileStorage) getLeaseFilePath(id string) string {
+	return filepath.Join(s.historyDir(), fmt.Sprintf("history_%s.lease", id))
+}
+
 // getHistoryDir - checks first config's dir if valid and present uses it else default dir is used
 func (s *FileStorage) historyDir() string {
 	if s != nil && s.cfg != nil && s.cfg.Storage.History.Path != "" {
@@ -174,4 +280,4 @@ func (s *FileStorage) historyDir() string {
 		}
 	}
 	return s.dataDir
-}
\ No newline at end of file
+}
@@ -0,0 +1,178 @@
+// Package inputhistory keeps the prompts sent from the chat input, across
+// chats and sessions, so they can be recalled like shell commands.
+package inputhistory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aj-seven/llmverse/internal/filelock"
+)
+
+const (
+	appDirName  = "llmv"
+	fileName    = "input_history.jsonl"
+	lockTimeout = 2 * time.Second
+
+	// MaxEntries is the number of prompts kept.
+	MaxEntries = 1000
+)
+
+// History is the list of sent prompts, oldest first. Each prompt appears
+// once, at the position it was last sent.
+type History struct {
+	mu      sync.Mutex
+	path    string // empty keeps the history in memory only
+	entries []string
+	lines   int // entries in the file, including superseded ones
+}
+
+// Load reads the input history from ~/.llmv/input_history.jsonl.
+func Load() (*History, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user home dir: %w", err)
+	}
+	dir := filepath.Join(homeDir, "."+appDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create app directory: %w", err)
+	}
+
+	h := &History{path: filepath.Join(dir, fileName)}
+
+	data, err := os.ReadFile(h.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input history: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 16*1024*1024)
+	for scanner.Scan() {
+		var prompt string
+		if json.Unmarshal(scanner.Bytes(), &prompt) != nil {
+			continue // skip lines cut off by a crash
+		}
+		h.lines++
+		h.push(prompt)
+	}
+	return h, nil
+}
+
+// NewMemory returns a history that is not saved to disk, e.g. when chat
+// histories are encrypted and prompts must not be stored in the clear.
+func NewMemory() *History {
+	return &History{}
+}
+
+// Add records a sent prompt and appends it to the history file. The file
+// is compacted once superseded entries make up most of it.
+func (h *History) Add(prompt string) error {
+	if strings.TrimSpace(prompt) == "" {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.push(prompt)
+	if h.path == "" {
+		return nil
+	}
+
+	lock, err := filelock.Acquire(h.path, lockTimeout)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	if h.lines++; h.lines > 2*MaxEntries {
+		return h.compact()
+	}
+
+	line, err := json.Marshal(prompt)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(h.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open input history: %w", err)
+	}
+	_, err = f.Write(append(line, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Len returns the number of prompts.
+func (h *History) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.entries)
+}
+
+// At returns the i-th prompt, oldest first.
+func (h *History) At(i int) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.entries[i]
+}
+
+// Search returns the index of the newest prompt before index before that
+// contains query, ignoring case, or -1 if there is none.
+func (h *History) Search(query string, before int) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	query = strings.ToLower(query)
+	for i := min(before, len(h.entries)) - 1; i >= 0; i-- {
+		if strings.Contains(strings.ToLower(h.entries[i]), query) {
+			return i
+		}
+	}
+	return -1
+}
+
+// push moves prompt to the end of the entries. The caller must hold h.mu.
+func (h *History) push(prompt string) {
+	for i, e := range h.entries {
+		if e == prompt {
+			h.entries = append(h.entries[:i], h.entries[i+1:]...)
+			break
+		}
+	}
+	h.entries = append(h.entries, prompt)
+	if len(h.entries) > MaxEntries {
+		h.entries = h.entries[len(h.entries)-MaxEntries:]
+	}
+}
+
+// compact rewrites the file with the current entries only. The caller
+// must hold h.mu and the file lock.
+func (h *History) compact() error {
+	var b bytes.Buffer
+	for _, e := range h.entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	if err := filelock.WriteFile(h.path, b.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write input history: %w", err)
+	}
+	h.lines = len(h.entries)
+	return nil
+}
@@ -0,0 +1,206 @@
+package inputhistory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		name string
+		add  []string
+		want []string
+	}{
+		{
+			name: "in order",
+			add:  []string{"a", "b", "c"},
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name: "blank prompts ignored",
+			add:  []string{"a", "", "  \n", "b"},
+			want: []string{"a", "b"},
+		},
+		{
+			name: "resent prompt moves to the end",
+			add:  []string{"a", "b", "c", "a"},
+			want: []string{"b", "c", "a"},
+		},
+		{
+			name: "repeated prompt kept once",
+			add:  []string{"a", "a", "a"},
+			want: []string{"a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewMemory()
+			for _, p := range tt.add {
+				if err := h.Add(p); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := entries(h); !slices.Equal(got, tt.want) {
+				t.Errorf("entries = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddCapsEntries(t *testing.T) {
+	h := NewMemory()
+	for i := range MaxEntries + 5 {
+		h.Add(fmt.Sprintf("p%d", i))
+	}
+
+	if h.Len() != MaxEntries {
+		t.Fatalf("Len() = %d, want %d", h.Len(), MaxEntries)
+	}
+	if first, last := h.At(0), h.At(h.Len()-1); first != "p5" || last != fmt.Sprintf("p%d", MaxEntries+4) {
+		t.Errorf("entries run from %s to %s, want the newest %d", first, last, MaxEntries)
+	}
+}
+
+func TestAddCompactsFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	h, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Alternating prompts supersede each other, so the file grows while
+	// the history keeps two entries.
+	add := func(n int) {
+		t.Helper()
+		for i := range n {
+			if err := h.Add([]string{"a", "b"}[i%2]); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	add(2 * MaxEntries)
+	if n := fileLines(t, h.path); n != 2*MaxEntries {
+		t.Fatalf("file has %d lines before compaction, want %d", n, 2*MaxEntries)
+	}
+
+	add(1)
+	if n := fileLines(t, h.path); n != 2 {
+		t.Fatalf("file has %d lines after compaction, want 2", n)
+	}
+
+	reloaded, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := entries(reloaded), []string{"b", "a"}; !slices.Equal(got, want) {
+		t.Errorf("entries after reload = %q, want %q", got, want)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		want      []string
+		wantLines int
+	}{
+		{
+			name:      "prompts",
+			file:      "\"a\"\n\"multi\\nline\"\n",
+			want:      []string{"a", "multi\nline"},
+			wantLines: 2,
+		},
+		{
+			name:      "superseded prompts",
+			file:      "\"a\"\n\"b\"\n\"a\"\n",
+			want:      []string{"b", "a"},
+			wantLines: 3,
+		},
+		{
+			name:      "truncated last line",
+			file:      "\"a\"\n\"b\"\n\"cut of",
+			want:      []string{"a", "b"},
+			wantLines: 2,
+		},
+		{
+			name:      "truncated line in the middle",
+			file:      "\"a\"\n\"cut of\n\"b\"\n",
+			want:      []string{"a", "b"},
+			wantLines: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			dir := filepath.Join(home, "."+appDirName)
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, fileName), []byte(tt.file), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			h, err := Load()
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if got := entries(h); !slices.Equal(got, tt.want) {
+				t.Errorf("entries = %q, want %q", got, tt.want)
+			}
+			if h.lines != tt.wantLines {
+				t.Errorf("lines = %d, want %d", h.lines, tt.wantLines)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	h := NewMemory()
+	for _, p := range []string{"Explain Go channels", "fix the test", "explain closures"} {
+		h.Add(p)
+	}
+
+	tests := []struct {
+		query  string
+		before int
+		want   int
+	}{
+		{"explain", 3, 2},
+		{"explain", 2, 0},
+		{"EXPLAIN GO", 3, 0},
+		{"explain", 100, 2},
+		{"test", 1, -1},
+		{"missing", 3, -1},
+	}
+
+	for _, tt := range tests {
+		if got := h.Search(tt.query, tt.before); got != tt.want {
+			t.Errorf("Search(%q, %d) = %d, want %d", tt.query, tt.before, got, tt.want)
+		}
+	}
+}
+
+func entries(h *History) []string {
+	var out []string
+	for i := range h.Len() {
+		out = append(out, h.At(i))
+	}
+	return out
+}
+
+func fileLines(t *testing.T, path string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(data), "\n")
+}
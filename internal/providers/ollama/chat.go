@@ -2,6 +2,7 @@ package aihub
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -14,32 +15,49 @@ type OllamaChatRequest struct {
 	Model    string         `json:"model"`
 	Messages []chat.Message `json:"messages"`
 	Stream   bool           `json:"stream"`
+	Options  map[string]any `json:"options,omitempty"`
 }
 
 type OllamaChatResponse struct {
 	Message chat.Message `json:"message"`
 	Done    bool         `json:"done"`
+	Error   string       `json:"error,omitempty"`
 }
 
+// errorPrefix starts the chunk a stream ends with when it fails.
+const errorPrefix = "Error: "
+
+// StatusError is returned when Ollama answers a request with an error
+// status, e.g. 503 while a model is loading. Message is the error Ollama
+// gave, such as `model "x" not found`, if any.
+type StatusError struct {
+	StatusCode int
+	Status     string
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message != "" {
+		return "chat request failed: " + e.Message + " (" + e.Status + ")"
+	}
+	return "chat request failed: " + e.Status
+}
+
+// StreamChat streams the answer of modelName. Cancelling ctx aborts the
+// request and closes the stream, also when nobody reads it anymore.
 func StreamChat(
+	ctx context.Context,
 	modelName string,
 	messages []chat.Message,
+	options map[string]any,
 	cfg *config.Config,
 ) (chan string, error) {
 
-	if cfg != nil && cfg.Assistant.Message != "" {
-		messages = append([]chat.Message{
-			{
-				Role:    "system",
-				Content: cfg.Assistant.Message,
-			},
-		}, messages...)
-	}
-
 	reqBody := OllamaChatRequest{
 		Model:    modelName,
 		Messages: messages,
 		Stream:   true,
+		Options:  options,
 	}
 
 	reqBytes, err := json.Marshal(reqBody)
@@ -47,14 +65,29 @@ func StreamChat(
 		return nil, err
 	}
 
-	resp, err := http.Post(
-		cfg.Host+"/api/chat",
-		"application/json",
-		bytes.NewBuffer(reqBytes),
-	)
+	client, err := Client(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.Host+"/api/chat", bytes.NewBuffer(reqBytes))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		var body struct {
+			Error string `json:"error"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&body)
+		return nil, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status, Message: body.Error}
+	}
 
 	stream := make(chan string)
 
@@ -62,21 +95,31 @@ func StreamChat(
 		defer close(stream)
 		defer resp.Body.Close()
 
+		send := func(chunk string) bool {
+			select {
+			case stream <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
 		decoder := json.NewDecoder(resp.Body)
 		for {
 			var chatResp OllamaChatResponse
 			if err := decoder.Decode(&chatResp); err != nil {
-				if err == io.EOF {
-					break
+				if err != io.EOF {
+					send(errorPrefix + err.Error())
 				}
-				stream <- "Error: " + err.Error()
+				return
+			}
+			if chatResp.Error != "" {
+				send(errorPrefix + chatResp.Error)
 				return
 			}
 
-			stream <- chatResp.Message.Content
-
-			if chatResp.Done {
-				break
+			if !send(chatResp.Message.Content) || chatResp.Done {
+				return
 			}
 		}
 	}()
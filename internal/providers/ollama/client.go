@@ -0,0 +1,135 @@
+package aihub
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aj-seven/llmverse/internal/config"
+)
+
+// Clients are built once per set of HTTP settings and shared by all
+// requests using them, so connections to a host are reused.
+var (
+	clientsMu sync.Mutex
+	clients   = map[string]*http.Client{}
+)
+
+// Client returns the HTTP client for requests to cfg.Host, configured
+// from cfg.HTTP. It has no overall timeout, since chat responses stream
+// for as long as the model writes.
+func Client(cfg *config.Config) (*http.Client, error) {
+	key := fmt.Sprintf("%+v", cfg.HTTP)
+
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+
+	if c, ok := clients[key]; ok {
+		return c, nil
+	}
+	c, err := newClient(cfg.HTTP)
+	if err != nil {
+		return nil, err
+	}
+	clients[key] = c
+	return c, nil
+}
+
+func newClient(h config.HTTP) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = h.ResponseTimeout
+
+	if h.ConnectTimeout > 0 {
+		dialer := &net.Dialer{Timeout: h.ConnectTimeout, KeepAlive: 30 * time.Second}
+		transport.DialContext = dialer.DialContext
+		transport.TLSHandshakeTimeout = h.ConnectTimeout
+	}
+
+	if h.Proxy != "" {
+		proxy, err := url.Parse(h.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if h.CAFile != "" || h.CertFile != "" || h.KeyFile != "" {
+		tlsConfig, err := tlsConfig(h)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	headers := http.Header{}
+	for name, value := range h.Headers {
+		headers.Set(name, value)
+	}
+	if h.TokenEnv != "" {
+		token := os.Getenv(h.TokenEnv)
+		if token == "" {
+			return nil, fmt.Errorf("bearer token variable %s is not set", h.TokenEnv)
+		}
+		headers.Set("Authorization", "Bearer "+token)
+	}
+
+	var rt http.RoundTripper = transport
+	if len(headers) > 0 {
+		rt = &headerTransport{base: transport, headers: headers}
+	}
+	return &http.Client{Transport: rt}, nil
+}
+
+// tlsConfig adds the CA bundle to the trusted authorities and loads the
+// client certificate.
+func tlsConfig(h config.HTTP) (*tls.Config, error) {
+	c := &tls.Config{}
+
+	if h.CAFile != "" {
+		pem, err := os.ReadFile(h.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", h.CAFile)
+		}
+		c.RootCAs = pool
+	}
+
+	if h.CertFile != "" || h.KeyFile != "" {
+		if h.CertFile == "" || h.KeyFile == "" {
+			return nil, fmt.Errorf("client certificate needs both cert_file and key_file")
+		}
+		cert, err := tls.LoadX509KeyPair(h.CertFile, h.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+
+	return c, nil
+}
+
+// headerTransport adds the configured headers to every request.
+type headerTransport struct {
+	base    http.RoundTripper
+	headers http.Header
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, values := range t.headers {
+		req.Header[name] = values
+	}
+	return t.base.RoundTrip(req)
+}
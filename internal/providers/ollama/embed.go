@@ -0,0 +1,64 @@
+package aihub
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/aj-seven/llmverse/internal/config"
+)
+
+type OllamaEmbedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type OllamaEmbedResponse struct {
+	Embeddings [][]float32 `json:"embeddings"`
+}
+
+// Embed returns one embedding vector per input, computed by modelName.
+func Embed(
+	modelName string,
+	inputs []string,
+	cfg *config.Config,
+) ([][]float32, error) {
+
+	reqBytes, err := json.Marshal(OllamaEmbedRequest{
+		Model: modelName,
+		Input: inputs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := Client(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Post(
+		cfg.Host+"/api/embed",
+		"application/json",
+		bytes.NewBuffer(reqBytes),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embed request failed: %s", resp.Status)
+	}
+
+	var embedResp OllamaEmbedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embedResp); err != nil {
+		return nil, err
+	}
+	if len(embedResp.Embeddings) != len(inputs) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(inputs), len(embedResp.Embeddings))
+	}
+
+	return embedResp.Embeddings, nil
+}
@@ -2,8 +2,10 @@ package aihub
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"github.com/aj-seven/llmverse/internal/config"
+	"sync"
 	"time"
 )
 
@@ -17,6 +19,9 @@ type OllamaModel struct {
 	ModifiedAt time.Time `json:"modified_at"`
 	Size       int64     `json:"size"`
 	Digest     string    `json:"digest"`
+	// Host names the endpoint serving the model when models of several
+	// hosts are listed together.
+	Host    string `json:"-"`
 	Details    struct {
 		Family            string `json:"family"`
 		ParameterSize     string `json:"parameter_size"`
@@ -24,13 +29,28 @@ type OllamaModel struct {
 	} `json:"details"`
 }
 
+// modelsTimeout bounds the model list request, which also serves as the
+// check whether the server is reachable.
+const modelsTimeout = 5 * time.Second
+
 func GetModelsDetailed(cfg *config.Config) ([]OllamaModel, error) {
-	resp, err := http.Get(cfg.Host + "/api/tags")
+	shared, err := Client(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client := *shared
+	client.Timeout = modelsTimeout
+
+	resp, err := client.Get(cfg.Host + "/api/tags")
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("model list request failed: %s", resp.Status)
+	}
+
 	var models OllamaModels
 	if err := json.NewDecoder(resp.Body).Decode(&models); err != nil {
 		return nil, err
@@ -38,3 +58,35 @@ func GetModelsDetailed(cfg *config.Config) ([]OllamaModel, error) {
 
 	return models.Models, nil
 }
+
+// HostModels is the model list of one endpoint, or the error that kept
+// it from being fetched.
+type HostModels struct {
+	Endpoint config.Endpoint
+	Models   []OllamaModel
+	Err      error
+}
+
+// GetModelsAllHosts requests the model lists of all configured endpoints
+// at once, so an unreachable host only costs one timeout. The models are
+// tagged with the name of their endpoint.
+func GetModelsAllHosts(cfg *config.Config) []HostModels {
+	endpoints := cfg.Endpoints()
+	hosts := make([]HostModels, len(endpoints))
+
+	var wg sync.WaitGroup
+	for i, e := range endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			models, err := GetModelsDetailed(cfg.ForHost(e.Name))
+			for j := range models {
+				models[j].Host = e.Name
+			}
+			hosts[i] = HostModels{Endpoint: e, Models: models, Err: err}
+		}()
+	}
+	wg.Wait()
+
+	return hosts
+}
@@ -0,0 +1,198 @@
+package aihub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aj-seven/llmverse/internal/config"
+	"github.com/aj-seven/llmverse/pkg/chat"
+)
+
+const (
+	defaultAttempts = 3
+	defaultBackoff  = time.Second
+)
+
+// ErrCancelled is returned when a request is cancelled while waiting to
+// be retried.
+var ErrCancelled = errors.New("request cancelled")
+
+// Target is a model on a named host; an empty host is the default one.
+type Target struct {
+	Model string
+	Host  string
+}
+
+// ParseTarget parses "model" or "model@host".
+func ParseTarget(s string) Target {
+	model, host, _ := strings.Cut(s, "@")
+	return Target{Model: model, Host: host}
+}
+
+func (t Target) String() string {
+	if t.Host == "" {
+		return t.Model
+	}
+	return t.Model + "@" + t.Host
+}
+
+// Chain returns model on host followed by its configured fallbacks.
+// Fallbacks are looked up for "model@host" first, then for "model".
+func Chain(cfg *config.Config, model, host string) []Target {
+	primary := Target{Model: model, Host: host}
+	targets := []Target{primary}
+	if cfg == nil {
+		return targets
+	}
+
+	fallbacks, ok := cfg.Fallbacks[primary.String()]
+	if !ok {
+		fallbacks = cfg.Fallbacks[model]
+	}
+	for _, f := range fallbacks {
+		t := ParseTarget(f)
+		if t.Host == "" {
+			t.Host = host
+		}
+		if t != primary {
+			targets = append(targets, t)
+		}
+	}
+	return targets
+}
+
+// StreamChatRetry streams the chat from the first target that answers.
+// Errors before the first token are retried with exponential backoff as
+// configured, then the next target is tried; errors that retrying cannot
+// fix, such as an unknown model, move on right away. It returns the
+// stream and the target it comes from. Closing cancel stops waiting for a
+// retry and stops the returned stream.
+func StreamChatRetry(
+	targets []Target,
+	messages []chat.Message,
+	options map[string]any,
+	cfg *config.Config,
+	cancel <-chan struct{},
+) (<-chan string, Target, error) {
+
+	attempts, backoff := cfg.Retry.Attempts, cfg.Retry.Backoff
+	if attempts <= 0 {
+		attempts = defaultAttempts
+	}
+	if backoff <= 0 {
+		backoff = defaultBackoff
+	}
+
+	var errs []error
+	for _, target := range targets {
+		wait := backoff
+		for attempt := 1; attempt <= attempts; attempt++ {
+			stream, err := openStream(target, messages, options, cfg, cancel)
+			if err == nil {
+				return stream, target, nil
+			}
+			if errors.Is(err, ErrCancelled) {
+				return nil, target, err
+			}
+			if attempt == attempts || !retryable(err) {
+				errs = append(errs, fmt.Errorf("%s: %w", target, err))
+				break
+			}
+
+			select {
+			case <-cancel:
+				return nil, target, ErrCancelled
+			case <-time.After(wait):
+			}
+			wait *= 2
+		}
+	}
+
+	if len(errs) == 1 {
+		return nil, targets[0], errs[0]
+	}
+	return nil, targets[0], errors.Join(errs...)
+}
+
+// openStream starts a chat and waits for its first token, so a stream
+// that fails right away counts as a failed request. Closing cancel aborts
+// the request, so it does not linger once nobody reads the stream.
+func openStream(
+	target Target,
+	messages []chat.Message,
+	options map[string]any,
+	cfg *config.Config,
+	cancel <-chan struct{},
+) (_ <-chan string, err error) {
+
+	ctx, stop := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-cancel:
+			stop()
+		case <-ctx.Done():
+		}
+	}()
+	defer func() {
+		if err != nil {
+			stop()
+		}
+	}()
+
+	stream, err := StreamChat(ctx, target.Model, messages, options, cfg.ForHost(target.Host))
+	if err != nil {
+		return nil, err
+	}
+
+	var first string
+	for open := true; open && first == ""; {
+		select {
+		case first, open = <-stream:
+		case <-cancel:
+			return nil, ErrCancelled
+		}
+	}
+	if msg, ok := strings.CutPrefix(first, errorPrefix); ok {
+		return nil, errors.New(msg)
+	}
+
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		defer stop()
+		for chunk := first; ; {
+			if chunk != "" {
+				select {
+				case out <- chunk:
+				case <-cancel:
+					return
+				}
+			}
+			var ok bool
+			// Once cancelled, the stream only ends with the abort error.
+			if chunk, ok = <-stream; !ok || ctx.Err() != nil {
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// retryable reports whether err may go away when the request is sent
+// again: connection problems, timeouts and overloaded or loading servers.
+// Other error statuses, like an unknown model, will not.
+func retryable(err error) bool {
+	var status *StatusError
+	if errors.As(err, &status) {
+		switch status.StatusCode {
+		case http.StatusRequestTimeout, http.StatusTooManyRequests:
+			return true
+		}
+		return status.StatusCode >= 500
+	}
+	return true
+}
@@ -0,0 +1,295 @@
+package aihub
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aj-seven/llmverse/internal/config"
+	"github.com/aj-seven/llmverse/pkg/chat"
+)
+
+// reply is a canned answer of the test server: an error status with an
+// Ollama error body, or a stream of chunks.
+type reply struct {
+	status int
+	err    string
+	chunks []string
+}
+
+func TestStreamChatRetry(t *testing.T) {
+	ok := reply{status: http.StatusOK, chunks: []string{"hel", "lo"}}
+	unavailable := reply{status: http.StatusServiceUnavailable, err: "server busy"}
+	notFound := reply{status: http.StatusNotFound, err: `model "a" not found`}
+	streamErr := reply{status: http.StatusOK, err: "model crashed"}
+
+	tests := []struct {
+		name    string
+		targets []string
+		// replies lists the answers per model, one per request; the last
+		// one repeats.
+		replies    map[string][]reply
+		wantCalls  []string
+		wantTarget string
+		wantErr    string
+		wantStatus int
+	}{
+		{
+			name:       "first try",
+			targets:    []string{"a"},
+			replies:    map[string][]reply{"a": {ok}},
+			wantCalls:  []string{"a"},
+			wantTarget: "a",
+		},
+		{
+			name:       "retry unavailable",
+			targets:    []string{"a"},
+			replies:    map[string][]reply{"a": {unavailable, ok}},
+			wantCalls:  []string{"a", "a"},
+			wantTarget: "a",
+		},
+		{
+			name:       "retry error in stream",
+			targets:    []string{"a"},
+			replies:    map[string][]reply{"a": {streamErr, ok}},
+			wantCalls:  []string{"a", "a"},
+			wantTarget: "a",
+		},
+		{
+			name:       "fall back after attempts",
+			targets:    []string{"a", "b"},
+			replies:    map[string][]reply{"a": {unavailable}, "b": {ok}},
+			wantCalls:  []string{"a", "a", "a", "b"},
+			wantTarget: "b",
+		},
+		{
+			name:       "fall back at once on unknown model",
+			targets:    []string{"a", "b"},
+			replies:    map[string][]reply{"a": {notFound}, "b": {ok}},
+			wantCalls:  []string{"a", "b"},
+			wantTarget: "b",
+		},
+		{
+			name:       "unknown model",
+			targets:    []string{"a"},
+			replies:    map[string][]reply{"a": {notFound}},
+			wantCalls:  []string{"a"},
+			wantErr:    `a: chat request failed: model "a" not found (404 Not Found)`,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:      "all targets fail",
+			targets:   []string{"a", "b"},
+			replies:   map[string][]reply{"a": {notFound}, "b": {unavailable}},
+			wantCalls: []string{"a", "b", "b", "b"},
+			wantErr: `a: chat request failed: model "a" not found (404 Not Found)` + "\n" +
+				"b: chat request failed: server busy (503 Service Unavailable)",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu    sync.Mutex
+				calls []string
+				sent  = map[string]int{}
+			)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req OllamaChatRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Error(err)
+				}
+				mu.Lock()
+				calls = append(calls, req.Model)
+				n := sent[req.Model]
+				sent[req.Model]++
+				mu.Unlock()
+
+				replies := tt.replies[req.Model]
+				writeReply(w, replies[min(n, len(replies)-1)])
+			}))
+			defer srv.Close()
+
+			var targets []Target
+			for _, s := range tt.targets {
+				targets = append(targets, ParseTarget(s))
+			}
+
+			stream, target, err := StreamChatRetry(targets, nil, nil, testConfig(srv.URL), make(chan struct{}))
+
+			if tt.wantErr != "" {
+				if err == nil {
+					t.Fatalf("StreamChatRetry() error = nil, want %q", tt.wantErr)
+				}
+				if err.Error() != tt.wantErr {
+					t.Errorf("StreamChatRetry() error = %q, want %q", err, tt.wantErr)
+				}
+				var status *StatusError
+				if !errors.As(err, &status) || status.StatusCode != tt.wantStatus {
+					t.Errorf("StreamChatRetry() error %v is not a %d StatusError", err, tt.wantStatus)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("StreamChatRetry() error = %v", err)
+				}
+				if target.String() != tt.wantTarget {
+					t.Errorf("StreamChatRetry() target = %s, want %s", target, tt.wantTarget)
+				}
+				if got := collect(stream); got != "hello" {
+					t.Errorf("stream = %q, want %q", got, "hello")
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if !slices.Equal(calls, tt.wantCalls) {
+				t.Errorf("requests = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestStreamChatRetryCancel(t *testing.T) {
+	tests := []struct {
+		name string
+		// first is sent before the server waits for the client to go away.
+		first string
+	}{
+		{name: "before first token"},
+		{name: "while streaming", first: "hel"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started, gone := make(chan struct{}), make(chan struct{})
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				if tt.first != "" {
+					writeChunk(w, tt.first, false)
+				}
+				w.(http.Flusher).Flush()
+				close(started)
+				<-r.Context().Done()
+				close(gone)
+			}))
+			defer srv.Close()
+
+			cancel := make(chan struct{})
+			type result struct {
+				stream <-chan string
+				err    error
+			}
+			done := make(chan result, 1)
+			go func() {
+				stream, _, err := StreamChatRetry([]Target{{Model: "a"}}, nil, nil, testConfig(srv.URL), cancel)
+				done <- result{stream, err}
+			}()
+
+			if tt.first == "" {
+				wait(t, started)
+				close(cancel)
+				if res := wait(t, done); !errors.Is(res.err, ErrCancelled) {
+					t.Fatalf("StreamChatRetry() error = %v, want ErrCancelled", res.err)
+				}
+			} else {
+				res := wait(t, done)
+				if res.err != nil {
+					t.Fatalf("StreamChatRetry() error = %v", res.err)
+				}
+				if chunk := <-res.stream; chunk != tt.first {
+					t.Fatalf("first chunk = %q, want %q", chunk, tt.first)
+				}
+				close(cancel)
+				if _, open := <-res.stream; open {
+					t.Error("stream still open after cancel")
+				}
+			}
+
+			// The request must be aborted rather than left to the server.
+			wait(t, gone)
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	cfg := &config.Config{Fallbacks: map[string][]string{
+		"a":     {"b", "c@gpu"},
+		"a@gpu": {"a", "d"},
+	}}
+
+	tests := []struct {
+		model, host string
+		want        []string
+	}{
+		{"a", "", []string{"a", "b", "c@gpu"}},
+		{"a", "cpu", []string{"a@cpu", "b@cpu", "c@gpu"}},
+		{"a", "gpu", []string{"a@gpu", "d@gpu"}},
+		{"x", "", []string{"x"}},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, target := range Chain(cfg, tt.model, tt.host) {
+			got = append(got, target.String())
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Chain(%q, %q) = %v, want %v", tt.model, tt.host, got, tt.want)
+		}
+	}
+}
+
+func testConfig(host string) *config.Config {
+	cfg := &config.Config{Host: host}
+	cfg.Retry.Attempts = 3
+	cfg.Retry.Backoff = time.Millisecond
+	return cfg
+}
+
+func writeReply(w http.ResponseWriter, r reply) {
+	if r.status != http.StatusOK {
+		w.WriteHeader(r.status)
+		json.NewEncoder(w).Encode(map[string]string{"error": r.err})
+		return
+	}
+	if r.err != "" {
+		json.NewEncoder(w).Encode(OllamaChatResponse{Error: r.err})
+		return
+	}
+	for i, chunk := range r.chunks {
+		writeChunk(w, chunk, i == len(r.chunks)-1)
+	}
+}
+
+func writeChunk(w http.ResponseWriter, content string, done bool) {
+	json.NewEncoder(w).Encode(OllamaChatResponse{
+		Message: chat.Message{Role: "assistant", Content: content},
+		Done:    done,
+	})
+}
+
+func collect(stream <-chan string) string {
+	var b strings.Builder
+	for chunk := range stream {
+		b.WriteString(chunk)
+	}
+	return b.String()
+}
+
+// wait receives from ch, failing the test if nothing arrives in time.
+func wait[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out")
+		panic("unreachable")
+	}
+}
@@ -0,0 +1,85 @@
+package aihub
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aj-seven/llmverse/internal/config"
+	"github.com/aj-seven/llmverse/pkg/chat"
+)
+
+const (
+	titlePrompt = "Write a short title (at most 6 words) for the conversation below. " +
+		"Reply with the title only, without quotes or punctuation at the end."
+
+	// maxTitleInput keeps title requests small when the first prompt is a
+	// long paste.
+	maxTitleInput = 2000
+)
+
+// GenerateTitle asks modelName for a concise title summarizing messages.
+func GenerateTitle(
+	modelName string,
+	messages []chat.Message,
+	cfg *config.Config,
+) (string, error) {
+
+	var convo strings.Builder
+	for _, msg := range messages {
+		content := msg.Content
+		if len(content) > maxTitleInput {
+			// Drop a rune cut in half at the end.
+			content = strings.ToValidUTF8(content[:maxTitleInput], "")
+		}
+		fmt.Fprintf(&convo, "%s: %s\n\n", msg.Role, content)
+	}
+
+	reqBody := OllamaChatRequest{
+		Model: modelName,
+		Messages: []chat.Message{
+			{Role: "system", Content: titlePrompt},
+			{Role: "user", Content: convo.String()},
+		},
+		Stream: false,
+	}
+
+	reqBytes, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", err
+	}
+
+	client, err := Client(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := client.Post(
+		cfg.Host+"/api/chat",
+		"application/json",
+		bytes.NewBuffer(reqBytes),
+	)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("title request failed: %s", resp.Status)
+	}
+
+	var chatResp OllamaChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return "", err
+	}
+
+	title := strings.TrimSpace(chatResp.Message.Content)
+	title, _, _ = strings.Cut(title, "\n")
+	title = strings.Trim(title, "\"'`*# ")
+	if title == "" {
+		return "", fmt.Errorf("model returned an empty title")
+	}
+	return title, nil
+}
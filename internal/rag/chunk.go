@@ -0,0 +1,81 @@
+package rag
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Chunk is a piece of a document small enough to embed on its own.
+type Chunk struct {
+	Path   string    `json:"path"` // relative to the collection root
+	Line   int       `json:"line"` // first line, 1-based
+	Text   string    `json:"text"`
+	Vector []float32 `json:"vector"`
+}
+
+// SplitText cuts text into chunks of about size characters along line
+// boundaries. Consecutive chunks share up to overlap characters so a
+// passage split in two can still be found from either side.
+func SplitText(path, text string, size, overlap int) []Chunk {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	var (
+		chunks []Chunk
+		cur    []string
+		curLen int
+		start  = 1
+	)
+
+	flush := func(next int) {
+		body := strings.TrimSpace(strings.Join(cur, "\n"))
+		if body != "" {
+			chunks = append(chunks, Chunk{Path: path, Line: start, Text: body})
+		}
+
+		// Carry trailing lines over as the overlap of the next chunk.
+		keep, kept := 0, 0
+		for i := len(cur) - 1; i > 0 && kept+len(cur[i]) < overlap; i-- {
+			kept += len(cur[i]) + 1
+			keep++
+		}
+		cur = append([]string(nil), cur[len(cur)-keep:]...)
+		curLen = kept
+		start = next - keep
+	}
+
+	for i, line := range lines {
+		// Very long lines (minified files) are hard-wrapped.
+		for len(line) > size {
+			cut := wrapAt(line, size)
+			cur = append(cur, line[:cut])
+			curLen += cut
+			flush(i + 1)
+			line = line[cut:]
+		}
+
+		if curLen+len(line) > size && len(cur) > 0 {
+			flush(i + 1)
+		}
+		cur = append(cur, line)
+		curLen += len(line) + 1
+	}
+	if len(cur) > 0 {
+		flush(len(lines) + 1)
+	}
+
+	return chunks
+}
+
+// wrapAt returns where to cut a line longer than size: at most size bytes
+// in, but never inside a UTF-8 sequence.
+func wrapAt(line string, size int) int {
+	cut := size
+	for cut > 0 && !utf8.RuneStart(line[cut]) {
+		cut--
+	}
+	if cut == 0 {
+		// size is smaller than the first rune; keep the rune whole.
+		_, cut = utf8.DecodeRuneInString(line)
+	}
+	return cut
+}
@@ -0,0 +1,88 @@
+package rag
+
+import (
+	"slices"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitText(t *testing.T) {
+	type chunk struct {
+		line int
+		text string
+	}
+
+	tests := []struct {
+		name          string
+		text          string
+		size, overlap int
+		want          []chunk
+	}{
+		{
+			name: "one chunk",
+			text: "a\nb",
+			size: 100,
+			want: []chunk{{1, "a\nb"}},
+		},
+		{
+			name: "blank text",
+			text: "\n  \n",
+			size: 100,
+		},
+		{
+			name: "along lines",
+			text: "aaaa\nbbbb\ncccc",
+			size: 10,
+			want: []chunk{{1, "aaaa\nbbbb"}, {3, "cccc"}},
+		},
+		{
+			name:    "overlap",
+			text:    "aaaa\nbbbb\ncccc",
+			size:    10,
+			overlap: 6,
+			want:    []chunk{{1, "aaaa\nbbbb"}, {2, "bbbb\ncccc"}},
+		},
+		{
+			name: "crlf",
+			text: "a\r\nb",
+			size: 1,
+			want: []chunk{{1, "a"}, {2, "b"}},
+		},
+		{
+			name: "long word",
+			text: "abcdefghij",
+			size: 4,
+			want: []chunk{{1, "abcd"}, {1, "efgh"}, {1, "ij"}},
+		},
+		{
+			name: "non-ASCII",
+			text: "äöüß",
+			size: 3,
+			want: []chunk{{1, "ä"}, {1, "ö"}, {1, "ü"}, {1, "ß"}},
+		},
+		{
+			name: "rune longer than size",
+			text: "日本",
+			size: 1,
+			want: []chunk{{1, "日"}, {1, "本"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []chunk
+			for _, c := range SplitText("doc.txt", tt.text, tt.size, tt.overlap) {
+				if c.Path != "doc.txt" {
+					t.Errorf("chunk path = %q, want doc.txt", c.Path)
+				}
+				if !utf8.ValidString(c.Text) {
+					t.Errorf("chunk %q is not valid UTF-8", c.Text)
+				}
+				got = append(got, chunk{c.Line, c.Text})
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("SplitText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
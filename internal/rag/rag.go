@@ -0,0 +1,377 @@
+// Package rag indexes local documents with embeddings and retrieves the
+// chunks most relevant to a question.
+package rag
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aj-seven/llmverse/internal/attach"
+	"github.com/aj-seven/llmverse/internal/filelock"
+)
+
+const (
+	appDirName  = "llmv"
+	ragDirName  = "rag"
+	indexExt    = ".json"
+	lockTimeout = 2 * time.Second
+
+	DefaultModel     = "nomic-embed-text"
+	DefaultTopK      = 4
+	DefaultChunkSize = 1000
+
+	// maxDocSize skips files that are too large to be useful documents.
+	maxDocSize = 2 * 1024 * 1024
+	embedBatch = 16
+)
+
+var (
+	// ErrNotFound is returned for an unknown collection name.
+	ErrNotFound = errors.New("collection not found")
+
+	validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+)
+
+// EmbedFunc computes one embedding vector per input text with model.
+type EmbedFunc func(model string, texts []string) ([][]float32, error)
+
+// Options control how a collection is indexed.
+type Options struct {
+	Model     string // embedding model
+	ChunkSize int
+	Overlap   int
+}
+
+// Collection is an indexed directory of documents.
+type Collection struct {
+	Name      string    `json:"name"`
+	Root      string    `json:"root"`
+	Model     string    `json:"model"`
+	ChunkSize int       `json:"chunk_size"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Files maps each indexed path to a hash of its content, so
+	// re-indexing only embeds files that changed.
+	Files  map[string]string `json:"files"`
+	Chunks []Chunk           `json:"chunks"`
+}
+
+// Info summarizes a collection for listings.
+type Info struct {
+	Name      string
+	Root      string
+	Model     string
+	Files     int
+	Chunks    int
+	UpdatedAt time.Time
+}
+
+// Result is a chunk returned by Search.
+type Result struct {
+	Chunk
+	Score float32
+}
+
+// Dir returns the directory collections are stored in, creating it if
+// needed: ~/.llmv/rag
+func Dir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home dir: %w", err)
+	}
+
+	dir := filepath.Join(homeDir, "."+appDirName, ragDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create rag directory: %w", err)
+	}
+	return dir, nil
+}
+
+// List summarizes all collections, sorted by name.
+func List() ([]Info, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rag directory: %w", err)
+	}
+
+	var out []Info
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != indexExt {
+			continue
+		}
+		c, err := Load(strings.TrimSuffix(e.Name(), indexExt))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, Info{
+			Name:      c.Name,
+			Root:      c.Root,
+			Model:     c.Model,
+			Files:     len(c.Files),
+			Chunks:    len(c.Chunks),
+			UpdatedAt: c.UpdatedAt,
+		})
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out, nil
+}
+
+// Load reads a collection by name.
+func Load(name string) (Collection, error) {
+	path, err := indexPath(name)
+	if err != nil {
+		return Collection{}, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Collection{}, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return Collection{}, fmt.Errorf("failed to read collection %s: %w", name, err)
+	}
+
+	var c Collection
+	if err := json.Unmarshal(data, &c); err != nil {
+		return Collection{}, fmt.Errorf("failed to unmarshal collection %s: %w", name, err)
+	}
+	return c, nil
+}
+
+// Remove deletes a collection.
+func Remove(name string) error {
+	path, err := indexPath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); os.IsNotExist(err) {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	} else if err != nil {
+		return fmt.Errorf("failed to remove collection %s: %w", name, err)
+	}
+	return nil
+}
+
+// Index walks root and embeds its text documents into the collection
+// name, creating or updating it. Files whose content did not change since
+// the last run keep their vectors. progress, if set, is called after each
+// embedded batch.
+func Index(name, root string, opts Options, embed EmbedFunc, progress func(done, total int)) (Collection, error) {
+	if !validName.MatchString(name) {
+		return Collection{}, fmt.Errorf("invalid collection name %q", name)
+	}
+	if opts.Model == "" {
+		opts.Model = DefaultModel
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = DefaultChunkSize
+	}
+	if opts.Overlap <= 0 {
+		opts.Overlap = opts.ChunkSize / 8
+	}
+
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return Collection{}, err
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return Collection{}, fmt.Errorf("%s is not a directory", root)
+	}
+
+	prev, err := Load(name)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return Collection{}, err
+	}
+	// Vectors from another model or chunking cannot be reused.
+	if prev.Model != opts.Model || prev.ChunkSize != opts.ChunkSize || prev.Root != root {
+		prev = Collection{}
+	}
+
+	c := Collection{
+		Name:      name,
+		Root:      root,
+		Model:     opts.Model,
+		ChunkSize: opts.ChunkSize,
+		Files:     map[string]string{},
+	}
+
+	var pending []Chunk
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if p != root && attach.IgnoredDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || info.Size() > maxDocSize {
+			return nil
+		}
+
+		data, err := os.ReadFile(p)
+		if err != nil || attach.IsBinary(data) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])
+		c.Files[rel] = hash
+
+		if prev.Files[rel] == hash {
+			for _, ch := range prev.Chunks {
+				if ch.Path == rel {
+					c.Chunks = append(c.Chunks, ch)
+				}
+			}
+			return nil
+		}
+
+		pending = append(pending, SplitText(rel, string(data), opts.ChunkSize, opts.Overlap)...)
+		return nil
+	})
+	if err != nil {
+		return Collection{}, err
+	}
+
+	for i := 0; i < len(pending); i += embedBatch {
+		batch := pending[i:min(i+embedBatch, len(pending))]
+		texts := make([]string, len(batch))
+		for j, ch := range batch {
+			texts[j] = ch.Text
+		}
+
+		vectors, err := embed(opts.Model, texts)
+		if err != nil {
+			return Collection{}, fmt.Errorf("failed to embed %s: %w", batch[0].Path, err)
+		}
+		for j := range batch {
+			batch[j].Vector = normalize(vectors[j])
+		}
+
+		if progress != nil {
+			progress(i+len(batch), len(pending))
+		}
+	}
+	c.Chunks = append(c.Chunks, pending...)
+	c.UpdatedAt = time.Now()
+
+	if err := c.save(); err != nil {
+		return Collection{}, err
+	}
+	return c, nil
+}
+
+// Search returns the k chunks closest to the query vector.
+func (c Collection) Search(query []float32, k int) []Result {
+	if k <= 0 {
+		k = DefaultTopK
+	}
+	query = normalize(query)
+
+	results := make([]Result, 0, len(c.Chunks))
+	for _, ch := range c.Chunks {
+		if len(ch.Vector) != len(query) {
+			continue
+		}
+		results = append(results, Result{Chunk: ch, Score: dot(ch.Vector, query)})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results
+}
+
+// Helpers
+
+func (c Collection) save() error {
+	path, err := indexPath(c.Name)
+	if err != nil {
+		return err
+	}
+
+	lock, err := filelock.Acquire(path, lockTimeout)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to marshal collection: %w", err)
+	}
+	if err := filelock.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write collection: %w", err)
+	}
+	return nil
+}
+
+func indexPath(name string) (string, error) {
+	if !validName.MatchString(name) {
+		return "", fmt.Errorf("invalid collection name %q", name)
+	}
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+indexExt), nil
+}
+
+// normalize scales v to unit length, so cosine similarity is a dot product.
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+
+	norm := float32(math.Sqrt(sum))
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = x / norm
+	}
+	return out
+}
+
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
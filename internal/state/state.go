@@ -0,0 +1,201 @@
+// Package state keeps small bits of UI state, such as favourite models,
+// across runs in ~/.llmv/state.json.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aj-seven/llmverse/internal/filelock"
+)
+
+const (
+	appDirName  = "llmv"
+	fileName    = "state.json"
+	lockTimeout = 2 * time.Second
+
+	// maxRecentModels is the number of recently used models remembered.
+	maxRecentModels = 10
+)
+
+// ErrCorrupt is returned by Open when the state file cannot be parsed. The
+// store it returns along with the error starts empty and replaces the
+// file on the next change.
+var ErrCorrupt = errors.New("state file is corrupt")
+
+// State is the persisted UI state.
+type State struct {
+	// FavouriteModels are pinned to the top of the model picker.
+	FavouriteModels []string `json:"favourite_models,omitempty"`
+	// RecentModels lists the models chats were started with, most
+	// recent first.
+	RecentModels []string `json:"recent_models,omitempty"`
+
+	// LastModel is the model of the active chat when llmv last exited.
+	LastModel string `json:"last_model,omitempty"`
+	// Session holds the chats that were open then.
+	Session *Session `json:"session,omitempty"`
+}
+
+// Session is the set of chats open when llmv exited.
+type Session struct {
+	Tabs   []Tab  `json:"tabs"`
+	Active string `json:"active,omitempty"`
+}
+
+// Tab is an open chat and its scroll position.
+type Tab struct {
+	ID     string `json:"id"`
+	Scroll int    `json:"scroll"`
+	// Bottom is set if the chat was scrolled to the end, so that it
+	// opens at the end even if it has grown since.
+	Bottom bool `json:"bottom,omitempty"`
+}
+
+// Store loads and saves the State. Several instances may share the file,
+// so every change is applied to the latest copy on disk.
+type Store struct {
+	mu    sync.Mutex
+	path  string // empty keeps the state in memory only
+	state State
+}
+
+// Open loads the state from ~/.llmv/state.json.
+func Open() (*Store, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user home dir: %w", err)
+	}
+	dir := filepath.Join(homeDir, "."+appDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create app directory: %w", err)
+	}
+
+	s := &Store{path: filepath.Join(dir, fileName)}
+	if err := s.read(); errors.Is(err, ErrCorrupt) {
+		return s, err
+	} else if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewMemory returns a store that is not saved to disk.
+func NewMemory() *Store {
+	return &Store{}
+}
+
+// Get returns a copy of the current state.
+func (s *Store) Get() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := s.state
+	st.FavouriteModels = append([]string(nil), st.FavouriteModels...)
+	st.RecentModels = append([]string(nil), st.RecentModels...)
+	if st.Session != nil {
+		session := *st.Session
+		session.Tabs = append([]Tab(nil), session.Tabs...)
+		st.Session = &session
+	}
+	return st
+}
+
+// Update applies fn to the latest state and saves it.
+func (s *Store) Update(fn func(*State)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.path == "" {
+		fn(&s.state)
+		return nil
+	}
+
+	lock, err := filelock.Acquire(s.path, lockTimeout)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	if err := s.read(); err != nil && !errors.Is(err, ErrCorrupt) {
+		return err
+	}
+	fn(&s.state)
+
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+	if err := filelock.WriteFile(s.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
+
+// ToggleFavourite adds the model to the favourites or removes it. It
+// reports whether the model is a favourite now.
+func (s *Store) ToggleFavourite(model string) (bool, error) {
+	var favourite bool
+	err := s.Update(func(st *State) {
+		st.FavouriteModels, favourite = toggle(st.FavouriteModels, model)
+	})
+	return favourite, err
+}
+
+// UseModel records the model as the most recently used one.
+func (s *Store) UseModel(model string) error {
+	return s.Update(func(st *State) {
+		recent := []string{model}
+		for _, m := range st.RecentModels {
+			if m != model && len(recent) < maxRecentModels {
+				recent = append(recent, m)
+			}
+		}
+		st.RecentModels = recent
+	})
+}
+
+// SaveSession records the model and chats open at exit.
+func (s *Store) SaveSession(model string, session *Session) error {
+	return s.Update(func(st *State) {
+		st.LastModel = model
+		st.Session = session
+	})
+}
+
+// Helpers
+
+// read replaces the in-memory state with the file. The caller must hold
+// s.mu.
+func (s *Store) read() error {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.state = State{}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var st State
+	if err := json.Unmarshal(data, &st); err != nil {
+		s.state = State{}
+		return fmt.Errorf("%w: %s: %v", ErrCorrupt, s.path, err)
+	}
+	s.state = st
+	return nil
+}
+
+func toggle(list []string, item string) ([]string, bool) {
+	for i, v := range list {
+		if v == item {
+			return append(list[:i], list[i+1:]...), false
+		}
+	}
+	return append(list, item), true
+}
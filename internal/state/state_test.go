@@ -0,0 +1,95 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string // empty means no file
+		wantErr error
+		want    []string
+	}{
+		{name: "missing"},
+		{name: "valid", file: `{"favourite_models": ["llama3"]}`, want: []string{"llama3"}},
+		{name: "corrupt", file: `{"favourite_models": [`, wantErr: ErrCorrupt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := statePath(t)
+			if tt.file != "" {
+				if err := os.WriteFile(path, []byte(tt.file), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			s, err := Open()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Open() error = %v, want %v", err, tt.wantErr)
+			}
+			if s == nil {
+				t.Fatal("Open() returned no store")
+			}
+			if got := s.Get().FavouriteModels; !slices.Equal(got, tt.want) {
+				t.Errorf("favourites = %v, want %v", got, tt.want)
+			}
+
+			// Changes replace a corrupt file.
+			if _, err := s.ToggleFavourite("mistral"); err != nil {
+				t.Fatalf("ToggleFavourite() error = %v", err)
+			}
+			reopened, err := Open()
+			if err != nil {
+				t.Fatalf("Open() after a change error = %v", err)
+			}
+			if want := append(tt.want, "mistral"); !slices.Equal(reopened.Get().FavouriteModels, want) {
+				t.Errorf("favourites after a change = %v, want %v", reopened.Get().FavouriteModels, want)
+			}
+		})
+	}
+}
+
+func TestUseModel(t *testing.T) {
+	statePath(t)
+	s, err := Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range []string{"a", "b", "a", "c"} {
+		if err := s.UseModel(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := s.Get().RecentModels, []string{"c", "a", "b"}; !slices.Equal(got, want) {
+		t.Errorf("recent models = %v, want %v", got, want)
+	}
+
+	for i := range maxRecentModels + 5 {
+		if err := s.UseModel(string(rune('d' + i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := len(s.Get().RecentModels); got != maxRecentModels {
+		t.Errorf("%d recent models kept, want %d", got, maxRecentModels)
+	}
+}
+
+// statePath points HOME at a temporary directory and returns the path of
+// the state file in it.
+func statePath(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := filepath.Join(home, "."+appDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, fileName)
+}
@@ -0,0 +1,228 @@
+package templates
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	appDirName       = "llmv"
+	templatesDirName = "templates"
+	templateExt      = ".tmpl"
+
+	frontMatterDelim = "---"
+
+	// maxIncludeSize caps how much a single file or command may add to a
+	// prompt.
+	maxIncludeSize = 256 * 1024
+	shellTimeout   = 10 * time.Second
+)
+
+// Var is a value the user is asked for before a template is rendered.
+type Var struct {
+	Name    string `yaml:"name"`
+	Prompt  string `yaml:"prompt"`
+	Default string `yaml:"default"`
+}
+
+// Template is a reusable user prompt written in Go text/template syntax.
+// Files in the templates directory start with a YAML front matter block:
+//
+//	---
+//	name: review
+//	description: Review a source file
+//	vars:
+//	  - name: path
+//	    prompt: File to review
+//	---
+//	Review this code for bugs:
+//
+//	{{ file .path }}
+type Template struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Vars        []Var  `yaml:"vars"`
+
+	Body string `yaml:"-"`
+	Path string `yaml:"-"`
+}
+
+// Dir returns the templates directory, creating it if needed:
+// ~/.llmv/templates
+func Dir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home dir: %w", err)
+	}
+
+	dir := filepath.Join(homeDir, "."+appDirName, templatesDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create templates directory: %w", err)
+	}
+	return dir, nil
+}
+
+// Load reads all templates from the templates directory, sorted by name.
+// Files that cannot be parsed are skipped and reported in skipped, so one
+// broken template does not hide the others.
+func Load() (loaded []Template, skipped []error, err error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read templates directory: %w", err)
+	}
+
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != templateExt {
+			continue
+		}
+
+		t, err := parseFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			skipped = append(skipped, err)
+			continue
+		}
+		loaded = append(loaded, t)
+	}
+
+	sort.Slice(loaded, func(i, j int) bool {
+		return loaded[i].Name < loaded[j].Name
+	})
+	return loaded, skipped, nil
+}
+
+// Find returns the template with the given name.
+func Find(templates []Template, name string) (Template, bool) {
+	for _, t := range templates {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Template{}, false
+}
+
+// Render executes the template with the given variable values. Besides
+// the variables (as .name), templates can call:
+//
+//	file "path"   contents of a file
+//	clipboard     contents of the system clipboard
+//	shell "cmd"   output of a shell command
+func (t Template) Render(values map[string]string) (string, error) {
+	tmpl, err := template.New(t.Name).
+		Option("missingkey=zero").
+		Funcs(template.FuncMap{
+			"file":      includeFile,
+			"clipboard": clipboard.ReadAll,
+			"shell":     runShell,
+		}).
+		Parse(t.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template %s: %w", t.Name, err)
+	}
+
+	data := make(map[string]string, len(t.Vars))
+	for _, v := range t.Vars {
+		data[v.Name] = v.Default
+	}
+	for k, v := range values {
+		data[k] = v
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render template %s: %w", t.Name, err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// Helpers
+
+func parseFile(path string) (Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Template{}, fmt.Errorf("failed to read template %s: %w", filepath.Base(path), err)
+	}
+
+	t := Template{Path: path}
+	content := strings.ReplaceAll(string(data), "\r\n", "\n")
+
+	if rest, ok := strings.CutPrefix(content, frontMatterDelim+"\n"); ok {
+		header, body, found := strings.Cut(rest, "\n"+frontMatterDelim+"\n")
+		if !found {
+			return Template{}, fmt.Errorf("template %s: unterminated front matter", filepath.Base(path))
+		}
+		if err := yaml.Unmarshal([]byte(header), &t); err != nil {
+			return Template{}, fmt.Errorf("template %s: %w", filepath.Base(path), err)
+		}
+		content = body
+	}
+
+	t.Body = content
+	if t.Name == "" {
+		t.Name = strings.TrimSuffix(filepath.Base(path), templateExt)
+	}
+	return t, nil
+}
+
+func includeFile(path string) (string, error) {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Size() > maxIncludeSize {
+		return "", fmt.Errorf("%s is larger than %d KB", path, maxIncludeSize/1024)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func runShell(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), shellTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+
+	out, err := cmd.CombinedOutput()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "", fmt.Errorf("command timed out: %s", command)
+	}
+	if err != nil {
+		return "", fmt.Errorf("command failed: %s: %w", command, err)
+	}
+	if len(out) > maxIncludeSize {
+		out = out[:maxIncludeSize]
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
@@ -0,0 +1,91 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	files := map[string]string{
+		"plain.tmpl":  "Explain {{ .topic }}",
+		"review.tmpl": "---\nname: code-review\ndescription: Review a file\nvars:\n  - name: path\n    prompt: File\n---\nReview {{ file .path }}\n",
+		"broken.tmpl": "---\nname: [unclosed\n---\nbody\n",
+		"open.tmpl":   "---\nname: open\nbody without end\n",
+		"notes.txt":   "not a template",
+	}
+
+	t.Setenv("HOME", t.TempDir())
+	dir, err := Dir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	loaded, skipped, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	var names []string
+	for _, tmpl := range loaded {
+		names = append(names, tmpl.Name)
+	}
+	if want := []string{"code-review", "plain"}; !slices.Equal(names, want) {
+		t.Errorf("loaded %v, want %v", names, want)
+	}
+
+	var reported []string
+	for _, err := range skipped {
+		reported = append(reported, err.Error())
+	}
+	slices.Sort(reported)
+	if len(reported) != 2 || !strings.HasPrefix(reported[0], "template broken.tmpl: ") ||
+		reported[1] != "template open.tmpl: unterminated front matter" {
+		t.Errorf("skipped %q, want broken.tmpl and open.tmpl", reported)
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name   string
+		tmpl   Template
+		values map[string]string
+		want   string
+	}{
+		{
+			name:   "values",
+			tmpl:   Template{Name: "t", Body: "Explain {{ .topic }} briefly"},
+			values: map[string]string{"topic": "channels"},
+			want:   "Explain channels briefly",
+		},
+		{
+			name: "defaults",
+			tmpl: Template{Name: "t", Body: "Translate to {{ .lang }}", Vars: []Var{{Name: "lang", Default: "French"}}},
+			want: "Translate to French",
+		},
+		{
+			name: "missing value",
+			tmpl: Template{Name: "t", Body: "\n[{{ .missing }}]\n"},
+			want: "[]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.tmpl.Render(tt.values)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,15 +1,21 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/aj-seven/llmverse/internal/config"
 	"github.com/aj-seven/llmverse/internal/history"
+	"github.com/aj-seven/llmverse/internal/inputhistory"
+	"github.com/aj-seven/llmverse/internal/state"
 	"github.com/aj-seven/llmverse/pkg/keymap"
 	aihub "github.com/aj-seven/llmverse/internal/providers/ollama"
 	"github.com/aj-seven/llmverse/pkg/messages"
 
+	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -22,6 +28,9 @@ const (
 	ChatView View = iota
 	HistoryView
 	ModelSelectionView
+	CollectionsView
+	CompareView
+	HostsView
 )
 
 // Root Model
@@ -37,24 +46,55 @@ type Model struct {
 	footer         *Footer
 	toast          *Toast
 	chat           *ChatModel
+	// tabs holds a chat per open history, in tab order; chat is the
+	// active one.
+	tabs           []*ChatModel
 	history        *HistoryModel
 	modelSelection *ModelSelection
+	collections    *CollectionsModel
+	compare        *CompareModel
+	hosts          *HostsModel
 
+	// models lists the models of every reachable host.
 	models         []aihub.OllamaModel
 	historyManager *history.Manager
 	currentModel   string
+	// currentHost names the endpoint serving currentModel.
+	currentHost string
+
+	// inputs is the input history recalled in every chat.
+	inputs *inputhistory.History
+
+	// state holds the UI state kept across runs.
+	state *state.Store
+
+	// online holds the hosts that answered the last check; hostErrs
+	// holds why the others did not.
+	online   map[string]bool
+	hostErrs map[string]error
 
 	lastWS tea.WindowSizeMsg
 	hasWS  bool
 
+	// confirm is a dialog shown on top of the active view; onConfirm
+	// receives the user's choice once it is made.
+	confirm   *ConfirmDialog
+	onConfirm func(yes bool) tea.Cmd
+
+	// info is a read-only popup, e.g. the /help output, closed by any key.
+	info *Popup
+
+	commands *commandRegistry
+
 	cfg *config.Config
 }
 
 // Constructor
 
 func New(
-	models []aihub.OllamaModel,
+	hosts []aihub.HostModels,
 	historyManager *history.Manager,
+	st *state.Store,
 	cfg *config.Config,
 ) *Model {
 
@@ -66,12 +106,19 @@ func New(
 		header:         NewHeader(),
 		footer:         newFooter(toast),
 		toast:          toast,
-		models:         models,
 		historyManager: historyManager,
-		currentModel:   models[0].Name,
+		currentHost:    endpointName(cfg, ""),
+		inputs:         loadInputHistory(cfg),
+		state:          st,
+		commands:       newCommandRegistry(),
 		cfg:            cfg,
 	}
 
+	m.setHosts(hosts)
+	if m.currentModel == "" {
+		last := lastModel(m.models, st, m.currentHost)
+		m.currentModel, m.currentHost = last.Name, last.Host
+	}
 	m.newChat(m.currentModel, "")
 	m.updateFooterContent()
 
@@ -81,7 +128,7 @@ func New(
 // Init 
 
 func (m *Model) Init() tea.Cmd {
-	return m.chat.Init()
+	return tea.Batch(m.chat.Init(), m.checkConnection(true))
 }
 
 // Update
@@ -93,6 +140,19 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds = append(cmds, cmd)
 	}
 
+	// Stream messages go to the chat of their tab, whichever tab or view
+	// is active.
+	if id, ok := streamOwner(msg); ok {
+		return m, tea.Batch(append(cmds, m.updateTab(id, msg))...)
+	}
+	if _, ok := msg.(spinner.TickMsg); ok {
+		for _, chat := range m.tabs {
+			_, cmd := chat.Update(msg)
+			cmds = append(cmds, cmd)
+		}
+		return m, tea.Batch(cmds...)
+	}
+
 	switch msg := msg.(type) {
 
 	// WINDOW RESIZE
@@ -111,6 +171,10 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.footer.SetWidth(msg.Width)
 		m.toast.SetWidth(msg.Width)
 
+		if m.confirm != nil {
+			m.confirm.SetSize(msg.Width, m.contentHeight())
+		}
+
 		m.updateFooterContent()
 		m.applyLayout()
 
@@ -118,19 +182,153 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case ModelSelectedMsg:
 		m.currentModel = msg.Name
+		if msg.Host != "" {
+			m.currentHost = msg.Host
+		}
 		m.newChat(m.currentModel, "")
 		m.view = ChatView
 		m.updateFooterContent()
 		m.applyLayout()
 
+		if err := m.state.UseModel(msg.Name); err != nil {
+			return m, ShowToast("Failed to save state: "+err.Error(), 3*time.Second)
+		}
 		return m, ShowToast(
-			fmt.Sprintf("Model changed to %s", msg.Name),
+			fmt.Sprintf("Model changed to %s", m.modelLabel()),
 			2*time.Second,
 		)
 
+	// HOSTS
+
+	case HostSelectedMsg:
+		return m, m.switchHost(msg.Name)
+
+	case openHostsMsg:
+		return m, m.openHosts()
+
+	// CONNECTION
+
+	case connectionMsg:
+		return m, m.handleConnection(msg)
+
+	case connectionTickMsg:
+		return m, m.checkConnection(true)
+
+	case reconnectMsg:
+		return m, m.checkConnection(false)
+
+	// GENERATED TITLES
+
+	case titleGeneratedMsg:
+		if msg.err != nil {
+			return m, nil
+		}
+		_ = m.historyManager.EditHistory(msg.historyID, func(h *history.History) {
+			// Keep a title the user set while the title was generated.
+			if len(h.Messages) > 0 && h.Title == history.TitleFromPrompt(h.Messages[0].Content) {
+				h.Title = msg.title
+			}
+		})
+		if m.view == HistoryView {
+			m.history.reload()
+		}
+		return m, nil
+
+	// CONCURRENT EDITS
+
+	case historyConflictMsg:
+		return m, m.askConfirm(
+			"Chat changed in another window",
+			"Overwrite it with this window's version?\nChoosing No reloads the other version.",
+			func(yes bool) tea.Cmd {
+				if yes {
+					if err := m.historyManager.ForceSaveHistory(msg.historyID); err != nil {
+						return ShowToast("Save failed: "+err.Error(), 3*time.Second)
+					}
+					return ShowToast("Chat saved", 2*time.Second)
+				}
+				if _, err := m.historyManager.ReloadHistory(msg.historyID); err != nil {
+					return ShowToast("Reload failed: "+err.Error(), 3*time.Second)
+				}
+				// Rebuild the tab's chat on the reloaded history.
+				m.tabs = slices.DeleteFunc(m.tabs, func(c *ChatModel) bool {
+					return c.historyID == msg.historyID
+				})
+				m.syncTabs()
+				m.updateFooterContent()
+				m.applyLayout()
+				return tea.Batch(m.chat.Init(), ShowToast("Chat reloaded", 2*time.Second))
+			},
+		)
+
+	// PERSONAS
+
+	case personaPickedMsg:
+		m.newChat(m.currentModel, "")
+		applyPersona(m.historyManager, m.cfg, msg.name)
+		m.updateFooterContent()
+		m.applyLayout()
+		return m, tea.Batch(
+			m.chat.Init(),
+			ShowToast(fmt.Sprintf("New chat with %s", msg.name), 2*time.Second),
+		)
+
+	// SLASH COMMANDS
+
+	case slashCommandMsg:
+		cmd := m.commands.Run(m, msg.input)
+		m.updateFooterContent()
+		return m, cmd
+
+	// MODEL COMPARISON
+
+	case CompareModelsMsg:
+		m.compare = NewCompareModel(msg.Models, m.historyManager, m.cfg, m.chat.workDir, func(historyID string) bool {
+			chat := m.tab(historyID)
+			return chat != nil && chat.streaming
+		})
+		m.view = CompareView
+		m.updateFooterContent()
+		m.applyLayout()
+		return m, tea.Batch(append(cmds, m.compare.Init())...)
+
+	case compareStartedMsg, compareChunkMsg, compareDoneMsg:
+		// Streams are drained even if another view was opened meanwhile.
+		if m.compare != nil {
+			model, cmd := m.compare.Update(msg)
+			m.compare = model.(*CompareModel)
+			cmds = append(cmds, cmd)
+		}
+		return m, tea.Batch(cmds...)
+
+	case compareKeptMsg:
+		m.chat.updateViewport(true)
+		return m, tea.Batch(
+			m.chat.saveHistory(),
+			ShowToast(fmt.Sprintf("Kept the answer of %s", msg.model), 2*time.Second),
+			func() tea.Msg { return messages.GoBackMsg{} },
+		)
+
+	// DOCUMENT COLLECTIONS
+
+	case collectionIndexMsg:
+		// Indexing keeps running when the user leaves the view.
+		if m.collections != nil {
+			model, cmd := m.collections.Update(msg)
+			m.collections = model.(*CollectionsModel)
+			return m, tea.Batch(append(cmds, cmd)...)
+		}
+		return m, tea.Batch(cmds...)
+
+	case collectionsChangedMsg:
+		for _, chat := range m.tabs {
+			chat.collection = nil
+		}
+		return m, tea.Batch(cmds...)
+
 	// SYSTEM POPUPS
 
-	case messages.SystemPopupStatusMsg:
+	case messages.SystemPopupStatusMsg, chatModeMsg:
 		m.updateFooterContent()
 		m.applyLayout()
 		return m, nil
@@ -159,6 +357,31 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, tea.Quit
 	}
 
+	// INFO POPUP
+
+	if m.info != nil {
+		if _, ok := msg.(tea.KeyMsg); ok {
+			m.info = nil
+			return m, tea.Batch(cmds...)
+		}
+	}
+
+	// CONFIRM DIALOG
+
+	if m.confirm != nil {
+		if _, ok := msg.(tea.KeyMsg); ok {
+			m.confirm.Update(msg)
+			if m.confirm.Choice != nil {
+				choice, onConfirm := *m.confirm.Choice, m.onConfirm
+				m.confirm, m.onConfirm = nil, nil
+				if onConfirm != nil {
+					cmds = append(cmds, onConfirm(choice))
+				}
+			}
+			return m, tea.Batch(cmds...)
+		}
+	}
+
 	//GLOBAL KEYS 
 
 	if k, ok := msg.(tea.KeyMsg); ok {
@@ -169,18 +392,21 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tea.Quit
 
 		case "ctrl+h":
-			histories, _ := m.historyManager.GetAllHistories()
-			m.history = NewHistoryModel(histories)
-			m.applyLayout()
-			return m, func() tea.Msg {
-				return messages.PushViewMsg{View: int(HistoryView)}
+			return m, m.openHistoryView()
+
+		case "ctrl+n":
+			if m.view != ChatView || m.chat.system.codePopup || m.chat.templates.IsOpen() {
+				break
 			}
+			return m, m.startNewChat()
 
 		case "ctrl+o":
-			m.modelSelection = NewModelSelection(m.models)
-			m.applyLayout()
-			return m, func() tea.Msg {
-				return messages.PushViewMsg{View: int(ModelSelectionView)}
+			return m, m.openModelSelection()
+		}
+
+		if m.view == ChatView && !m.chat.system.codePopup && !m.chat.templates.IsOpen() {
+			if cmd, ok := m.handleTabKey(k); ok {
+				return m, tea.Batch(append(cmds, cmd)...)
 			}
 		}
 	}
@@ -203,28 +429,12 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.history = model.(*HistoryModel)
 		cmds = append(cmds, cmd)
 
-		if id := m.history.DeletedID(); id != "" {
-			m.historyManager.DeleteHistory(id)
-			histories, _ := m.historyManager.GetAllHistories()
-			m.history = NewHistoryModel(histories)
-			m.applyLayout()
-			return m, ShowToast("Chat deleted", 2*time.Second)
-		}
+		m.updateFooterContent()
+		// Deleting or trashing an open chat replaces it with a new one.
+		m.syncTabs()
 
 		if id := m.history.SelectedHistoryID(); id != "" {
-			m.newChat("", id)
-			m.view = ChatView
-			m.updateFooterContent()
-			m.applyLayout()
-
-			h := m.historyManager.GetCurrentHistory()
-			return m, ShowToast(
-				fmt.Sprintf(
-					"Loaded chat from %s",
-					h.UpdatedAt.Format("2006-01-02 15:04"),
-				),
-				2*time.Second,
-			)
+			cmds = append(cmds, m.openHistory(id, false))
 		}
 
 	case ModelSelectionView:
@@ -232,6 +442,24 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		model, cmd = m.modelSelection.Update(msg)
 		m.modelSelection = model.(*ModelSelection)
 		cmds = append(cmds, cmd)
+
+	case CollectionsView:
+		var model tea.Model
+		model, cmd = m.collections.Update(msg)
+		m.collections = model.(*CollectionsModel)
+		cmds = append(cmds, cmd)
+
+	case CompareView:
+		var model tea.Model
+		model, cmd = m.compare.Update(msg)
+		m.compare = model.(*CompareModel)
+		cmds = append(cmds, cmd)
+
+	case HostsView:
+		var model tea.Model
+		model, cmd = m.hosts.Update(msg)
+		m.hosts = model.(*HostsModel)
+		cmds = append(cmds, cmd)
 	}
 
 	return m, tea.Batch(cmds...)
@@ -242,6 +470,13 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m *Model) View() string {
 	var content string
 
+	// Tabs and search matches change with every stream message.
+	m.footer.SetStatus("")
+	if m.view == ChatView {
+		m.header.SetTabs(m.tabBar())
+		m.footer.SetStatus(m.chat.FindStatus())
+	}
+
 	switch m.view {
 	case ChatView:
 		content = m.chat.View()
@@ -249,6 +484,18 @@ func (m *Model) View() string {
 		content = m.history.View()
 	case ModelSelectionView:
 		content = m.modelSelection.View()
+	case CollectionsView:
+		content = m.collections.View()
+	case CompareView:
+		content = m.compare.View()
+	case HostsView:
+		content = m.hosts.View()
+	}
+
+	if m.confirm != nil {
+		content = m.confirm.View()
+	} else if m.info != nil {
+		content = popupCentered(m.width, m.contentHeight(), m.info.View())
 	}
 
 	header := m.header.View()
@@ -291,8 +538,8 @@ func (m *Model) applyLayout() {
 	w := m.lastWS.Width
 	h := m.contentHeight()
 
-	if m.chat != nil {
-		m.chat.SetSize(w, h)
+	for _, chat := range m.tabs {
+		chat.SetSize(w, h)
 	}
 	if m.history != nil {
 		m.history.SetSize(w, h)
@@ -300,78 +547,522 @@ func (m *Model) applyLayout() {
 	if m.modelSelection != nil {
 		m.modelSelection.SetSize(w, h)
 	}
+	if m.collections != nil {
+		m.collections.SetSize(w, h)
+	}
+	if m.compare != nil {
+		m.compare.SetSize(w, h)
+	}
+	if m.hosts != nil {
+		m.hosts.SetSize(w, h)
+	}
 }
 
+// newChat opens a new or saved chat in the current tab. If a response is
+// streaming there, the chat opens in a new tab instead so the stream keeps
+// running in the background.
 func (m *Model) newChat(modelName, historyID string) {
 	if historyID != "" {
-		h, err := m.historyManager.LoadHistory(historyID)
-		if err != nil {
+		if err := m.loadChat(historyID, true); err != nil {
 			m.historyManager.NewHistory(m.currentModel)
-		} else {
-			m.currentModel = h.Model
 		}
 	} else {
-		m.historyManager.NewHistory(modelName)
+		// Text typed into a chat with no messages yet moves along to the
+		// new chat instead of being left behind as a draft.
+		var draft string
+		if h := m.historyManager.GetCurrentHistory(); h != nil && len(h.Messages) == 0 && !m.streaming() {
+			draft = h.Draft
+			m.historyManager.SetDraft(h.ID, "")
+		}
+
+		var h *history.History
+		if m.streaming() {
+			h = m.historyManager.NewTab(modelName)
+		} else {
+			h = m.historyManager.NewHistory(modelName)
+		}
+		m.historyManager.SetDraft(h.ID, draft)
+		m.historyManager.SetHost(m.currentHost)
+		applyPersona(m.historyManager, m.cfg, defaultPersonaName)
 	}
 
-	m.chat = NewChatModel(
-		m.currentModel,
+	m.syncTabs()
+}
+
+// loadChat makes a saved history current, in a new tab if a response is
+// streaming in the current one. It returns history.ErrLeased if the chat
+// is open in another llmv instance, unless force is set.
+func (m *Model) loadChat(id string, force bool) error {
+	if !m.streaming() || m.historyManager.Tab(id) != nil {
+		_, err := m.historyManager.LoadHistory(id, force)
+		return err
+	}
+
+	prev := m.chat.historyID
+	tab := m.historyManager.NewTab(m.currentModel)
+	if _, err := m.historyManager.LoadHistory(id, force); err != nil {
+		m.historyManager.CloseTab(tab.ID)
+		m.historyManager.SwitchTab(prev)
+		return err
+	}
+	return nil
+}
+
+// streaming reports whether a response is streaming in the current chat.
+func (m *Model) streaming() bool {
+	return m.chat != nil && m.chat.streaming
+}
+
+func (m *Model) newChatModel(h *history.History) *ChatModel {
+	modelName, host := h.Model, h.Host
+	if modelName == "" {
+		modelName, host = m.currentModel, m.currentHost
+	}
+
+	chat := NewChatModel(
+		modelName,
+		h.ID,
 		m.historyManager,
 		m.cfg,
 	)
+	chat.completer = func(input string) []completionItem {
+		return m.commands.Complete(m, input)
+	}
+	chat.inputs = m.inputs
+	chat.host = endpointName(m.cfg, host)
+	chat.SetOffline(!m.isOnline(chat.host))
+	return chat
+}
+
+// lastModel returns the model used when llmv last exited if it is still
+// installed, preferring the one on host, otherwise the first model of
+// host or of any host. Without a model list, as when starting offline,
+// the last model is assumed to be there.
+func lastModel(models []aihub.OllamaModel, st *state.Store, host string) aihub.OllamaModel {
+	last := st.Get().LastModel
+	if len(models) == 0 {
+		return aihub.OllamaModel{Name: last, Host: host}
+	}
+
+	found := -1
+	for i, model := range models {
+		if model.Name == last {
+			if model.Host == host {
+				return model
+			}
+			if found < 0 {
+				found = i
+			}
+		}
+	}
+	if found >= 0 {
+		return models[found]
+	}
+	for _, model := range models {
+		if model.Host == host {
+			return model
+		}
+	}
+	return models[0]
+}
+
+// hostLabel returns the URL of the named host without its scheme, after
+// the host's name when several hosts are configured.
+func hostLabel(cfg *config.Config, name string) string {
+	if cfg == nil {
+		return ""
+	}
+	e, ok := cfg.Endpoint(name)
+	if !ok {
+		e = cfg.DefaultEndpoint()
+	}
+	host := strings.TrimPrefix(e.URL, "http://")
+	host = strings.TrimPrefix(host, "https://")
+	if len(cfg.Endpoints()) > 1 {
+		return e.Name + " " + host
+	}
+	return host
+}
+
+// modelLabel names the current model, with its host when several hosts
+// are configured.
+func (m *Model) modelLabel() string {
+	if m.cfg == nil || len(m.cfg.Endpoints()) < 2 {
+		return m.currentModel
+	}
+	return m.currentModel + " @ " + m.currentHost
+}
+
+// loadInputHistory loads the input history. Prompts are only kept in
+// memory when chat histories are encrypted, so they never reach the disk
+// in the clear.
+func loadInputHistory(cfg *config.Config) *inputhistory.History {
+	if cfg != nil && cfg.Storage.History.Encrypt {
+		return inputhistory.NewMemory()
+	}
+	inputs, err := inputhistory.Load()
+	if err != nil {
+		return inputhistory.NewMemory()
+	}
+	return inputs
+}
+
+// Tabs
+
+// syncTabs matches the chats to the histories open in the manager: new
+// tabs get a chat, and streams of closed tabs are stopped.
+func (m *Model) syncTabs() {
+	current := m.historyManager.GetCurrentHistory()
+
+	var tabs []*ChatModel
+	for _, h := range m.historyManager.Tabs() {
+		chat := m.tab(h.ID)
+		if chat == nil {
+			chat = m.newChatModel(h)
+			if m.hasWS {
+				chat.SetSize(m.lastWS.Width, m.contentHeight())
+			}
+		}
+		tabs = append(tabs, chat)
+
+		if h == current {
+			m.chat = chat
+			m.currentModel, m.currentHost = chat.modelName, chat.host
+		}
+	}
+
+	for _, chat := range m.tabs {
+		if chat.streaming && !slices.Contains(tabs, chat) {
+			_ = chat.stopStreaming()
+		}
+	}
+
+	m.tabs = tabs
+	m.chat.unseen = false
+}
+
+// tab returns the chat of the open history with the given ID, or nil.
+func (m *Model) tab(historyID string) *ChatModel {
+	for _, chat := range m.tabs {
+		if chat.historyID == historyID {
+			return chat
+		}
+	}
+	return nil
+}
+
+// updateTab hands a stream message to the chat of its tab. A response
+// that finishes in a background tab is marked in the tab bar.
+func (m *Model) updateTab(historyID string, msg tea.Msg) tea.Cmd {
+	chat := m.tab(historyID)
+	if chat == nil {
+		return nil
+	}
+
+	_, cmd := chat.Update(msg)
+	if _, ok := msg.(streamDoneMsg); ok && chat != m.chat {
+		chat.unseen = true
+	}
+	return cmd
+}
+
+// handleTabKey opens, closes and switches tabs. It reports whether k was
+// a tab key.
+func (m *Model) handleTabKey(k tea.KeyMsg) (tea.Cmd, bool) {
+	i := slices.Index(m.tabs, m.chat)
+
+	switch key := k.String(); key {
+	case "alt+t":
+		return m.newTab(), true
+
+	case "alt+w":
+		return m.closeTab(), true
+
+	case "ctrl+pgdown":
+		return m.switchTab((i + 1) % len(m.tabs)), true
+
+	case "ctrl+pgup":
+		return m.switchTab((i + len(m.tabs) - 1) % len(m.tabs)), true
+
+	case "alt+1", "alt+2", "alt+3", "alt+4", "alt+5", "alt+6", "alt+7", "alt+8", "alt+9":
+		return m.switchTab(int(key[len(key)-1] - '1')), true
+	}
+	return nil, false
+}
+
+// newTab opens a new chat next to the current one.
+func (m *Model) newTab() tea.Cmd {
+	m.historyManager.NewTab(m.currentModel)
+	m.historyManager.SetHost(m.currentHost)
+	applyPersona(m.historyManager, m.cfg, defaultPersonaName)
+	m.syncTabs()
+	m.updateFooterContent()
+	m.applyLayout()
+	return m.chat.Init()
+}
+
+// closeTab saves and closes the current chat, stopping its response.
+func (m *Model) closeTab() tea.Cmd {
+	m.historyManager.CloseTab(m.chat.historyID)
+	m.syncTabs()
+	m.updateFooterContent()
+	m.applyLayout()
+	return m.chat.Init()
+}
+
+func (m *Model) switchTab(i int) tea.Cmd {
+	if i < 0 || i >= len(m.tabs) || m.tabs[i] == m.chat {
+		return nil
+	}
+	m.historyManager.SwitchTab(m.tabs[i].historyID)
+	m.syncTabs()
+	m.updateFooterContent()
+	m.applyLayout()
+	return m.chat.FocusInput()
+}
+
+// tabBar describes the open chats for the header.
+func (m *Model) tabBar() []Tab {
+	tabs := make([]Tab, len(m.tabs))
+	for i, chat := range m.tabs {
+		title := "New chat"
+		if h := chat.chatHistory(); h != nil && h.Title != "" {
+			title = h.Title
+		}
+		tabs[i] = Tab{
+			Title:     title,
+			Active:    chat == m.chat,
+			Streaming: chat.streaming,
+			Unseen:    chat.unseen,
+		}
+	}
+	return tabs
+}
+
+// openHistory switches the chat view to a saved history. If the chat is
+// already open in another llmv instance the user is asked first, since
+// both windows would otherwise keep overwriting each other.
+func (m *Model) openHistory(id string, force bool) tea.Cmd {
+	if !force {
+		if err := m.loadChat(id, false); errors.Is(err, history.ErrLeased) {
+			return m.askConfirm(
+				"Chat already open",
+				"This chat is open in another llmv window.\nOpen it here anyway?",
+				func(yes bool) tea.Cmd {
+					if !yes {
+						return nil
+					}
+					return m.openHistory(id, true)
+				},
+			)
+		}
+	}
+
+	m.newChat("", id)
+	m.view = ChatView
+	m.updateFooterContent()
+	m.applyLayout()
+
+	h := m.historyManager.GetCurrentHistory()
+	return tea.Batch(
+		m.chat.Init(),
+		ShowToast(
+			fmt.Sprintf(
+				"Loaded chat from %s",
+				h.UpdatedAt.Format("2006-01-02 15:04"),
+			),
+			2*time.Second,
+		),
+	)
+}
+
+// startNewChat opens the persona picker when personas are configured,
+// otherwise it starts a new chat right away.
+func (m *Model) startNewChat() tea.Cmd {
+	if m.cfg != nil && len(m.cfg.Personas) > 0 {
+		return m.chat.system.OpenPicker()
+	}
+	m.newChat(m.currentModel, "")
+	m.updateFooterContent()
+	m.applyLayout()
+	return tea.Batch(m.chat.Init(), ShowToast("New chat", 2*time.Second))
+}
+
+func (m *Model) openHistoryView() tea.Cmd {
+	m.history = NewHistoryModel(m.historyManager)
+	m.applyLayout()
+	return func() tea.Msg {
+		return messages.PushViewMsg{View: int(HistoryView)}
+	}
+}
+
+func (m *Model) openModelSelection() tea.Cmd {
+	m.modelSelection = NewModelSelection(m.models, m.currentModel, m.currentHost, m.state)
+	m.applyLayout()
+	return func() tea.Msg {
+		return messages.PushViewMsg{View: int(ModelSelectionView)}
+	}
+}
+
+func (m *Model) openHosts() tea.Cmd {
+	m.hosts = NewHostsModel(m.cfg, m.currentHost, m.online, m.hostErrs, m.models)
+	m.applyLayout()
+	return func() tea.Msg {
+		return messages.PushViewMsg{View: int(HostsView)}
+	}
+}
+
+// switchHost makes the named host serve new chats and starts one, keeping
+// the current model if the host has it.
+func (m *Model) switchHost(name string) tea.Cmd {
+	var models []aihub.OllamaModel
+	for _, model := range m.models {
+		if model.Host == name {
+			models = append(models, model)
+		}
+	}
+
+	m.currentHost = name
+	if !slices.ContainsFunc(models, func(model aihub.OllamaModel) bool {
+		return model.Name == m.currentModel
+	}) {
+		m.currentModel = lastModel(models, m.state, name).Name
+	}
+
+	m.newChat(m.currentModel, "")
+	m.view = ChatView
+	m.updateFooterContent()
+	m.applyLayout()
+
+	toast := fmt.Sprintf("Switched to %s", m.modelLabel())
+	if !m.isOnline(name) {
+		toast = fmt.Sprintf("Switched to %s, which is offline", name)
+	}
+	return tea.Batch(m.chat.Init(), ShowToast(toast, 2*time.Second))
 }
 
-func (m *Model) isSystemMessageSet() bool {
-	return m.cfg != nil && m.cfg.Assistant.Message != ""
+func (m *Model) openCollections() tea.Cmd {
+	if m.collections == nil || m.collections.indexing == "" {
+		m.collections = NewCollectionsModel(m.historyManager, m.cfg)
+	} else {
+		m.collections.reload()
+	}
+	m.applyLayout()
+	return func() tea.Msg {
+		return messages.PushViewMsg{View: int(CollectionsView)}
+	}
 }
 
+// showInfo shows a read-only popup over the current view.
+func (m *Model) showInfo(title, body string) {
+	m.info = NewPopup(title, body+"\n\nPress any key to close.", 72)
+}
+
+// askConfirm shows a confirmation dialog over the current view and calls
+// onConfirm with the user's answer.
+func (m *Model) askConfirm(title, msg string, onConfirm func(yes bool) tea.Cmd) tea.Cmd {
+	m.confirm = NewConfirmDialog(title, msg)
+	m.confirm.SetSize(m.lastWS.Width, m.contentHeight())
+	m.onConfirm = onConfirm
+	return nil
+}
+
+
 // Footer
 func (m *Model) updateFooterContent() {
 	m.footer.ShowContent(false)
 	m.footer.ShowShortcuts(false)
+	m.header.SetTabs(nil)
+	m.header.SetConnection(hostLabel(m.cfg, m.currentHost), m.isOnline(m.currentHost))
 
 	switch m.view {
 
 	case ChatView:
 		m.header.SetTitle("Chat")
+		m.header.SetTabs(m.tabBar())
 
-		sysMsgIndicator := " ✖"
-		if m.isSystemMessageSet() {
-			sysMsgIndicator = " ✔"
+		h := m.historyManager.GetCurrentHistory()
+		secondary := fmt.Sprintf("Persona: %s", personaLabel(h))
+		if h != nil && h.Collection != "" {
+			secondary += " · Docs: " + h.Collection
 		}
-
 		m.footer.SetContent(
-			fmt.Sprintf("Model: %s", m.currentModel),
-			fmt.Sprintf("System Message: %s", sysMsgIndicator),
+			fmt.Sprintf("Model: %s", m.modelLabel()),
+			secondary,
 		)
 		m.footer.SetShortcuts(
+			keymap.Shortcut{Key: "ctrl+n", Action: "New Chat"},
+			keymap.Shortcut{Key: "alt+t", Action: "New Tab"},
 			keymap.Shortcut{Key: "ctrl+o", Action: "Models"},
 			keymap.Shortcut{Key: "ctrl+h", Action: "History"},
-			keymap.Shortcut{Key: "ctrl+a", Action: "System Message"},
+			keymap.Shortcut{Key: "ctrl+a", Action: "Persona"},
+			keymap.Shortcut{Key: "alt+p", Action: "Templates"},
+			keymap.Shortcut{Key: "ctrl+s", Action: "Select"},
+			keymap.Shortcut{Key: "alt+e", Action: "Editor"},
+			keymap.Shortcut{Key: "ctrl+r", Action: "Recall"},
+			keymap.Shortcut{Key: "ctrl+f", Action: "Find"},
+			keymap.Shortcut{Key: "/", Action: "Commands"},
 			keymap.Shortcut{Key: "ctrl+q", Action: "Quit"},
 		)
+		if m.chat.Selecting() {
+			m.footer.SetShortcuts(append(
+				m.chat.SelectionShortcuts(),
+				keymap.Shortcut{Key: "ctrl+q", Action: "Quit"},
+			)...)
+		}
+		if m.chat.Searching() {
+			m.footer.SetShortcuts(append(
+				m.chat.SearchShortcuts(),
+				keymap.Shortcut{Key: "ctrl+q", Action: "Quit"},
+			)...)
+		}
+		if m.chat.Finding() {
+			m.footer.SetShortcuts(append(
+				m.chat.FindShortcuts(),
+				keymap.Shortcut{Key: "ctrl+q", Action: "Quit"},
+			)...)
+		}
 		m.footer.ShowContent(true)
 		m.footer.ShowShortcuts(true)
 
 	case HistoryView:
-		m.header.SetTitle("Chat History")
-		m.footer.SetShortcuts(
-			keymap.Shortcut{Key: "↑/↓", Action: "Navigate"},
-			keymap.Shortcut{Key: "enter", Action: "Open"},
-			keymap.Shortcut{Key: "ctrl+d", Action: "Delete"},
-			keymap.Shortcut{Key: "esc", Action: "Back"},
+		m.header.SetTitle(m.history.Title())
+		m.footer.SetShortcuts(append(
+			m.history.Shortcuts(),
 			keymap.Shortcut{Key: "ctrl+q", Action: "Quit"},
-		)
+		)...)
+		m.footer.ShowShortcuts(true)
+
+	case CompareView:
+		m.header.SetTitle("Compare Models")
+		m.footer.SetShortcuts(append(
+			m.compare.Shortcuts(),
+			keymap.Shortcut{Key: "ctrl+q", Action: "Quit"},
+		)...)
+		m.footer.ShowShortcuts(true)
+
+	case CollectionsView:
+		m.header.SetTitle("Document Collections")
+		m.footer.SetShortcuts(append(
+			m.collections.Shortcuts(),
+			keymap.Shortcut{Key: "ctrl+q", Action: "Quit"},
+		)...)
 		m.footer.ShowShortcuts(true)
 
 	case ModelSelectionView:
 		m.header.SetTitle("Model Selection")
-		m.footer.SetShortcuts(
-			keymap.Shortcut{Key: "↑/↓", Action: "Navigate"},
-			keymap.Shortcut{Key: "enter", Action: "Select"},
-			keymap.Shortcut{Key: "esc", Action: "Back"},
+		m.footer.SetShortcuts(append(
+			m.modelSelection.Shortcuts(),
 			keymap.Shortcut{Key: "ctrl+q", Action: "Quit"},
-		)
+		)...)
+		m.footer.ShowShortcuts(true)
+
+	case HostsView:
+		m.header.SetTitle("Hosts")
+		m.footer.SetShortcuts(append(
+			m.hosts.Shortcuts(),
+			keymap.Shortcut{Key: "ctrl+q", Action: "Quit"},
+		)...)
 		m.footer.ShowShortcuts(true)
 	}
 }
\ No newline at end of file
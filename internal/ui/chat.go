@@ -1,11 +1,17 @@
 package ui
 
 import (
+	"errors"
+	"fmt"
+	"os"
 	"strings"
 	"time"
 
+	"github.com/aj-seven/llmverse/internal/attach"
 	"github.com/aj-seven/llmverse/internal/config"
 	"github.com/aj-seven/llmverse/internal/history"
+	"github.com/aj-seven/llmverse/internal/inputhistory"
+	"github.com/aj-seven/llmverse/internal/rag"
 	aihub "github.com/aj-seven/llmverse/internal/providers/ollama"
 	"github.com/aj-seven/llmverse/pkg/chat"
 	"github.com/aj-seven/llmverse/pkg/messages"
@@ -14,29 +20,91 @@ import (
 	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss"
 )
 
 const (
 	minInputLines = 1
 	maxInputLines = 5
+
+	maxPathCompletions = 20
+
+	defaultPlaceholder = "Start asking anything..."
 )
 
 // Messages
 
-type streamChunkMsg struct{ chunk string }
-type streamDoneMsg struct{}
-type startStreamMsg struct{}
-type animationTickMsg struct{}
+// The stream messages carry the ID of the history being answered, so they
+// reach the chat of their tab even when another tab is active.
+
+type streamChunkMsg struct {
+	historyID string
+	chunk     string
+}
+type streamDoneMsg struct{ historyID string }
+type startStreamMsg struct{ historyID string }
+
+// streamOpenedMsg reports the outcome of a chat request, after retries
+// and fallbacks: the stream and the model it comes from, or the error.
+// cancel tells apart requests that were stopped meanwhile.
+type streamOpenedMsg struct {
+	historyID string
+	stream    <-chan string
+	target    aihub.Target
+	err       error
+	cancel    chan struct{}
+}
+type animationTickMsg struct{ historyID string }
+
+// titleGeneratedMsg carries a title generated in the background for the
+// history with the given ID.
+type titleGeneratedMsg struct {
+	historyID string
+	title     string
+	err       error
+}
+
+// sourcesRetrievedMsg carries the document chunks retrieved for the
+// question being answered.
+type sourcesRetrievedMsg struct {
+	historyID  string
+	collection *rag.Collection
+	sources    []chat.Source
+	err        error
+}
+
+// historyConflictMsg reports that the chat with the given history ID was
+// saved by another llmv instance since it was loaded.
+type historyConflictMsg struct{ historyID string }
+
+// renderedMessage is a rendered message bubble, kept along with what it
+// was rendered from.
+type renderedMessage struct {
+	key    renderKey
+	body   string
+	bubble string
+}
+
+// renderKey is what a message bubble depends on. Comparing keys of an
+// unchanged message is cheap: its content string shares its bytes with
+// the key, so equal strings are recognized without reading them.
+type renderKey struct {
+	role, content, suffix string
+	block                 int
+	model, host           string
+	// chatModel and chatHost are the chat's model and host, which the
+	// label of an answer names or compares against.
+	chatModel, chatHost string
+}
 
 // Chat Model
 
 type ChatModel struct {
 	viewport viewport.Model
-	textarea textarea.Model
-	system   *SystemModel
-	spinner  spinner.Model
+	textarea  textarea.Model
+	system    *SystemModel
+	templates *TemplateModel
+	spinner   spinner.Model
 
 	userStyle        lipgloss.Style
 	botStyle         lipgloss.Style
@@ -52,21 +120,82 @@ type ChatModel struct {
 	bubbleUnfocused  lipgloss.Style
 
 	modelName string
-	back      bool
+	// host names the endpoint serving the model.
+	host string
+	back bool
 
+	// historyID is the open history shown by this chat.
+	historyID      string
 	historyManager *history.Manager
 	cfg            *config.Config
 
+	// completion is the autocompletion popup above the input; completer
+	// supplies its suggestions for the current input value.
+	completion completion
+	completer  func(input string) []completionItem
+
+	// workDir is where @path mentions are resolved; paths indexes it for
+	// completion.
+	workDir string
+	paths   *attach.PathIndex
+
+	// inputs is the input history shared by all chats. recall is the
+	// index of the prompt shown in the input while stepping through it,
+	// or -1, and pending the text typed before. search is the open
+	// reverse search, if any, and draft the last recorded draft.
+	inputs  *inputhistory.History
+	recall  int
+	pending string
+	search  *inputSearch
+	draft   string
+
+	// collection caches the loaded document collection of the chat.
+	collection *rag.Collection
+
+	// rendered caches the bubble of each message, so that only messages
+	// that changed are rendered again; renderedWidth and renderedStyle
+	// are the layout it was rendered for.
+	markdown      markdownRenderer
+	rendered      []renderedMessage
+	renderedWidth int
+	renderedStyle string
+
+	// offsets holds the first viewport line of each message, followed by
+	// the line after the last one.
+	offsets []int
+
+	// Selection mode: selected is the highlighted message and block its
+	// highlighted code block. saveDialog asks where to save saveCode.
+	selecting  bool
+	selected   int
+	block      int
+	saveDialog *InputDialog
+	saveCode   string
+
+	// find is the open search in the chat, if any.
+	find *chatFind
+
 	stream       <-chan string
 	cancelStream chan struct{}
 	streaming    bool
 
+	// unseen marks a response that finished while the chat was in a
+	// background tab.
+	unseen bool
+
+	// offline is set while the Ollama server cannot be reached.
+	offline bool
+
 	width       int
 	height      int
 	maxMsgWidth int
 	ready       bool
 	lockScroll  bool
 
+	// scrollTo is a scroll position to restore once the chat is sized,
+	// or -1.
+	scrollTo int
+
 	animationStep int
 
 	// click-to-focus geometry
@@ -80,34 +209,55 @@ type ChatModel struct {
 
 func NewChatModel(
 	modelName string,
+	historyID string,
 	hm *history.Manager,
 	cfg *config.Config,
 ) *ChatModel {
 
 	ta := textarea.New()
-	ta.Placeholder = "Start asking anything..."
+	ta.Placeholder = defaultPlaceholder
 	ta.Prompt = "❯ "
 	ta.ShowLineNumbers = false
 	ta.KeyMap.InsertNewline.SetEnabled(true)
 	ta.Focus()
 	ta.Cursor.Blink = true
 
+	var draft string
+	if hm != nil {
+		if h := hm.Tab(historyID); h != nil {
+			draft = h.Draft
+			ta.SetValue(draft)
+		}
+	}
+
 	sp := spinner.New()
 	sp.Spinner = spinner.MiniDot
 	sp.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("5"))
 
-	system := NewSystemModel(cfg)
+	system := NewSystemModel(cfg, hm)
 	if system == nil {
 		system = &SystemModel{}
 	}
 
+	workDir, err := os.Getwd()
+	if err != nil {
+		workDir = "."
+	}
+
 	return &ChatModel{
 		modelName:      modelName,
+		historyID:      historyID,
 		textarea:       ta,
 		system:         system,
+		templates:      NewTemplateModel(),
+		workDir:        workDir,
+		paths:          attach.NewPathIndex(workDir),
 		historyManager: hm,
 		cfg:            cfg,
 		spinner:        sp,
+		recall:         -1,
+		draft:          draft,
+		scrollTo:       -1,
 
 		userStyle: lipgloss.NewStyle().
 			Foreground(lipgloss.Color("5")).Bold(true),
@@ -199,6 +349,11 @@ func (m *ChatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, tea.Batch(cmds...)
 	}
 
+	if m.templates.IsOpen() && !isWindowSizeMsg(msg) {
+		cmds = append(cmds, m.templates.Update(msg))
+		return m, tea.Batch(cmds...)
+	}
+
 	m.spinner, cmd = m.spinner.Update(msg)
 	cmds = append(cmds, cmd)
 
@@ -209,10 +364,29 @@ func (m *ChatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			break
 		}
 
+		if m.saveDialog != nil {
+			return m, m.updateSaveDialog(msg)
+		}
+		if m.selecting {
+			return m, m.updateSelection(msg)
+		}
+		if m.search != nil {
+			return m, m.updateSearch(msg)
+		}
+		if m.find != nil {
+			return m, m.updateFind(msg)
+		}
+
 		if isTypingKey(msg) && !m.streaming && !m.textarea.Focused() {
 			cmds = append(cmds, m.FocusInput())
 		}
 
+		if m.completion.visible() {
+			if handled, cmd := m.updateCompletion(msg); handled {
+				return m, cmd
+			}
+		}
+
 		switch msg.String() {
 
 		case "ctrl+q":
@@ -224,7 +398,31 @@ func (m *ChatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "ctrl+a":
 			m.blurInput()
 			if !m.streaming {
-				m.system.Focus()
+				return m, m.system.Focus()
+			}
+			return m, nil
+
+		case "ctrl+s":
+			return m, m.startSelection()
+
+		case "ctrl+r":
+			return m, m.startSearch()
+
+		case "ctrl+f":
+			return m, m.startFind()
+
+		case "alt+e":
+			if m.streaming {
+				return m, nil
+			}
+			m.blurInput()
+			m.completion.clear()
+			return m, openEditor(m.textarea.Value())
+
+		case "alt+p":
+			if !m.streaming {
+				m.blurInput()
+				return m, m.templates.Open(m.historyID)
 			}
 			return m, nil
 
@@ -236,11 +434,42 @@ func (m *ChatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 
 		case "enter":
-			if strings.TrimSpace(m.textarea.Value()) != "" && !m.streaming {
+			if m.streaming {
+				break
+			}
+			input := strings.TrimSpace(m.textarea.Value())
+			if isSlashCommand(input) {
+				m.completion.clear()
+				m.setInput("")
+				return m, tea.Batch(
+					m.addInput(input),
+					func() tea.Msg { return slashCommandMsg{input: input} },
+				)
+			}
+			if strings.HasPrefix(input, commandPrefix+commandPrefix) {
+				m.textarea.SetValue(strings.TrimPrefix(input, commandPrefix))
+			}
+			if input != "" && m.offline {
+				return m, tea.Batch(
+					ShowToast("Ollama is offline; your prompt is kept", 3*time.Second),
+					func() tea.Msg { return reconnectMsg{} },
+				)
+			}
+			if input != "" {
 				return m.handleUserInput()
 			}
 
-		case "up", "down", "ctrl+up", "ctrl+down":
+		case "up", "down":
+			if m.canRecall() {
+				if msg.String() == "up" {
+					return m, m.recallInput(-1)
+				}
+				return m, m.recallInput(1)
+			}
+			m.blurInput()
+			m.lockScroll = true
+
+		case "ctrl+up", "ctrl+down":
 			m.blurInput()
 			m.lockScroll = true
 		}
@@ -268,24 +497,70 @@ func (m *ChatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.viewport.ScrollDown(3)
 		}
 
+	case templateRenderedMsg:
+		if msg.err != nil {
+			return m, tea.Batch(
+				ShowToast(msg.err.Error(), 3*time.Second),
+				m.FocusInput(),
+			)
+		}
+		if msg.text == "" {
+			return m, nil
+		}
+		// An unsent draft is kept with the template after it, and while a
+		// reply streams the text waits in the input; the user sends it.
+		if draft := strings.TrimSpace(m.textarea.Value()); draft != "" || m.streaming {
+			if draft != "" {
+				msg.text = draft + "\n\n" + msg.text
+			}
+			m.setInput(msg.text)
+			return m, tea.Batch(
+				ShowToast("Template added to the input", 2*time.Second),
+				m.FocusInput(),
+			)
+		}
+		m.setInput(msg.text)
+		return m.handleUserInput()
+
+	case editorFinishedMsg:
+		cmds = append(cmds, m.handleEditorFinished(msg))
+
 	case startStreamMsg:
 		cmds = append(cmds, m.startStream())
 
+	case sourcesRetrievedMsg:
+		if msg.collection != nil {
+			m.collection = msg.collection
+		}
+		if msg.err != nil {
+			cmds = append(cmds, ShowToast("Retrieval failed: "+msg.err.Error(), 3*time.Second))
+		} else {
+			m.historyManager.SetLastUserSources(m.historyID, msg.sources)
+		}
+		if m.streaming {
+			cmds = append(cmds, m.startStream())
+		}
+
+	case streamOpenedMsg:
+		if msg.cancel == m.cancelStream {
+			cmds = append(cmds, m.openStream(msg))
+		}
+
 	case streamChunkMsg:
-		m.historyManager.UpdateAssistantMessage(msg.chunk)
+		m.historyManager.UpdateAssistantMessage(m.historyID, msg.chunk)
 		m.updateViewport(true)
-		cmds = append(cmds, readStreamCmd(m.stream, m.cancelStream))
+		cmds = append(cmds, readStreamCmd(m.historyID, m.stream, m.cancelStream))
 
 	case animationTickMsg:
 		if m.streaming {
 			m.animationStep++
 			m.updateViewport(true)
-			cmds = append(cmds, animationTick())
+			cmds = append(cmds, animationTick(m.historyID))
 		}
 
 	case streamDoneMsg:
 		cmd := m.finishStream()
-		cmds = append(cmds, cmd)
+		cmds = append(cmds, cmd, m.generateTitle())
 		cmds = append(cmds, func() tea.Msg {
 			return messages.ChatCompletionMsg{}
 		})
@@ -295,6 +570,8 @@ func (m *ChatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	cmds = append(cmds, cmd)
 
 	m.resizeTextarea()
+	m.refreshCompletion()
+	m.syncDraft()
 	return m, tea.Batch(cmds...)
 }
 
@@ -308,7 +585,22 @@ func (m *ChatModel) View() string {
 		return systemView
 	}
 
+	if templatesView := m.templates.View(); templatesView != "" {
+		return templatesView
+	}
+
+	if m.saveDialog != nil {
+		return m.saveDialog.View()
+	}
+
 	viewportView := m.viewport.View()
+	if m.completion.visible() {
+		lines := m.completion.render(m.maxMsgWidth)
+		for i, line := range lines {
+			lines[i] = lipgloss.PlaceHorizontal(m.width, lipgloss.Center, line)
+		}
+		viewportView = overlayBottom(viewportView, lines)
+	}
 	inputView := m.renderInputRow()
 	divider := dividerStyle.Render(strings.Repeat("─", m.width))
 
@@ -348,6 +640,18 @@ func (m *ChatModel) renderInputRow() string {
 
 	var content string
 	switch {
+	case m.search != nil:
+		content = lipgloss.PlaceHorizontal(
+			contentWidth,
+			lipgloss.Left,
+			m.renderSearch(contentWidth),
+		)
+	case m.find != nil:
+		content = lipgloss.PlaceHorizontal(
+			contentWidth,
+			lipgloss.Left,
+			m.renderFind(),
+		)
 	case m.streaming:
 		content = lipgloss.PlaceHorizontal(
 			contentWidth,
@@ -411,9 +715,17 @@ func (m *ChatModel) SetSize(w, h int) {
 
 	// System popup still overlays everything
 	m.system.SetSize(w, h)
+	m.templates.SetSize(w, h)
+	if m.saveDialog != nil {
+		m.saveDialog.SetSize(w, h)
+	}
 
 	m.ready = true
 	m.updateViewport(true)
+	if m.scrollTo >= 0 {
+		m.viewport.SetYOffset(m.scrollTo)
+		m.scrollTo = -1
+	}
 }
 
 func (m *ChatModel) handleUserInput() (tea.Model, tea.Cmd) {
@@ -422,28 +734,191 @@ func (m *ChatModel) handleUserInput() (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
-	m.historyManager.AddUserMessage(input)
+	attachments, err := attach.Resolve(input, m.workDir)
+	if err != nil {
+		return m, ShowToast(err.Error(), 3*time.Second)
+	}
+
+	m.historyManager.AddUserMessage(m.historyID, input, attachments...)
+	m.setInput("")
+
+	return m, tea.Batch(m.addInput(input), m.beginStream())
+}
 
+// addInput records a sent prompt in the input history.
+func (m *ChatModel) addInput(input string) tea.Cmd {
+	if m.inputs == nil {
+		return nil
+	}
+	if err := m.inputs.Add(input); err != nil {
+		return ShowToast("Failed to save input history: "+err.Error(), 3*time.Second)
+	}
+	return nil
+}
+
+// Retry discards the last answer and asks the model again.
+func (m *ChatModel) Retry() tea.Cmd {
+	if m.streaming || !m.historyManager.ResetLastAssistantMessage(m.historyID) {
+		return ShowToast("Nothing to retry", 2*time.Second)
+	}
+	return m.beginStream()
+}
+
+func (m *ChatModel) beginStream() tea.Cmd {
 	m.streaming = true
 	m.animationStep = 0
 	m.lockScroll = false
 
 	m.blurInput()
-	m.textarea.Reset()
+	m.completion.clear()
 	m.updateViewport(true)
 
-	return m, tea.Batch(
+	return tea.Batch(
 		m.spinner.Tick,
-		func() tea.Msg { return startStreamMsg{} },
-		animationTick(),
+		m.retrieveSources(),
+		animationTick(m.historyID),
 	)
 }
 
+// retrieveSources looks up context for the last question in the chat's
+// document collection, then lets the response start. Without a
+// collection the response starts right away.
+func (m *ChatModel) retrieveSources() tea.Cmd {
+	id := m.historyID
+	start := func() tea.Msg { return startStreamMsg{historyID: id} }
+
+	h := m.chatHistory()
+	if h == nil || h.Collection == "" || len(h.Messages) < 2 {
+		return start
+	}
+	question := h.Messages[len(h.Messages)-2]
+	if question.Role != "user" || len(question.Sources) > 0 {
+		return start
+	}
+
+	name := h.Collection
+	cached := m.collection
+	cfg := m.cfg
+	topK := rag.DefaultTopK
+	if cfg != nil && cfg.RAG.TopK > 0 {
+		topK = cfg.RAG.TopK
+	}
+
+	return func() tea.Msg {
+		c := cached
+		if c == nil || c.Name != name {
+			loaded, err := rag.Load(name)
+			if err != nil {
+				return sourcesRetrievedMsg{historyID: id, err: err}
+			}
+			c = &loaded
+		}
+
+		vectors, err := aihub.Embed(c.Model, []string{question.Content}, cfg)
+		if err != nil {
+			return sourcesRetrievedMsg{historyID: id, collection: c, err: err}
+		}
+
+		var sources []chat.Source
+		for _, r := range c.Search(vectors[0], topK) {
+			sources = append(sources, chat.Source{
+				Collection: c.Name,
+				Path:       r.Path,
+				Line:       r.Line,
+				Text:       r.Text,
+				Score:      r.Score,
+			})
+		}
+		return sourcesRetrievedMsg{historyID: id, collection: c, sources: sources}
+	}
+}
+
+// updateCompletion handles the keys that drive the completion popup. It
+// reports whether the key was consumed.
+func (m *ChatModel) updateCompletion(msg tea.KeyMsg) (bool, tea.Cmd) {
+	switch msg.String() {
+	case "up":
+		m.completion.move(-1)
+	case "down":
+		m.completion.move(1)
+	case "esc":
+		m.completion.dismissed = m.textarea.Value()
+		m.completion.clear()
+	case "tab":
+		m.acceptCompletion()
+	case "enter":
+		// Enter completes a partial entry and submits a complete one.
+		if m.completion.selected().Value == m.completion.token {
+			return false, nil
+		}
+		m.acceptCompletion()
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+func (m *ChatModel) acceptCompletion() {
+	m.textarea.SetValue(replaceLastToken(m.textarea.Value(), m.completion.selected().Value))
+	m.textarea.CursorEnd()
+	m.refreshCompletion()
+}
+
+// refreshCompletion updates the suggestions for the current input:
+// file paths after "@", and slash commands typed on one line.
+func (m *ChatModel) refreshCompletion() {
+	value := m.textarea.Value()
+	if value != m.completion.dismissed {
+		m.completion.dismissed = ""
+	}
+
+	token := lastToken(value)
+	switch {
+	case m.streaming || m.completion.dismissed != "":
+		m.completion.clear()
+
+	case strings.HasPrefix(token, attach.MentionPrefix):
+		m.completion.set(token, m.pathCompletions(strings.TrimPrefix(token, attach.MentionPrefix)))
+
+	case m.completer != nil && isSlashCommand(value) && !strings.Contains(value, "\n"):
+		m.completion.set(token, m.completer(value))
+
+	default:
+		m.completion.clear()
+	}
+}
+
+func (m *ChatModel) pathCompletions(query string) []completionItem {
+	var items []completionItem
+	for _, match := range m.paths.Complete(query, maxPathCompletions) {
+		value := match.Path
+		if match.IsDir {
+			value += "/"
+		}
+		items = append(items, completionItem{
+			Value: attach.MentionPrefix + value,
+			Label: value,
+		})
+	}
+	return items
+}
+
+// isSlashCommand reports whether input should run as a command. A leading
+// "//" sends the message as text.
+func isSlashCommand(input string) bool {
+	return strings.HasPrefix(input, commandPrefix) &&
+		!strings.HasPrefix(input, commandPrefix+commandPrefix)
+}
+
 func (m *ChatModel) updateViewport(forceBottom bool) {
 	if !m.ready {
 		return
 	}
-	m.viewport.SetContent(m.renderMessages())
+	content := m.renderMessages()
+	if m.find != nil {
+		content = m.find.highlight(content)
+	}
+	m.viewport.SetContent(content)
 	if forceBottom && !m.lockScroll {
 		m.viewport.GotoBottom()
 	}
@@ -457,26 +932,61 @@ func (m *ChatModel) stopStreaming() tea.Cmd {
 	m.streaming = false
 	m.updateViewport(true)
 	// Save the partial response
-	m.historyManager.SaveCurrent()
-	return m.FocusInput()
+	return tea.Batch(m.saveHistory(), m.FocusInput())
 }
 
 func (m *ChatModel) startStream() tea.Cmd {
-	currentHistory := m.historyManager.GetCurrentHistory()
+	currentHistory := m.chatHistory()
 	if currentHistory == nil {
 		return nil
 	}
 	// Exclude the last (empty) assistant message for the API call
-	msgs := currentHistory.Messages[:len(currentHistory.Messages)-1]
+	var msgs []chat.Message
+	if currentHistory.SystemPrompt != "" {
+		msgs = append(msgs, chat.Message{
+			Role:    "system",
+			Content: currentHistory.SystemPrompt,
+		})
+	}
+	for _, msg := range currentHistory.Messages[:len(currentHistory.Messages)-1] {
+		msgs = append(msgs, msg.Expanded())
+	}
 
-	stream, err := aihub.StreamChat(m.modelName, msgs, m.cfg)
-	if err != nil {
-		return m.finishStream()
+	// The request runs in the background, since retries wait between
+	// attempts; stopping the response cancels it.
+	id, options, cfg := m.historyID, currentHistory.Options, m.cfg
+	targets := aihub.Chain(m.cfg, m.modelName, m.host)
+	cancel := make(chan struct{})
+	m.cancelStream = cancel
+
+	return func() tea.Msg {
+		stream, target, err := aihub.StreamChatRetry(targets, msgs, options, cfg, cancel)
+		return streamOpenedMsg{historyID: id, stream: stream, target: target, err: err, cancel: cancel}
+	}
+}
+
+// openStream starts reading the response once the request succeeded,
+// noting which model answers.
+func (m *ChatModel) openStream(msg streamOpenedMsg) tea.Cmd {
+	if msg.err != nil {
+		return tea.Batch(
+			m.finishStream(),
+			ShowToast("Request failed: "+msg.err.Error(), 3*time.Second),
+			func() tea.Msg { return reconnectMsg{} },
+		)
 	}
 
-	m.stream = stream
-	m.cancelStream = make(chan struct{})
-	return readStreamCmd(m.stream, m.cancelStream)
+	m.stream = msg.stream
+	m.historyManager.SetLastAssistantModel(m.historyID, msg.target.Model, msg.target.Host)
+
+	cmds := []tea.Cmd{readStreamCmd(m.historyID, m.stream, m.cancelStream)}
+	if msg.target.Model != m.modelName || msg.target.Host != m.host {
+		cmds = append(cmds, ShowToast(
+			fmt.Sprintf("%s failed, %s is answering", m.modelName, m.targetLabel(msg.target)),
+			3*time.Second,
+		))
+	}
+	return tea.Batch(cmds...)
 }
 
 func (m *ChatModel) finishStream() tea.Cmd {
@@ -484,24 +994,66 @@ func (m *ChatModel) finishStream() tea.Cmd {
 	m.cancelStream = nil
 	m.updateViewport(true)
 	// Save the final response
-	m.historyManager.SaveCurrent()
-	return m.FocusInput()
+	return tea.Batch(m.saveHistory(), m.FocusInput())
+}
+
+// generateTitle asks a model for a concise title once the first exchange
+// of a chat is complete. It runs in the background and reports back with
+// a titleGeneratedMsg.
+func (m *ChatModel) generateTitle() tea.Cmd {
+	if m.cfg == nil || !m.cfg.Titles.Generate {
+		return nil
+	}
+
+	h := m.chatHistory()
+	if h == nil || len(h.Messages) != 2 || h.Messages[1].Content == "" {
+		return nil
+	}
+
+	id := h.ID
+	msgs := append([]chat.Message{}, h.Messages...)
+	// A configured title model is "model" on the default host or
+	// "model@host".
+	target := aihub.Target{Model: m.modelName, Host: m.host}
+	if m.cfg.Titles.Model != "" {
+		target = aihub.ParseTarget(m.cfg.Titles.Model)
+	}
+	model, cfg := target.Model, m.cfg.ForHost(target.Host)
+
+	return func() tea.Msg {
+		title, err := aihub.GenerateTitle(model, msgs, cfg)
+		return titleGeneratedMsg{historyID: id, title: title, err: err}
+	}
+}
+
+// saveHistory persists the chat, asking the root model to resolve the
+// conflict if another instance changed it in the meantime.
+func (m *ChatModel) saveHistory() tea.Cmd {
+	if err := m.historyManager.SaveHistory(m.historyID); errors.Is(err, history.ErrConflict) {
+		id := m.historyID
+		return func() tea.Msg { return historyConflictMsg{historyID: id} }
+	}
+	return nil
+}
+
+// chatHistory returns the open history shown by the chat.
+func (m *ChatModel) chatHistory() *history.History {
+	return m.historyManager.Tab(m.historyID)
 }
 
 // Rendering
 var animationFrames = []string{`.`, `..`, `...`, `..`, `.`}
 
 func (m *ChatModel) renderMessages() string {
-	// Render gradient banner
-	banner := RenderBanner(m.width, 8)
-
-	// Centered plain text
-	startText := lipgloss.NewStyle().
-		Align(lipgloss.Center).
-		Render("Start chatting with " + m.modelName)
-
-	currentHistory := m.historyManager.GetCurrentHistory()
+	currentHistory := m.chatHistory()
 	if currentHistory == nil || len(currentHistory.Messages) == 0 {
+		// Render gradient banner
+		banner := RenderBanner(m.width, 8)
+
+		// Centered plain text
+		startText := lipgloss.NewStyle().
+			Align(lipgloss.Center).
+			Render("Start chatting with " + m.modelName)
 
 		// Combine banner + text
 		content := lipgloss.JoinVertical(
@@ -523,74 +1075,218 @@ func (m *ChatModel) renderMessages() string {
 
 	// ---- Normal message rendering below ----
 
-	var out []string
 	messages := currentHistory.Messages
 
-	for i, msg := range messages {
-		style := m.bubble.Width(m.maxMsgWidth)
+	// Rendered bubbles only depend on the layout and the messages, so
+	// they are kept until one of them changes.
+	style := m.markdownStyle()
+	if m.renderedWidth != m.maxMsgWidth || m.renderedStyle != style {
+		m.rendered = nil
+		m.renderedWidth, m.renderedStyle = m.maxMsgWidth, style
+	}
+	if len(m.rendered) > len(messages) {
+		m.rendered = m.rendered[:len(messages)]
+	}
+	for len(m.rendered) < len(messages) {
+		m.rendered = append(m.rendered, renderedMessage{})
+	}
+
+	out := make([]string, len(messages))
+	m.offsets = m.offsets[:0]
+	line := 0
+	for i := range messages {
+		out[i] = m.renderMessage(i, messages)
+		m.offsets = append(m.offsets, line)
+		line += lipgloss.Height(out[i])
+	}
+	m.offsets = append(m.offsets, line)
 
-		if msg.Role == "user" {
-			out = append(out,
-				m.userStyle.Render("You")+"\n"+
-					style.Render(msg.Content),
-			)
-			continue
-		}
+	return strings.Join(out, "\n")
+}
 
-		content := msg.Content
-		// Render Markdown
-		renderedContent, err := glamour.Render(content, m.cfg.Theme.Markdown)
-		if err != nil {
-			renderedContent = content
-		}
+// renderMessage returns the bubble of messages[i], rendering it only if
+// it changed since the last call. While a response streams, only its
+// tail is rendered again for each chunk.
+func (m *ChatModel) renderMessage(i int, messages []chat.Message) string {
+	msg := messages[i]
+	style := m.bubble.Width(m.maxMsgWidth)
+	streaming := m.streaming && i == len(messages)-1
+
+	block := m.selectedBlock(i)
+	if block >= 0 {
+		style = bubbleSelected.Width(m.maxMsgWidth)
+	}
+
+	var suffix string
+	switch {
+	case msg.Role == "user" && len(msg.Attachments) > 0:
+		suffix = "\n" + m.thinkingStyle.Render(attachmentSummary(msg.Attachments))
+	case msg.Role != "user" && !streaming && i > 0 && len(messages[i-1].Sources) > 0:
+		suffix = m.thinkingStyle.Render(citations(messages[i-1].Sources))
+	}
 
-		if m.streaming && i == len(messages)-1 {
-			renderedContent += " " + m.animationStyle.Render(
+	c := &m.rendered[i]
+	key := renderKey{
+		role: msg.Role, content: msg.Content, suffix: suffix,
+		block: block, model: msg.Model, host: msg.Host,
+		chatModel: m.modelName, chatHost: m.host,
+	}
+	if c.key != key {
+		*c = renderedMessage{key: key, body: m.renderBody(msg, block)}
+	}
+
+	if streaming {
+		// The animation changes on every tick, so the bubble is not kept.
+		return m.answerLabel(msg) + "\n" +
+			style.Render(c.body+" "+m.animationStyle.Render(
 				animationFrames[m.animationStep%len(animationFrames)],
-			)
+			))
+	}
+
+	if c.bubble == "" {
+		label := m.answerLabel(msg)
+		if msg.Role == "user" {
+			label = m.userStyle.Render("You")
 		}
+		c.bubble = label + "\n" + style.Render(c.body+suffix)
+	}
+	return c.bubble
+}
 
-		out = append(out,
-			m.botStyle.Render(m.modelName)+"\n"+
-				style.Render(renderedContent),
-		)
+// answerLabel names the model that wrote an answer above its bubble,
+// noting when it was a fallback for the chat's model.
+func (m *ChatModel) answerLabel(msg chat.Message) string {
+	if msg.Model == "" {
+		return m.botStyle.Render(m.modelName)
 	}
+	target := aihub.Target{Model: msg.Model, Host: msg.Host}
+	label := m.botStyle.Render(m.targetLabel(target))
+	if msg.Model != m.modelName || (msg.Host != "" && msg.Host != m.host) {
+		label += m.thinkingStyle.Render(" · fallback for " + m.modelName)
+	}
+	return label
+}
 
-	return strings.Join(out, "\n")
+// targetLabel names a model, with its host when several hosts are
+// configured.
+func (m *ChatModel) targetLabel(t aihub.Target) string {
+	if m.cfg == nil || len(m.cfg.Endpoints()) < 2 {
+		return t.Model
+	}
+	return t.String()
+}
+
+// renderBody renders the text of a message: markdown with numbered code
+// blocks for answers, plain text for the user's messages. block is the
+// highlighted code block, or -1.
+func (m *ChatModel) renderBody(msg chat.Message, block int) string {
+	if msg.Role == "user" {
+		return msg.Content
+	}
+
+	// Leave room for the bubble's border and padding.
+	content := numberCodeBlocks(msg.Content, block)
+	rendered, err := m.markdown.Render(content, m.markdownStyle(), m.maxMsgWidth-4)
+	if err != nil {
+		return msg.Content
+	}
+	return rendered
+}
+
+func (m *ChatModel) markdownStyle() string {
+	if m.cfg == nil {
+		return ""
+	}
+	return m.cfg.Theme.Markdown
+}
+
+// citations lists the sources an answer was given, one per line.
+func citations(sources []chat.Source) string {
+	var b strings.Builder
+	b.WriteString("Sources:")
+	for i, src := range sources {
+		fmt.Fprintf(&b, "\n[%d] %s:%d", i+1, src.Path, src.Line)
+	}
+	return b.String()
+}
+
+// attachmentSummary lists attached files, e.g. "📎 main.go, go.mod".
+func attachmentSummary(atts []chat.Attachment) string {
+	names := make([]string, len(atts))
+	for i, a := range atts {
+		names[i] = a.Path
+	}
+	summary := "📎 " + strings.Join(names, ", ")
+	if len(atts) > 5 {
+		summary = fmt.Sprintf("📎 %s, … (%d files)", strings.Join(names[:5], ", "), len(atts))
+	}
+	return summary
 }
 
 // Stream Cmd
 
-func animationTick() tea.Cmd {
+func animationTick(historyID string) tea.Cmd {
 	return tea.Tick(150*time.Millisecond, func(t time.Time) tea.Msg {
-		return animationTickMsg{}
+		return animationTickMsg{historyID: historyID}
 	})
 }
 
-func readStreamCmd(stream <-chan string, cancel <-chan struct{}) tea.Cmd {
+func readStreamCmd(historyID string, stream <-chan string, cancel <-chan struct{}) tea.Cmd {
 	return func() tea.Msg {
-		var batch []string
-		ticker := time.NewTicker(1 * time.Millisecond)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-cancel:
-				return streamDoneMsg{}
-			case chunk, ok := <-stream:
-				if !ok {
-					if len(batch) > 0 {
-						return streamChunkMsg{chunk: strings.Join(batch, "")}
-					}
-					return streamDoneMsg{}
-				}
-				batch = append(batch, chunk)
-			case <-ticker.C:
+		chunk, _, ok := nextChunk(stream, cancel)
+		if !ok {
+			return streamDoneMsg{historyID: historyID}
+		}
+		return streamChunkMsg{historyID: historyID, chunk: chunk}
+	}
+}
+
+// streamOwner returns the history ID of a stream message, so the root
+// model can hand it to the chat of its tab.
+func streamOwner(msg tea.Msg) (string, bool) {
+	switch msg := msg.(type) {
+	case startStreamMsg:
+		return msg.historyID, true
+	case sourcesRetrievedMsg:
+		return msg.historyID, true
+	case streamOpenedMsg:
+		return msg.historyID, true
+	case streamChunkMsg:
+		return msg.historyID, true
+	case streamDoneMsg:
+		return msg.historyID, true
+	case animationTickMsg:
+		return msg.historyID, true
+	case templateRenderedMsg:
+		return msg.historyID, true
+	}
+	return "", false
+}
+
+// nextChunk waits for stream output and returns everything that arrived
+// within a millisecond, so fast models don't flood the update loop, along
+// with the number of stream pieces joined. It reports false once the
+// stream is finished or cancelled.
+func nextChunk(stream <-chan string, cancel <-chan struct{}) (string, int, bool) {
+	var batch []string
+	ticker := time.NewTicker(1 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cancel:
+			return "", 0, false
+		case chunk, ok := <-stream:
+			if !ok {
 				if len(batch) > 0 {
-					msg := streamChunkMsg{chunk: strings.Join(batch, "")}
-					batch = nil
-					return msg
+					return strings.Join(batch, ""), len(batch), true
 				}
+				return "", 0, false
+			}
+			batch = append(batch, chunk)
+		case <-ticker.C:
+			if len(batch) > 0 {
+				return strings.Join(batch, ""), len(batch), true
 			}
 		}
 	}
@@ -601,8 +1297,10 @@ func (m *ChatModel) Back() bool { return m.back }
 
 func (m *ChatModel) ModelName() string { return m.modelName }
 
+func (m *ChatModel) HistoryID() string { return m.historyID }
+
 func (m *ChatModel) Messages() []chat.Message {
-	if h := m.historyManager.GetCurrentHistory(); h != nil {
+	if h := m.chatHistory(); h != nil {
 		return h.Messages
 	}
 	return nil
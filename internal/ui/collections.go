@@ -0,0 +1,368 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aj-seven/llmverse/internal/config"
+	"github.com/aj-seven/llmverse/internal/history"
+	aihub "github.com/aj-seven/llmverse/internal/providers/ollama"
+	"github.com/aj-seven/llmverse/internal/rag"
+	"github.com/aj-seven/llmverse/pkg/keymap"
+	messages "github.com/aj-seven/llmverse/pkg/messages"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Messages
+
+// collectionIndexMsg reports indexing progress, or the end of indexing
+// when done is set.
+type collectionIndexMsg struct {
+	name     string
+	current  int
+	total    int
+	done     bool
+	err      error
+	progress <-chan collectionIndexMsg
+}
+
+// collectionsChangedMsg tells the chat to drop its cached collection.
+type collectionsChangedMsg struct{}
+
+// Collections Model
+
+// CollectionsModel lists the document collections used for retrieval and
+// lets the user create, re-index, delete and pick them.
+type CollectionsModel struct {
+	historyManager *history.Manager
+	cfg            *config.Config
+
+	collections []rag.Info
+	cursor      int
+	loadErr     error
+
+	// indexing is the name of the collection being indexed, if any.
+	indexing      string
+	indexProgress string
+
+	form    *FormDialog
+	confirm *ConfirmDialog
+
+	width  int
+	height int
+}
+
+// Constructor
+func NewCollectionsModel(hm *history.Manager, cfg *config.Config) *CollectionsModel {
+	m := &CollectionsModel{
+		historyManager: hm,
+		cfg:            cfg,
+	}
+	m.reload()
+	return m
+}
+
+func (m *CollectionsModel) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+	if m.form != nil {
+		m.form.SetSize(w, h)
+	}
+	if m.confirm != nil {
+		m.confirm.SetSize(w, h)
+	}
+}
+
+func (m *CollectionsModel) Init() tea.Cmd { return nil }
+
+// Update
+
+func (m *CollectionsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(collectionIndexMsg); ok {
+		return m, m.handleIndexMsg(msg)
+	}
+
+	if m.form != nil {
+		cmd := m.form.Update(msg)
+		if !m.form.Done() {
+			return m, cmd
+		}
+		values := m.form.Values
+		m.form = nil
+		if values == nil {
+			return m, nil
+		}
+		return m, m.startIndex(strings.TrimSpace(values["name"]), strings.TrimSpace(values["root"]))
+	}
+
+	if m.confirm != nil {
+		m.confirm.Update(msg)
+		if m.confirm.Choice != nil {
+			var cmd tea.Cmd
+			if *m.confirm.Choice && len(m.collections) > 0 {
+				cmd = m.removeSelected()
+			}
+			m.confirm = nil
+			return m, cmd
+		}
+		return m, nil
+	}
+
+	k, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	switch k.String() {
+	case "esc":
+		return m, func() tea.Msg {
+			return messages.GoBackMsg{}
+		}
+
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+
+	case "down", "j":
+		if m.cursor < len(m.collections)-1 {
+			m.cursor++
+		}
+
+	case "enter":
+		if len(m.collections) > 0 {
+			return m, m.useSelected()
+		}
+
+	case "n":
+		if m.indexing == "" {
+			m.form = NewFormDialog("New Collection",
+				FormField{Name: "name", Label: "Name (letters, digits, . _ -)"},
+				FormField{Name: "root", Label: "Folder to index", Value: "."},
+			)
+			m.form.SetSize(m.width, m.height)
+		}
+
+	case "r":
+		if len(m.collections) > 0 && m.indexing == "" {
+			c := m.collections[m.cursor]
+			return m, m.startIndex(c.Name, c.Root)
+		}
+
+	case "ctrl+d":
+		if len(m.collections) > 0 {
+			m.confirm = NewConfirmDialog(
+				"Delete collection?",
+				fmt.Sprintf("The index of %s will be removed.\nYour documents are not touched.", m.collections[m.cursor].Name),
+			)
+			m.confirm.SetSize(m.width, m.height)
+		}
+	}
+
+	return m, nil
+}
+
+// View
+
+func (m *CollectionsModel) View() string {
+	if m.form != nil {
+		return m.form.View()
+	}
+	if m.confirm != nil {
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, m.confirm.View())
+	}
+
+	var b strings.Builder
+
+	current := ""
+	if h := m.historyManager.GetCurrentHistory(); h != nil {
+		current = h.Collection
+	}
+
+	switch {
+	case m.loadErr != nil:
+		b.WriteString(dimStyle.Render(" Failed to load collections: " + m.loadErr.Error()))
+	case len(m.collections) == 0:
+		b.WriteString(dimStyle.Render(" No collections yet. Press n to index a folder."))
+	default:
+		b.WriteString(m.renderHeader())
+		b.WriteString("\n")
+		for i, c := range m.collections {
+			row := m.renderRow(c, c.Name == current)
+			if i == m.cursor {
+				b.WriteString(selectedRowStyle.Render(row))
+			} else {
+				b.WriteString(rowStyle.Render(row))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	if m.indexing != "" {
+		b.WriteString("\n")
+		b.WriteString(dimStyle.Render(fmt.Sprintf(" Indexing %s… %s", m.indexing, m.indexProgress)))
+	}
+
+	return b.String()
+}
+
+// Helpers
+
+func (m *CollectionsModel) columns() (nameW, filesW, modelW, dateW, rootW int) {
+	nameW, filesW, modelW, dateW = 16, 14, 18, 16
+	rootW = max(10, m.width-nameW-filesW-modelW-dateW-3*5)
+	return
+}
+
+func (m *CollectionsModel) renderHeader() string {
+	nameW, filesW, modelW, dateW, rootW := m.columns()
+	return lipgloss.NewStyle().
+		Bold(true).
+		Render(fmt.Sprintf(
+			" %-*s │ %-*s │ %-*s │ %-*s │ %-*s",
+			nameW, "NAME",
+			filesW, "FILES/CHUNKS",
+			modelW, "MODEL",
+			dateW, "UPDATED",
+			rootW, "FOLDER",
+		))
+}
+
+func (m *CollectionsModel) renderRow(c rag.Info, inUse bool) string {
+	nameW, filesW, modelW, dateW, rootW := m.columns()
+
+	name := c.Name
+	if inUse {
+		name = "● " + name
+	}
+
+	return fmt.Sprintf(
+		"%-*s │ %-*s │ %-*s │ %-*s │ %-*s",
+		nameW, truncate(name, nameW),
+		filesW, fmt.Sprintf("%d/%d", c.Files, c.Chunks),
+		modelW, truncate(c.Model, modelW),
+		dateW, c.UpdatedAt.Format("2006-01-02 15:04"),
+		rootW, truncate(c.Root, rootW),
+	)
+}
+
+func (m *CollectionsModel) reload() {
+	m.collections, m.loadErr = rag.List()
+	if m.cursor >= len(m.collections) {
+		m.cursor = max(0, len(m.collections)-1)
+	}
+}
+
+// useSelected answers questions in the current chat from the selected
+// collection, or stops doing so if it is already in use.
+func (m *CollectionsModel) useSelected() tea.Cmd {
+	name := m.collections[m.cursor].Name
+	toast := fmt.Sprintf("Answering from %s", name)
+
+	if h := m.historyManager.GetCurrentHistory(); h != nil && h.Collection == name {
+		name = ""
+		toast = "Document retrieval off"
+	}
+	m.historyManager.SetCollection(name)
+
+	return tea.Batch(
+		ShowToast(toast, 2*time.Second),
+		func() tea.Msg { return messages.GoBackMsg{} },
+	)
+}
+
+func (m *CollectionsModel) removeSelected() tea.Cmd {
+	name := m.collections[m.cursor].Name
+	if err := rag.Remove(name); err != nil {
+		return ShowToast("Delete failed: "+err.Error(), 3*time.Second)
+	}
+	if h := m.historyManager.GetCurrentHistory(); h != nil && h.Collection == name {
+		m.historyManager.SetCollection("")
+	}
+	m.reload()
+	return tea.Batch(
+		ShowToast(fmt.Sprintf("Collection %s deleted", name), 2*time.Second),
+		func() tea.Msg { return collectionsChangedMsg{} },
+	)
+}
+
+// startIndex indexes root into the named collection in the background.
+func (m *CollectionsModel) startIndex(name, root string) tea.Cmd {
+	if name == "" || root == "" {
+		return ShowToast("Name and folder are required", 2*time.Second)
+	}
+
+	m.indexing = name
+	m.indexProgress = "scanning"
+
+	cfg := m.cfg
+	opts := rag.Options{}
+	if cfg != nil {
+		opts = rag.Options{Model: cfg.RAG.EmbeddingModel, ChunkSize: cfg.RAG.ChunkSize}
+	}
+	progress := make(chan collectionIndexMsg, 1)
+
+	go func() {
+		_, err := rag.Index(name, root, opts,
+			func(model string, texts []string) ([][]float32, error) {
+				return aihub.Embed(model, texts, cfg)
+			},
+			func(current, total int) {
+				select {
+				case progress <- collectionIndexMsg{name: name, current: current, total: total}:
+				default: // the UI is behind; skip this update
+				}
+			},
+		)
+		progress <- collectionIndexMsg{name: name, done: true, err: err}
+		close(progress)
+	}()
+
+	return waitIndex(progress)
+}
+
+func (m *CollectionsModel) handleIndexMsg(msg collectionIndexMsg) tea.Cmd {
+	if !msg.done {
+		m.indexProgress = fmt.Sprintf("%d/%d chunks", msg.current, msg.total)
+		return waitIndex(msg.progress)
+	}
+
+	m.indexing = ""
+	m.indexProgress = ""
+	m.reload()
+
+	if msg.err != nil {
+		return ShowToast("Indexing failed: "+msg.err.Error(), 4*time.Second)
+	}
+	return tea.Batch(
+		ShowToast(fmt.Sprintf("Collection %s indexed", msg.name), 2*time.Second),
+		func() tea.Msg { return collectionsChangedMsg{} },
+	)
+}
+
+func waitIndex(progress <-chan collectionIndexMsg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-progress
+		if !ok {
+			return nil
+		}
+		msg.progress = progress
+		return msg
+	}
+}
+
+// Public API
+
+// Shortcuts returns the footer shortcuts of the view.
+func (m *CollectionsModel) Shortcuts() []keymap.Shortcut {
+	return []keymap.Shortcut{
+		{Key: "enter", Action: "Use in Chat"},
+		{Key: "n", Action: "New"},
+		{Key: "r", Action: "Re-index"},
+		{Key: "ctrl+d", Action: "Delete"},
+		{Key: "esc", Action: "Back"},
+	}
+}
@@ -0,0 +1,515 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/aj-seven/llmverse/internal/history"
+	aihub "github.com/aj-seven/llmverse/internal/providers/ollama"
+	"github.com/aj-seven/llmverse/internal/rag"
+	"github.com/aj-seven/llmverse/internal/templates"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const commandPrefix = "/"
+
+// Messages
+
+// slashCommandMsg is sent by the chat input when the user submits a line
+// starting with "/".
+type slashCommandMsg struct {
+	input string
+}
+
+// SlashCommand is a command typed into the chat input, e.g. "/temp 0.2".
+type SlashCommand struct {
+	Name    string
+	Aliases []string
+	Args    string // usage hint, e.g. "<value>"
+	Help    string
+
+	// Complete optionally suggests values for the first argument.
+	Complete func(m *Model, prefix string) []completionItem
+
+	Run func(m *Model, args []string) tea.Cmd
+}
+
+// commandRegistry holds the available slash commands.
+type commandRegistry struct {
+	commands []SlashCommand
+}
+
+func newCommandRegistry() *commandRegistry {
+	r := &commandRegistry{}
+	registerDefaultCommands(r)
+	return r
+}
+
+// Register adds a command, replacing any command with the same name.
+func (r *commandRegistry) Register(c SlashCommand) {
+	for i := range r.commands {
+		if r.commands[i].Name == c.Name {
+			r.commands[i] = c
+			return
+		}
+	}
+	r.commands = append(r.commands, c)
+	sort.Slice(r.commands, func(i, j int) bool {
+		return r.commands[i].Name < r.commands[j].Name
+	})
+}
+
+// Lookup finds a command by name or alias.
+func (r *commandRegistry) Lookup(name string) (SlashCommand, bool) {
+	for _, c := range r.commands {
+		if c.Name == name {
+			return c, true
+		}
+		for _, a := range c.Aliases {
+			if a == name {
+				return c, true
+			}
+		}
+	}
+	return SlashCommand{}, false
+}
+
+// Complete suggests commands, or arguments of a command, for input.
+func (r *commandRegistry) Complete(m *Model, input string) []completionItem {
+	name, rest, hasArgs := strings.Cut(strings.TrimPrefix(input, commandPrefix), " ")
+
+	if hasArgs {
+		c, ok := r.Lookup(name)
+		if !ok || c.Complete == nil || strings.Contains(rest, " ") {
+			return nil
+		}
+		return c.Complete(m, rest)
+	}
+
+	var items []completionItem
+	for _, c := range r.commands {
+		if !strings.HasPrefix(c.Name, name) {
+			continue
+		}
+		label := commandPrefix + c.Name
+		if c.Args != "" {
+			label += " " + c.Args
+		}
+		items = append(items, completionItem{
+			Value:  commandPrefix + c.Name,
+			Label:  label,
+			Detail: c.Help,
+		})
+	}
+	return items
+}
+
+// Help lists all commands.
+func (r *commandRegistry) Help() string {
+	var b strings.Builder
+	for _, c := range r.commands {
+		usage := commandPrefix + c.Name
+		if c.Args != "" {
+			usage += " " + c.Args
+		}
+		fmt.Fprintf(&b, "%-22s %s\n", usage, c.Help)
+	}
+	b.WriteString("\nStart a message with // to send a literal /.")
+	return b.String()
+}
+
+// Run parses and executes a command line.
+func (r *commandRegistry) Run(m *Model, input string) tea.Cmd {
+	args := parseArgs(strings.TrimPrefix(input, commandPrefix))
+	if len(args) == 0 {
+		return nil
+	}
+
+	c, ok := r.Lookup(args[0])
+	if !ok {
+		return ShowToast(fmt.Sprintf("Unknown command /%s (try /help)", args[0]), 3*time.Second)
+	}
+	return c.Run(m, args[1:])
+}
+
+// parseArgs splits a command line on whitespace, keeping double-quoted
+// sections together. A backslash escapes a double quote or a backslash;
+// before anything else it is kept, so Windows paths need no escaping.
+func parseArgs(s string) []string {
+	var args []string
+	var cur strings.Builder
+	inQuotes, hasArg, escaped := false, false, false
+
+	for _, r := range s {
+		if escaped {
+			escaped = false
+			if r == '"' || r == '\\' {
+				cur.WriteRune(r)
+				continue
+			}
+			cur.WriteRune('\\')
+		}
+
+		switch {
+		case r == '\\':
+			escaped, hasArg = true, true
+		case r == '"':
+			inQuotes = !inQuotes
+			hasArg = true
+		case unicode.IsSpace(r) && !inQuotes:
+			if hasArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				hasArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			hasArg = true
+		}
+	}
+	if escaped {
+		cur.WriteRune('\\')
+	}
+	if hasArg {
+		args = append(args, cur.String())
+	}
+	return args
+}
+
+// Default commands
+
+func registerDefaultCommands(r *commandRegistry) {
+	r.Register(SlashCommand{
+		Name: "help",
+		Help: "List commands",
+		Run: func(m *Model, _ []string) tea.Cmd {
+			m.showInfo("Commands", m.commands.Help())
+			return nil
+		},
+	})
+
+	r.Register(SlashCommand{
+		Name:     "model",
+		Args:     "[name[@host]]",
+		Help:     "Switch model or open the model list",
+		Complete: completeModels,
+		Run: func(m *Model, args []string) tea.Cmd {
+			if len(args) == 0 {
+				return m.openModelSelection()
+			}
+			// Without a host, the current host's model is preferred.
+			name, host, ok := strings.Cut(args[0], "@")
+			if !ok {
+				host = m.currentHost
+			}
+			var match *aihub.OllamaModel
+			for i, model := range m.models {
+				if model.Name != name && !strings.HasPrefix(model.Name, name) {
+					continue
+				}
+				if model.Host == host {
+					match = &m.models[i]
+					break
+				}
+				if match == nil && !ok {
+					match = &m.models[i]
+				}
+			}
+			if match == nil {
+				return ShowToast("No model matches "+args[0], 2*time.Second)
+			}
+			selected := *match
+			return func() tea.Msg { return ModelSelectedMsg(selected) }
+		},
+	})
+
+	r.Register(SlashCommand{
+		Name:     "host",
+		Args:     "[name]",
+		Help:     "Switch host or open the host list",
+		Complete: completeHosts,
+		Run: func(m *Model, args []string) tea.Cmd {
+			if len(args) == 0 {
+				return m.openHosts()
+			}
+			if _, ok := m.cfg.Endpoint(args[0]); !ok {
+				return ShowToast("No host named "+args[0], 2*time.Second)
+			}
+			name := args[0]
+			return func() tea.Msg { return HostSelectedMsg{Name: name} }
+		},
+	})
+
+	r.Register(SlashCommand{
+		Name:    "new",
+		Aliases: []string{"clear"},
+		Help:    "Start a new chat (alias /clear)",
+		Run: func(m *Model, _ []string) tea.Cmd {
+			return m.startNewChat()
+		},
+	})
+
+	r.Register(SlashCommand{
+		Name:     "system",
+		Aliases:  []string{"persona"},
+		Args:     "[persona]",
+		Help:     "Pick or manage the system prompt",
+		Complete: completePersonas,
+		Run: func(m *Model, args []string) tea.Cmd {
+			if len(args) == 0 {
+				return m.chat.system.Focus()
+			}
+			name := strings.Join(args, " ")
+			if _, ok := m.cfg.FindPersona(name); !ok && name != defaultPersonaName {
+				return ShowToast("No persona named "+name, 2*time.Second)
+			}
+			applyPersona(m.historyManager, m.cfg, name)
+			m.updateFooterContent()
+			return ShowToast(fmt.Sprintf("Using persona %s", name), 2*time.Second)
+		},
+	})
+
+	r.Register(SlashCommand{
+		Name:     "template",
+		Args:     "[name]",
+		Help:     "Use a prompt template",
+		Complete: completeTemplates,
+		Run: func(m *Model, args []string) tea.Cmd {
+			if len(args) == 0 {
+				return m.chat.templates.Open(m.chat.historyID)
+			}
+			return m.chat.templates.OpenNamed(m.chat.historyID, args[0])
+		},
+	})
+
+	r.Register(SlashCommand{
+		Name:     "rag",
+		Args:     "[collection|off]",
+		Help:     "Answer from a document collection",
+		Complete: completeCollections,
+		Run: func(m *Model, args []string) tea.Cmd {
+			if len(args) == 0 {
+				return m.openCollections()
+			}
+			if args[0] == "off" {
+				m.historyManager.SetCollection("")
+				return ShowToast("Document retrieval off", 2*time.Second)
+			}
+			if _, err := rag.Load(args[0]); err != nil {
+				return ShowToast(err.Error(), 3*time.Second)
+			}
+			m.historyManager.SetCollection(args[0])
+			return ShowToast("Answering from "+args[0], 2*time.Second)
+		},
+	})
+
+	r.Register(SlashCommand{
+		Name: "retry",
+		Help: "Regenerate the last answer",
+		Run: func(m *Model, _ []string) tea.Cmd {
+			return m.chat.Retry()
+		},
+	})
+
+	r.Register(SlashCommand{
+		Name: "save",
+		Help: "Save the chat now",
+		Run: func(m *Model, _ []string) tea.Cmd {
+			return tea.Batch(m.chat.saveHistory(), ShowToast("Chat saved", 2*time.Second))
+		},
+	})
+
+	r.Register(SlashCommand{
+		Name: "export",
+		Args: "[file]",
+		Help: "Export the chat as Markdown",
+		Run: func(m *Model, args []string) tea.Cmd {
+			h := m.historyManager.GetCurrentHistory()
+			if h == nil || len(h.Messages) == 0 {
+				return ShowToast("Nothing to export", 2*time.Second)
+			}
+			if len(args) == 0 {
+				return exportChat(exportFileName(h), *h)
+			}
+			path := args[0]
+			if _, err := os.Stat(path); err == nil {
+				h := *h
+				return m.askConfirm("File exists", fmt.Sprintf("Overwrite %s?", path), func(yes bool) tea.Cmd {
+					if !yes {
+						return nil
+					}
+					return exportChat(path, h)
+				})
+			}
+			return exportChat(path, *h)
+		},
+	})
+
+	r.Register(SlashCommand{
+		Name: "temp",
+		Args: "[value]",
+		Help: "Set temperature for this chat (empty resets)",
+		Run: func(m *Model, args []string) tea.Cmd {
+			if len(args) == 0 {
+				m.historyManager.SetOption("temperature", nil)
+				return ShowToast("Temperature reset to model default", 2*time.Second)
+			}
+			v, err := strconv.ParseFloat(args[0], 64)
+			if err != nil || v < 0 || v > 2 {
+				return ShowToast("Temperature must be a number between 0 and 2", 2*time.Second)
+			}
+			m.historyManager.SetOption("temperature", v)
+			return ShowToast(fmt.Sprintf("Temperature set to %g", v), 2*time.Second)
+		},
+	})
+
+	r.Register(SlashCommand{
+		Name: "rename",
+		Args: "<title>",
+		Help: "Rename the chat",
+		Run: func(m *Model, args []string) tea.Cmd {
+			h := m.historyManager.GetCurrentHistory()
+			if h == nil || len(args) == 0 {
+				return ShowToast("Usage: /rename <title>", 2*time.Second)
+			}
+			title := strings.Join(args, " ")
+			if err := m.historyManager.EditHistory(h.ID, func(h *history.History) {
+				h.Title = title
+			}); err != nil {
+				return ShowToast("Rename failed: "+err.Error(), 3*time.Second)
+			}
+			return ShowToast("Chat renamed", 2*time.Second)
+		},
+	})
+
+	r.Register(SlashCommand{
+		Name: "history",
+		Help: "Open the chat history",
+		Run: func(m *Model, _ []string) tea.Cmd {
+			return m.openHistoryView()
+		},
+	})
+
+	r.Register(SlashCommand{
+		Name:    "quit",
+		Aliases: []string{"exit"},
+		Help:    "Quit llmv",
+		Run: func(m *Model, _ []string) tea.Cmd {
+			m.historyManager.Close()
+			return tea.Quit
+		},
+	})
+}
+
+// Completers
+
+func completeModels(m *Model, prefix string) []completionItem {
+	multiHost := m.cfg != nil && len(m.cfg.Endpoints()) > 1
+
+	var items []completionItem
+	for _, model := range m.models {
+		value := model.Name
+		if multiHost {
+			value += "@" + model.Host
+		}
+		if strings.HasPrefix(value, prefix) {
+			items = append(items, completionItem{
+				Value:  value,
+				Label:  value,
+				Detail: model.Details.ParameterSize,
+			})
+		}
+	}
+	return items
+}
+
+func completeHosts(m *Model, prefix string) []completionItem {
+	if m.cfg == nil {
+		return nil
+	}
+	var items []completionItem
+	for _, e := range m.cfg.Endpoints() {
+		if strings.HasPrefix(e.Name, prefix) {
+			items = append(items, completionItem{Value: e.Name, Label: e.Name, Detail: e.URL})
+		}
+	}
+	return items
+}
+
+func completePersonas(m *Model, prefix string) []completionItem {
+	var items []completionItem
+	for _, name := range m.chat.system.personaNames() {
+		if strings.HasPrefix(name, prefix) && !strings.Contains(name, " ") {
+			items = append(items, completionItem{Value: name, Label: name})
+		}
+	}
+	return items
+}
+
+func completeTemplates(m *Model, prefix string) []completionItem {
+	all, _, err := templates.Load()
+	if err != nil {
+		return nil
+	}
+	var items []completionItem
+	for _, t := range all {
+		if strings.HasPrefix(t.Name, prefix) {
+			items = append(items, completionItem{Value: t.Name, Label: t.Name, Detail: t.Description})
+		}
+	}
+	return items
+}
+
+func completeCollections(_ *Model, prefix string) []completionItem {
+	infos, err := rag.List()
+	if err != nil {
+		return nil
+	}
+	items := []completionItem{{Value: "off", Label: "off", Detail: "stop using documents"}}
+	for _, c := range infos {
+		if strings.HasPrefix(c.Name, prefix) {
+			items = append(items, completionItem{Value: c.Name, Label: c.Name, Detail: c.Root})
+		}
+	}
+	if !strings.HasPrefix("off", prefix) {
+		items = items[1:]
+	}
+	return items
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// exportChat writes h as Markdown to path.
+func exportChat(path string, h history.History) tea.Cmd {
+	if err := os.WriteFile(path, []byte(history.ExportMarkdown(h)), 0644); err != nil {
+		return ShowToast("Export failed: "+err.Error(), 3*time.Second)
+	}
+	return ShowToast("Exported to "+path, 3*time.Second)
+}
+
+// exportFileName builds a default export path in the working directory,
+// numbered so an earlier export is not overwritten.
+func exportFileName(h *history.History) string {
+	name := strings.Trim(unsafeFileChars.ReplaceAllString(strings.ToLower(h.Title), "-"), "-")
+	if len(name) > 40 {
+		name = name[:40]
+	}
+	if name == "" {
+		name = h.ID
+	}
+	path := filepath.Join(".", "llmv-"+name+".md")
+	for n := 2; ; n++ {
+		if _, err := os.Stat(path); err != nil {
+			return path
+		}
+		path = filepath.Join(".", fmt.Sprintf("llmv-%s-%d.md", name, n))
+	}
+}
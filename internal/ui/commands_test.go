@@ -0,0 +1,39 @@
+package ui
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+	}{
+		{"empty", "", nil},
+		{"blank", "  \t ", nil},
+		{"words", "temp 0.2", []string{"temp", "0.2"}},
+		{"extra whitespace", "  save \t notes.md  ", []string{"save", "notes.md"}},
+		{"quoted", `system "be brief and kind"`, []string{"system", "be brief and kind"}},
+		{"quotes inside a word", `tag a"b c"d`, []string{"tag", "ab cd"}},
+		{"empty quotes", `rename ""`, []string{"rename", ""}},
+		{"adjacent quotes", `"a""b"`, []string{"ab"}},
+		{"unterminated quote", `system "be brief`, []string{"system", "be brief"}},
+		{"escaped quote", `system say \"hi\"`, []string{"system", "say", `"hi"`}},
+		{"escaped quote in quotes", `system "say \"hi\""`, []string{"system", `say "hi"`}},
+		{"escaped backslash", `save a\\b`, []string{"save", `a\b`}},
+		{"escaped backslash before quote", `"a\\" b`, []string{`a\`, "b"}},
+		{"windows path", `export C:\Users\me\chat.md`, []string{"export", `C:\Users\me\chat.md`}},
+		{"trailing backslash", `save dir\`, []string{"save", `dir\`}},
+		{"non-ASCII", `tag "größe ümlaut" 日本`, []string{"tag", "größe ümlaut", "日本"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseArgs(tt.in); !slices.Equal(got, tt.want) {
+				t.Errorf("parseArgs(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
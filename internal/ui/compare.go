@@ -0,0 +1,490 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aj-seven/llmverse/internal/attach"
+	"github.com/aj-seven/llmverse/internal/config"
+	"github.com/aj-seven/llmverse/internal/history"
+	aihub "github.com/aj-seven/llmverse/internal/providers/ollama"
+	"github.com/aj-seven/llmverse/pkg/chat"
+	"github.com/aj-seven/llmverse/pkg/keymap"
+	messages "github.com/aj-seven/llmverse/pkg/messages"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Styles
+
+var (
+	compareColumnStyle = lipgloss.NewStyle().
+				Border(lipgloss.RoundedBorder()).
+				BorderForeground(lipgloss.Color("8")).
+				Padding(0, 1)
+
+	compareFocusedColumnStyle = compareColumnStyle.
+					BorderForeground(lipgloss.Color("6"))
+
+	compareTitleStyle = lipgloss.NewStyle().
+				Bold(true).
+				Foreground(lipgloss.Color("6"))
+)
+
+// Messages
+
+// CompareModelsMsg asks the root model to open the compare view for the
+// given models.
+type CompareModelsMsg struct {
+	Models []aihub.OllamaModel
+}
+
+// compareStartedMsg reports that the request of one column was accepted.
+type compareStartedMsg struct {
+	run    int
+	col    int
+	stream <-chan string
+	err    error
+}
+
+// compareChunkMsg carries output of one compare column. run tells apart
+// streams of an earlier prompt that are still draining.
+type compareChunkMsg struct {
+	run    int
+	col    int
+	chunk  string
+	pieces int
+}
+
+type compareDoneMsg struct {
+	run int
+	col int
+}
+
+// compareKeptMsg is sent after an answer was added to the chat history.
+type compareKeptMsg struct {
+	model string
+}
+
+// compareColumn is the answer of one model.
+type compareColumn struct {
+	model    string
+	host     string
+	content  string
+	err      error
+	viewport viewport.Model
+
+	stream    <-chan string
+	cancel    chan struct{}
+	streaming bool
+
+	started    time.Time
+	firstToken time.Duration
+	tokens     int // stream pieces, about one per token with Ollama
+	elapsed    time.Duration
+}
+
+// Compare Model
+
+// CompareModel sends one prompt to several models at once and shows the
+// answers side by side.
+type CompareModel struct {
+	columns  []*compareColumn
+	focused  int
+	textarea textarea.Model
+	prompt   string
+	run      int
+
+	historyManager *history.Manager
+	cfg            *config.Config
+	workDir        string
+	// chatStreaming reports whether the chat with the given history ID
+	// is receiving a reply.
+	chatStreaming func(historyID string) bool
+
+	markdown markdownRenderer
+
+	width  int
+	height int
+}
+
+// Constructor
+func NewCompareModel(models []aihub.OllamaModel, hm *history.Manager, cfg *config.Config, workDir string, chatStreaming func(historyID string) bool) *CompareModel {
+	ta := textarea.New()
+	ta.Placeholder = "Ask all models the same question..."
+	ta.Prompt = "❯ "
+	ta.ShowLineNumbers = false
+	ta.SetHeight(2)
+	ta.Focus()
+
+	m := &CompareModel{
+		textarea:       ta,
+		historyManager: hm,
+		cfg:            cfg,
+		workDir:        workDir,
+		chatStreaming:  chatStreaming,
+	}
+	for _, model := range models {
+		m.columns = append(m.columns, &compareColumn{model: model.Name, host: model.Host})
+	}
+	return m
+}
+
+func (m *CompareModel) Init() tea.Cmd {
+	return textarea.Blink
+}
+
+func (m *CompareModel) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+	m.textarea.SetWidth(w - 4)
+
+	colW := m.columnWidth()
+	colH := max(3, h-m.textarea.Height()-2-4)
+	for _, c := range m.columns {
+		c.viewport.Width = colW - 4
+		c.viewport.Height = colH
+	}
+	m.refreshAll()
+}
+
+// Update
+
+func (m *CompareModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+
+	case compareStartedMsg:
+		if msg.run != m.run {
+			// Stopped before it started; let the request finish quietly.
+			if msg.stream != nil {
+				go func() {
+					for range msg.stream {
+					}
+				}()
+			}
+			return m, nil
+		}
+		c := m.columns[msg.col]
+		if msg.err != nil {
+			c.err = msg.err
+			c.streaming = false
+			m.refresh(c, true)
+			if !m.streaming() {
+				return m, m.textarea.Focus()
+			}
+			return m, nil
+		}
+		c.stream = msg.stream
+		return m, readCompareCmd(m.run, msg.col, c.stream, c.cancel)
+
+	case compareChunkMsg:
+		if msg.run != m.run {
+			return m, nil
+		}
+		c := m.columns[msg.col]
+		if c.tokens == 0 {
+			c.firstToken = time.Since(c.started)
+		}
+		c.content += msg.chunk
+		c.tokens += msg.pieces
+		c.elapsed = time.Since(c.started)
+		m.refresh(c, true)
+		return m, readCompareCmd(m.run, msg.col, c.stream, c.cancel)
+
+	case compareDoneMsg:
+		if msg.run != m.run {
+			return m, nil
+		}
+		c := m.columns[msg.col]
+		c.streaming = false
+		c.elapsed = time.Since(c.started)
+		m.refresh(c, true)
+		if !m.streaming() {
+			return m, m.textarea.Focus()
+		}
+		return m, nil
+
+	case tea.MouseMsg:
+		switch msg.Type {
+		case tea.MouseWheelUp:
+			for _, c := range m.columns {
+				c.viewport.ScrollUp(3)
+			}
+		case tea.MouseWheelDown:
+			for _, c := range m.columns {
+				c.viewport.ScrollDown(3)
+			}
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc":
+			if m.streaming() {
+				m.stopAll()
+				return m, m.textarea.Focus()
+			}
+			return m, func() tea.Msg {
+				return messages.GoBackMsg{}
+			}
+
+		case "tab":
+			m.focused = (m.focused + 1) % len(m.columns)
+			return m, nil
+
+		case "shift+tab":
+			m.focused = (m.focused + len(m.columns) - 1) % len(m.columns)
+			return m, nil
+
+		case "pgup":
+			m.columns[m.focused].viewport.HalfPageUp()
+			return m, nil
+
+		case "pgdown":
+			m.columns[m.focused].viewport.HalfPageDown()
+			return m, nil
+
+		case "ctrl+k":
+			return m, m.keepFocused()
+
+		case "enter":
+			if !m.streaming() && strings.TrimSpace(m.textarea.Value()) != "" {
+				return m, m.send()
+			}
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.textarea, cmd = m.textarea.Update(msg)
+	return m, cmd
+}
+
+// View
+
+func (m *CompareModel) View() string {
+	cols := make([]string, len(m.columns))
+	for i, c := range m.columns {
+		style := compareColumnStyle
+		if i == m.focused {
+			style = compareFocusedColumnStyle
+		}
+		body := lipgloss.JoinVertical(
+			lipgloss.Left,
+			compareTitleStyle.Render(trim(m.label(c), m.columnWidth()-4)),
+			dimStyle.Render(c.stats()),
+			c.viewport.View(),
+		)
+		cols[i] = style.Width(m.columnWidth() - 2).Render(body)
+	}
+
+	input := m.textarea.View()
+	if m.streaming() {
+		input = dimStyle.Render(" Generating… (esc to stop)")
+	}
+
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		lipgloss.JoinHorizontal(lipgloss.Top, cols...),
+		dividerStyle.Render(strings.Repeat("─", m.width)),
+		input,
+	)
+}
+
+// Helpers
+
+func (m *CompareModel) columnWidth() int {
+	if len(m.columns) == 0 {
+		return m.width
+	}
+	return max(20, m.width/len(m.columns))
+}
+
+// label names the model of a column, with its host when several hosts
+// are configured.
+func (m *CompareModel) label(c *compareColumn) string {
+	if c.host == "" || m.cfg == nil || len(m.cfg.Endpoints()) < 2 {
+		return c.model
+	}
+	return c.model + " @ " + c.host
+}
+
+func (m *CompareModel) streaming() bool {
+	for _, c := range m.columns {
+		if c.streaming {
+			return true
+		}
+	}
+	return false
+}
+
+// send starts streaming the prompt to every model concurrently. The
+// current chat, including its system prompt, is sent as context.
+func (m *CompareModel) send() tea.Cmd {
+	m.prompt = strings.TrimSpace(m.textarea.Value())
+
+	atts, err := attach.Resolve(m.prompt, m.workDir)
+	if err != nil {
+		return ShowToast(err.Error(), 3*time.Second)
+	}
+
+	var msgs []chat.Message
+	if h := m.historyManager.GetCurrentHistory(); h != nil {
+		if h.SystemPrompt != "" {
+			msgs = append(msgs, chat.Message{Role: "system", Content: h.SystemPrompt})
+		}
+		for _, msg := range h.Messages {
+			msgs = append(msgs, msg.Expanded())
+		}
+	}
+	msgs = append(msgs, chat.Message{Role: "user", Content: m.prompt, Attachments: atts}.Expanded())
+
+	var options map[string]any
+	if h := m.historyManager.GetCurrentHistory(); h != nil {
+		options = h.Options
+	}
+
+	m.run++
+	m.textarea.Reset()
+	m.textarea.Blur()
+
+	// Each request runs in its own command, so a model that is slow to
+	// load does not hold back the others.
+	var cmds []tea.Cmd
+	for i, c := range m.columns {
+		*c = compareColumn{
+			model:     c.model,
+			host:      c.host,
+			viewport:  c.viewport,
+			started:   time.Now(),
+			cancel:    make(chan struct{}),
+			streaming: true,
+		}
+		m.refresh(c, true)
+
+		// Each column compares one model, so failures are retried but
+		// not handed to fallbacks.
+		run, col, cfg, cancel := m.run, i, m.cfg, c.cancel
+		targets := []aihub.Target{{Model: c.model, Host: c.host}}
+		cmds = append(cmds, func() tea.Msg {
+			stream, _, err := aihub.StreamChatRetry(targets, msgs, options, cfg, cancel)
+			return compareStartedMsg{run: run, col: col, stream: stream, err: err}
+		})
+	}
+	return tea.Batch(cmds...)
+}
+
+func (m *CompareModel) stopAll() {
+	for _, c := range m.columns {
+		if c.cancel != nil {
+			close(c.cancel)
+			c.cancel = nil
+		}
+		c.streaming = false
+	}
+	// Drop whatever the cancelled streams still deliver.
+	m.run++
+	m.refreshAll()
+}
+
+// keepFocused adds the prompt and the focused column's answer to the chat
+// history, as if the chat had been answered by that model.
+func (m *CompareModel) keepFocused() tea.Cmd {
+	c := m.columns[m.focused]
+	if m.prompt == "" || c.content == "" || c.streaming {
+		return ShowToast("Nothing to keep yet", 2*time.Second)
+	}
+
+	h := m.historyManager.GetCurrentHistory()
+	if h == nil {
+		return nil
+	}
+	// The reply would be written into the middle of the kept exchange.
+	if m.chatStreaming != nil && m.chatStreaming(h.ID) {
+		return ShowToast("The chat is still receiving a reply; keep the answer once it is done", 3*time.Second)
+	}
+
+	atts, _ := attach.Resolve(m.prompt, m.workDir)
+	m.historyManager.AddUserMessage(h.ID, m.prompt, atts...)
+	m.historyManager.UpdateAssistantMessage(h.ID, c.content)
+	m.historyManager.SetLastAssistantModel(h.ID, c.model, c.host)
+	m.prompt = ""
+
+	model := m.label(c)
+	return func() tea.Msg { return compareKeptMsg{model: model} }
+}
+
+func (m *CompareModel) markdownStyle() string {
+	if m.cfg == nil {
+		return ""
+	}
+	return m.cfg.Theme.Markdown
+}
+
+func (m *CompareModel) refreshAll() {
+	for _, c := range m.columns {
+		m.refresh(c, false)
+	}
+}
+
+func (m *CompareModel) refresh(c *compareColumn, follow bool) {
+	content := c.content
+	if content != "" {
+		if rendered, err := m.markdown.Render(content, m.markdownStyle(), c.viewport.Width-2); err == nil {
+			content = rendered
+		}
+	}
+	if c.err != nil {
+		content += "\n" + lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Render("Error: "+c.err.Error())
+	}
+
+	c.viewport.SetContent(content)
+	if follow && c.streaming {
+		c.viewport.GotoBottom()
+	}
+}
+
+func (c *compareColumn) stats() string {
+	switch {
+	case c.started.IsZero():
+		return "waiting for a prompt"
+	case c.tokens == 0 && c.streaming:
+		return "thinking…"
+	case c.tokens == 0:
+		return "no output"
+	}
+
+	rate := 0.0
+	if gen := c.elapsed - c.firstToken; gen > 0 {
+		rate = float64(c.tokens) / gen.Seconds()
+	}
+	return fmt.Sprintf("%.1f tok/s · %d tok · first %.1fs · %.1fs",
+		rate, c.tokens, c.firstToken.Seconds(), c.elapsed.Seconds())
+}
+
+func readCompareCmd(run, col int, stream <-chan string, cancel <-chan struct{}) tea.Cmd {
+	return func() tea.Msg {
+		chunk, pieces, ok := nextChunk(stream, cancel)
+		if !ok {
+			return compareDoneMsg{run: run, col: col}
+		}
+		return compareChunkMsg{run: run, col: col, chunk: chunk, pieces: pieces}
+	}
+}
+
+// Public API
+
+// Shortcuts returns the footer shortcuts of the view.
+func (m *CompareModel) Shortcuts() []keymap.Shortcut {
+	return []keymap.Shortcut{
+		{Key: "enter", Action: "Send"},
+		{Key: "tab", Action: "Next Column"},
+		{Key: "pgup/pgdn", Action: "Scroll"},
+		{Key: "ctrl+k", Action: "Keep Answer"},
+		{Key: "esc", Action: "Stop/Back"},
+	}
+}
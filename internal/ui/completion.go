@@ -0,0 +1,123 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const maxCompletions = 6
+
+// Styles
+
+var (
+	completionStyle = lipgloss.NewStyle().
+			PaddingLeft(1).
+			Background(lipgloss.Color("236")).
+			Foreground(lipgloss.Color("252"))
+
+	completionSelectedStyle = completionStyle.
+				Background(lipgloss.Color("57")).
+				Foreground(lipgloss.Color("230"))
+
+	completionDetailStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("245"))
+)
+
+// completionItem is one suggestion in the input autocompletion popup.
+// Value replaces the token being completed when the item is accepted.
+type completionItem struct {
+	Value  string
+	Label  string
+	Detail string
+}
+
+// completion holds the suggestions for the token at the end of the input.
+type completion struct {
+	token     string
+	items     []completionItem
+	cursor    int
+	dismissed string // input value the popup was dismissed for
+}
+
+func (c *completion) visible() bool {
+	return len(c.items) > 0
+}
+
+func (c *completion) set(token string, items []completionItem) {
+	if token != c.token {
+		c.cursor = 0
+	}
+	c.token = token
+	c.items = items
+	if c.cursor >= len(items) {
+		c.cursor = 0
+	}
+}
+
+func (c *completion) clear() {
+	c.token = ""
+	c.items = nil
+	c.cursor = 0
+}
+
+func (c *completion) move(delta int) {
+	if len(c.items) == 0 {
+		return
+	}
+	c.cursor = (c.cursor + delta + len(c.items)) % len(c.items)
+}
+
+func (c *completion) selected() completionItem {
+	return c.items[c.cursor]
+}
+
+// lastToken returns the whitespace-delimited word at the end of s.
+func lastToken(s string) string {
+	if i := strings.LastIndexAny(s, " \t\n"); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
+
+// replaceLastToken swaps the word at the end of s for value.
+func replaceLastToken(s, value string) string {
+	return strings.TrimSuffix(s, lastToken(s)) + value
+}
+
+// render draws the suggestions, windowed around the cursor.
+func (c *completion) render(width int) []string {
+	start := max(0, c.cursor-maxCompletions+1)
+	end := min(len(c.items), start+maxCompletions)
+
+	labelW := 0
+	for _, it := range c.items[start:end] {
+		labelW = max(labelW, lipgloss.Width(it.Label))
+	}
+
+	lines := make([]string, 0, end-start)
+	for i := start; i < end; i++ {
+		it := c.items[i]
+		style := completionStyle
+		if i == c.cursor {
+			style = completionSelectedStyle
+		}
+
+		line := it.Label + strings.Repeat(" ", labelW-lipgloss.Width(it.Label))
+		if it.Detail != "" {
+			line += "  " + completionDetailStyle.Render(it.Detail)
+		}
+		lines = append(lines, style.Width(width).MaxWidth(width).Render(line))
+	}
+	return lines
+}
+
+// overlayBottom replaces the last lines of view with overlay.
+func overlayBottom(view string, overlay []string) string {
+	lines := strings.Split(view, "\n")
+	if len(overlay) > len(lines) {
+		overlay = overlay[len(overlay)-len(lines):]
+	}
+	copy(lines[len(lines)-len(overlay):], overlay)
+	return strings.Join(lines, "\n")
+}
@@ -41,7 +41,8 @@ type ConfirmDialog struct {
 }
 
 func (c *ConfirmDialog) SetSize(width int, height int) {
-	panic("unimplemented")
+	c.width = width
+	c.height = height
 }
 
 // ConfirmDialog constructor
@@ -0,0 +1,161 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/aj-seven/llmverse/internal/config"
+	"github.com/aj-seven/llmverse/internal/history"
+	aihub "github.com/aj-seven/llmverse/internal/providers/ollama"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Connection
+//
+// llmv starts without a reachable Ollama server, with history browsing
+// and export still working. The model lists of all configured hosts are
+// polled in the background: often while the current host is offline so
+// it is picked up soon after it starts, and rarely while it is online to
+// notice it going away and models being pulled or removed.
+
+const (
+	offlinePollInterval = 3 * time.Second
+	onlinePollInterval  = 30 * time.Second
+)
+
+// Messages
+
+// connectionMsg carries the model lists of all hosts. poll is set for the
+// requests of the background poll, which schedule the next one.
+type connectionMsg struct {
+	hosts []aihub.HostModels
+	poll  bool
+}
+
+type connectionTickMsg struct{}
+
+// reconnectMsg asks for a connection check right away, e.g. after a
+// request failed.
+type reconnectMsg struct{}
+
+// checkConnection requests the model lists.
+func (m *Model) checkConnection(poll bool) tea.Cmd {
+	cfg := m.cfg
+	return func() tea.Msg {
+		return connectionMsg{hosts: aihub.GetModelsAllHosts(cfg), poll: poll}
+	}
+}
+
+// handleConnection updates the connection state and the model list.
+func (m *Model) handleConnection(msg connectionMsg) tea.Cmd {
+	var cmds []tea.Cmd
+
+	wasOnline := m.online
+	m.setHosts(msg.hosts)
+
+	if msg.poll {
+		interval := offlinePollInterval
+		if m.isOnline(m.currentHost) {
+			interval = onlinePollInterval
+		}
+		cmds = append(cmds, tea.Tick(interval, func(time.Time) tea.Msg {
+			return connectionTickMsg{}
+		}))
+	}
+
+	for _, chat := range m.tabs {
+		chat.SetOffline(!m.isOnline(chat.host))
+	}
+	if m.hosts != nil {
+		m.hosts.setStatus(m.online, m.hostErrs, m.models)
+	}
+
+	for _, h := range msg.hosts {
+		name := "Ollama"
+		if len(msg.hosts) > 1 {
+			name = h.Endpoint.Name
+		}
+		switch host := h.Endpoint.Name; {
+		case m.online[host] && !wasOnline[host]:
+			cmds = append(cmds, ShowToast(fmt.Sprintf("Connected to %s", name), 2*time.Second))
+		case !m.online[host] && wasOnline[host]:
+			cmds = append(cmds, ShowToast(fmt.Sprintf("Lost connection to %s", name), 3*time.Second))
+		}
+	}
+
+	m.updateFooterContent()
+	return tea.Batch(cmds...)
+}
+
+// setHosts records which hosts answered, and why the others did not, and
+// lists the models of those that did.
+func (m *Model) setHosts(hosts []aihub.HostModels) {
+	online, errs := map[string]bool{}, map[string]error{}
+	var models []aihub.OllamaModel
+	for _, h := range hosts {
+		if h.Err != nil {
+			errs[h.Endpoint.Name] = h.Err
+			continue
+		}
+		online[h.Endpoint.Name] = true
+		models = append(models, h.Models...)
+	}
+	m.online, m.hostErrs = online, errs
+	m.setModels(models)
+}
+
+// setModels replaces the model list. Chats started before any model was
+// known get the default model.
+func (m *Model) setModels(models []aihub.OllamaModel) {
+	if slices.EqualFunc(models, m.models, func(a, b aihub.OllamaModel) bool {
+		return a.Name == b.Name && a.Host == b.Host && a.Digest == b.Digest
+	}) {
+		return
+	}
+	m.models = models
+
+	if m.currentModel != "" || len(models) == 0 {
+		return
+	}
+	last := lastModel(models, m.state, m.currentHost)
+	m.currentModel, m.currentHost = last.Name, last.Host
+	for _, chat := range m.tabs {
+		if chat.modelName == "" {
+			chat.modelName, chat.host = m.currentModel, m.currentHost
+			_ = m.historyManager.EditHistory(chat.historyID, func(h *history.History) {
+				h.Model, h.Host = m.currentModel, m.currentHost
+			})
+		}
+	}
+}
+
+// isOnline reports whether the named host answered the last check. Chats
+// of hosts that are no longer configured use the default host.
+func (m *Model) isOnline(host string) bool {
+	return m.online[endpointName(m.cfg, host)]
+}
+
+// endpointName returns name if it is a configured host, otherwise the name
+// of the default host.
+func endpointName(cfg *config.Config, name string) string {
+	if cfg == nil {
+		return name
+	}
+	if _, ok := cfg.Endpoint(name); ok {
+		return name
+	}
+	return cfg.DefaultEndpoint().Name
+}
+
+// SetOffline switches the chat in and out of offline mode, in which
+// prompts are kept in the input instead of being sent.
+func (m *ChatModel) SetOffline(offline bool) {
+	m.offline = offline
+	if offline {
+		m.textarea.Placeholder = "Ollama is offline · ctrl+h to browse history"
+	} else {
+		m.textarea.Placeholder = defaultPlaceholder
+	}
+}
@@ -0,0 +1,88 @@
+package ui
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Messages
+
+// editorFinishedMsg carries the text saved in the external editor.
+type editorFinishedMsg struct {
+	text string
+	err  error
+}
+
+// External Editor
+
+// openEditor suspends the program and edits text in the user's editor,
+// $VISUAL or $EDITOR. The saved text is sent back in an
+// editorFinishedMsg once the editor exits.
+func openEditor(text string) tea.Cmd {
+	editor := editorCommand()
+	if len(editor) == 0 {
+		return ShowToast("Set $EDITOR to edit prompts", 3*time.Second)
+	}
+
+	f, err := os.CreateTemp("", "llmv-prompt-*.md")
+	if err != nil {
+		return ShowToast("Editor failed: "+err.Error(), 3*time.Second)
+	}
+	path := f.Name()
+	_, err = f.WriteString(text)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return ShowToast("Editor failed: "+err.Error(), 3*time.Second)
+	}
+
+	cmd := exec.Command(editor[0], append(editor[1:], path)...)
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		defer os.Remove(path)
+		if err != nil {
+			return editorFinishedMsg{err: err}
+		}
+		data, err := os.ReadFile(path)
+		return editorFinishedMsg{text: string(data), err: err}
+	})
+}
+
+// editorCommand returns the editor command line with its arguments, e.g.
+// "code --wait".
+func editorCommand() []string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(env)); len(fields) > 0 {
+			return fields
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return []string{"notepad"}
+	}
+	if _, err := exec.LookPath("vi"); err == nil {
+		return []string{"vi"}
+	}
+	return nil
+}
+
+// handleEditorFinished loads the edited text into the input.
+func (m *ChatModel) handleEditorFinished(msg editorFinishedMsg) tea.Cmd {
+	if msg.err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(msg.err, &exitErr) {
+			return tea.Batch(ShowToast("Editor exited with an error; input unchanged", 3*time.Second), m.FocusInput())
+		}
+		return tea.Batch(ShowToast("Editor failed: "+msg.err.Error(), 3*time.Second), m.FocusInput())
+	}
+
+	m.textarea.SetValue(strings.TrimRight(msg.text, "\r\n"))
+	m.textarea.CursorEnd()
+	return m.FocusInput()
+}
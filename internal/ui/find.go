@@ -0,0 +1,308 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/aj-seven/llmverse/pkg/keymap"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Find in Chat
+//
+// ctrl+f searches the rendered messages of the chat. All matches are
+// highlighted, the current one in a different color, and n/N jump
+// between them.
+
+const (
+	matchOn         = "\x1b[7m"
+	matchOff        = "\x1b[27m"
+	currentMatchOn  = "\x1b[30;103m"
+	currentMatchOff = "\x1b[39;49m"
+)
+
+// chatFind is the state of an open search. While typing, keys edit the
+// query; afterwards they move between matches.
+type chatFind struct {
+	query   string
+	typing  bool
+	matches []findMatch
+	current int
+}
+
+// findMatch is a match in the viewport content: its line, and its first
+// and end column in visible runes.
+type findMatch struct {
+	line       int
+	start, end int
+}
+
+func (m *ChatModel) startFind() tea.Cmd {
+	if m.find == nil {
+		m.find = &chatFind{}
+	}
+	m.find.typing = true
+	m.blurInput()
+	m.completion.clear()
+	return func() tea.Msg { return chatModeMsg{} }
+}
+
+func (m *ChatModel) stopFind() tea.Cmd {
+	m.find = nil
+	m.updateViewport(false)
+	return tea.Batch(
+		m.FocusInput(),
+		func() tea.Msg { return chatModeMsg{} },
+	)
+}
+
+// updateFind handles keys while a search is open.
+func (m *ChatModel) updateFind(msg tea.KeyMsg) tea.Cmd {
+	f := m.find
+
+	if f.typing {
+		switch msg.Type {
+		case tea.KeyEsc:
+			return m.stopFind()
+		case tea.KeyEnter:
+			f.typing = false
+			return func() tea.Msg { return chatModeMsg{} }
+		case tea.KeyBackspace:
+			runes := []rune(f.query)
+			if len(runes) == 0 {
+				return nil
+			}
+			f.query = string(runes[:len(runes)-1])
+		case tea.KeyRunes, tea.KeySpace:
+			f.query += string(msg.Runes)
+		default:
+			return nil
+		}
+
+		// Find the matches, then mark the first one on screen.
+		m.updateViewport(false)
+		f.current = f.firstFrom(m.viewport.YOffset)
+		m.updateViewport(false)
+		m.showMatch()
+		return nil
+	}
+
+	switch msg.String() {
+	case "esc":
+		return m.stopFind()
+	case "ctrl+f", "/":
+		return m.startFind()
+	case "n", "enter":
+		m.stepMatch(1)
+	case "N", "shift+enter":
+		m.stepMatch(-1)
+	case "up", "k", "down", "j", "pgup", "pgdown", "home", "end":
+		m.lockScroll = true
+		var cmd tea.Cmd
+		m.viewport, cmd = m.viewport.Update(msg)
+		return cmd
+	}
+	return nil
+}
+
+// stepMatch moves to the next (1) or previous (-1) match, wrapping around.
+func (m *ChatModel) stepMatch(delta int) {
+	n := len(m.find.matches)
+	if n == 0 {
+		return
+	}
+	m.find.current = (m.find.current + delta + n) % n
+	m.updateViewport(false)
+	m.showMatch()
+}
+
+// showMatch scrolls the current match into the middle of the viewport.
+func (m *ChatModel) showMatch() {
+	if len(m.find.matches) == 0 {
+		return
+	}
+	m.lockScroll = true
+	m.viewport.SetYOffset(m.find.matches[m.find.current].line - m.viewport.Height/2)
+}
+
+// firstFrom returns the first match at or below line, or the first
+// match if there is none.
+func (f *chatFind) firstFrom(line int) int {
+	for i, match := range f.matches {
+		if match.line >= line {
+			return i
+		}
+	}
+	return 0
+}
+
+// highlight finds the query in the rendered content and marks the
+// matches. Matches do not span lines.
+func (f *chatFind) highlight(content string) string {
+	f.matches = f.matches[:0]
+	if f.query == "" {
+		return content
+	}
+	query := []rune(strings.ToLower(f.query))
+
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		text := []rune(strings.Map(unicode.ToLower, stripANSI(line)))
+
+		first := len(f.matches)
+		for j := 0; j+len(query) <= len(text); {
+			if string(text[j:j+len(query)]) == string(query) {
+				f.matches = append(f.matches, findMatch{line: i, start: j, end: j + len(query)})
+				j += len(query)
+			} else {
+				j++
+			}
+		}
+		if len(f.matches) > first {
+			lines[i] = markMatches(line, f.matches[first:], f.current-first)
+		}
+	}
+
+	if f.current >= len(f.matches) {
+		f.current = max(len(f.matches)-1, 0)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// markMatches wraps the given matches of one line in highlight escape
+// sequences. current is the index of the current match among them, if it
+// is on this line. Escape sequences inside a match are kept, with the
+// highlight turned back on after each.
+func markMatches(line string, matches []findMatch, current int) string {
+	var b strings.Builder
+	col, k := 0, 0
+	inside := false
+	on, off := matchOn, matchOff
+
+	for i := 0; i < len(line); {
+		if n := escapeLen(line[i:]); n > 0 {
+			b.WriteString(line[i : i+n])
+			if inside {
+				b.WriteString(on)
+			}
+			i += n
+			continue
+		}
+
+		if inside && col == matches[k].end {
+			b.WriteString(off)
+			inside = false
+			k++
+		}
+		if !inside && k < len(matches) && col == matches[k].start {
+			on, off = matchOn, matchOff
+			if k == current {
+				on, off = currentMatchOn, currentMatchOff
+			}
+			b.WriteString(on)
+			inside = true
+		}
+
+		_, size := utf8.DecodeRuneInString(line[i:])
+		b.WriteString(line[i : i+size])
+		i += size
+		col++
+	}
+	if inside {
+		b.WriteString(off)
+	}
+	return b.String()
+}
+
+// stripANSI removes escape sequences, leaving the visible text.
+func stripANSI(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if n := escapeLen(s[i:]); n > 0 {
+			i += n
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		b.WriteString(s[i : i+size])
+		i += size
+	}
+	return b.String()
+}
+
+// escapeLen returns the length of the CSI or OSC escape sequence s starts
+// with, or 0.
+func escapeLen(s string) int {
+	if len(s) < 2 || s[0] != '\x1b' {
+		return 0
+	}
+	switch s[1] {
+	case '[':
+		for i := 2; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7e {
+				return i + 1
+			}
+		}
+	case ']':
+		for i := 2; i < len(s); i++ {
+			if s[i] == '\a' {
+				return i + 1
+			}
+			if s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '\\' {
+				return i + 2
+			}
+		}
+	default:
+		return 2
+	}
+	return len(s)
+}
+
+// renderFind renders the search line shown in place of the input.
+func (m *ChatModel) renderFind() string {
+	line := searchPromptStyle.Render("Find: ") + m.find.query
+	if m.find.typing {
+		line += "▏"
+	}
+	if status := m.FindStatus(); status != "" {
+		line += "  " + m.placeholderStyle.Render(status)
+	}
+	return line
+}
+
+// Finding reports whether a search in the chat is open.
+func (m *ChatModel) Finding() bool {
+	return m.find != nil
+}
+
+// FindStatus returns the position of the current match, like "3/12".
+func (m *ChatModel) FindStatus() string {
+	switch {
+	case m.find == nil || m.find.query == "":
+		return ""
+	case len(m.find.matches) == 0:
+		return "no matches"
+	default:
+		return fmt.Sprintf("%d/%d", m.find.current+1, len(m.find.matches))
+	}
+}
+
+// FindShortcuts returns the shortcuts of the search in the chat.
+func (m *ChatModel) FindShortcuts() []keymap.Shortcut {
+	if m.find != nil && m.find.typing {
+		return []keymap.Shortcut{
+			{Key: "type", Action: "Search"},
+			{Key: "enter", Action: "Done"},
+			{Key: "esc", Action: "Close"},
+		}
+	}
+	return []keymap.Shortcut{
+		{Key: "n", Action: "Next"},
+		{Key: "shift+n", Action: "Previous"},
+		{Key: "↑/↓", Action: "Scroll"},
+		{Key: "/", Action: "Edit"},
+		{Key: "esc", Action: "Close"},
+	}
+}
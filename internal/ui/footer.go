@@ -16,6 +16,9 @@ type Footer struct {
 	secondaryContent string
 	showContent      bool
 
+	// status is shown on the right of the content row unless a toast is.
+	status string
+
 	// Shortcuts
 	shortcuts     []keymap.Shortcut
 	showShortcuts bool
@@ -63,6 +66,10 @@ func (f *Footer) SetContent(primary, secondary string) {
 	f.secondaryContent = secondary
 }
 
+func (f *Footer) SetStatus(status string) {
+	f.status = status
+}
+
 func (f *Footer) SetShortcuts(shortcuts ...keymap.Shortcut) {
 	f.shortcuts = shortcuts
 }
@@ -115,23 +122,10 @@ func (f *Footer) View() string {
 		modelName = modelContent
 	}
 
-	// secondary content (System Message)
-	sysMsgContent := f.secondaryContent
-	sysMsgLabel := ""
-	sysMsgIndicator := ""
-	if strings.HasPrefix(sysMsgContent, "System Message: ") {
-		sysMsgLabel = "System Message: "
-		sysMsgIndicator = strings.TrimPrefix(sysMsgContent, "System Message: ")
-	}
-
-	var sysMsgStatus string
-	var indicatorStyle lipgloss.Style
-	if strings.TrimSpace(sysMsgIndicator) == "✔" {
-		sysMsgStatus = "Available"
-		indicatorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Bold(true)
-	} else if strings.TrimSpace(sysMsgIndicator) == "✖" {
-		sysMsgStatus = "NA"
-		indicatorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Bold(true)
+	// secondary content (Persona)
+	secondaryLabel, secondaryValue := "", f.secondaryContent
+	if label, value, ok := strings.Cut(f.secondaryContent, ": "); ok {
+		secondaryLabel, secondaryValue = label+": ", value
 	}
 
 	leftContent := lipgloss.JoinHorizontal(
@@ -139,11 +133,8 @@ func (f *Footer) View() string {
 		secondaryStyle.Render(modelLabel),
 		primaryStyle.Render(modelName),
 		"  |  ", // separator
-		secondaryStyle.Render(sysMsgLabel),
-		" ",
-		primaryStyle.Render(sysMsgStatus),
-		" ",
-		indicatorStyle.Render(strings.TrimSpace(sysMsgIndicator)),
+		secondaryStyle.Render(secondaryLabel),
+		primaryStyle.Render(secondaryValue),
 	)
 
 	rightWidth := innerWidth - lipgloss.Width(leftContent)
@@ -151,10 +142,15 @@ func (f *Footer) View() string {
 		rightWidth = 0
 	}
 
+	right := f.toast.View()
+	if right == "" && f.status != "" {
+		right = primaryStyle.MarginRight(1).Render(f.status)
+	}
+
 	rightContent := lipgloss.NewStyle().
 		Width(rightWidth).
 		Align(lipgloss.Right).
-		Render(f.toast.View())
+		Render(right)
 
 	contentRow := lipgloss.JoinHorizontal(lipgloss.Bottom, leftContent, rightContent)
 
@@ -0,0 +1,152 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// FormField describes one input of a FormDialog.
+type FormField struct {
+	Name   string
+	Label  string
+	Value  string
+	Secret bool
+}
+
+// FormDialog types
+
+// FormDialog asks for several values at once. Values is set once the user
+// submits the last field; Cancelled is set if they dismiss the dialog.
+type FormDialog struct {
+	Title string
+
+	fields  []FormField
+	inputs  []textinput.Model
+	focused int
+
+	width  int
+	height int
+
+	Values    map[string]string
+	Cancelled bool
+}
+
+// FormDialog constructor
+func NewFormDialog(title string, fields ...FormField) *FormDialog {
+	inputs := make([]textinput.Model, len(fields))
+	for i, f := range fields {
+		ti := textinput.New()
+		ti.Prompt = "❯ "
+		ti.Width = 50
+		ti.SetValue(f.Value)
+		ti.CursorEnd()
+		if f.Secret {
+			ti.EchoMode = textinput.EchoPassword
+		}
+		inputs[i] = ti
+	}
+	if len(inputs) > 0 {
+		inputs[0].Focus()
+	}
+
+	return &FormDialog{
+		Title:  title,
+		fields: fields,
+		inputs: inputs,
+	}
+}
+
+func (d *FormDialog) SetSize(width int, height int) {
+	d.width = width
+	d.height = height
+}
+
+// Done reports whether the form was submitted or cancelled.
+func (d *FormDialog) Done() bool {
+	return d.Values != nil || d.Cancelled
+}
+
+// FormDialog methods
+// Update
+func (d *FormDialog) Update(msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+
+	case tea.WindowSizeMsg:
+		d.width = msg.Width
+		d.height = msg.Height
+		return nil
+
+	case tea.KeyMsg:
+		switch msg.String() {
+
+		case "esc":
+			d.Cancelled = true
+			return nil
+
+		case "tab", "down":
+			d.focus(d.focused + 1)
+			return nil
+
+		case "shift+tab", "up":
+			d.focus(d.focused - 1)
+			return nil
+
+		case "enter":
+			if d.focused < len(d.inputs)-1 {
+				d.focus(d.focused + 1)
+				return nil
+			}
+			d.Values = make(map[string]string, len(d.fields))
+			for i, f := range d.fields {
+				d.Values[f.Name] = d.inputs[i].Value()
+			}
+			return nil
+		}
+	}
+
+	if len(d.inputs) == 0 {
+		return nil
+	}
+	var cmd tea.Cmd
+	d.inputs[d.focused], cmd = d.inputs[d.focused].Update(msg)
+	return cmd
+}
+
+// View
+
+func (d *FormDialog) View() string {
+	var b strings.Builder
+	for i, f := range d.fields {
+		b.WriteString(dimStyle.Render(f.Label))
+		b.WriteString("\n")
+		b.WriteString(d.inputs[i].View())
+		b.WriteString("\n\n")
+	}
+
+	box := dialogStyle.Render(
+		lipgloss.NewStyle().Bold(true).Render(d.Title) +
+			"\n\n" +
+			b.String() +
+			dimStyle.Render("tab = next field • enter = submit • esc = cancel"),
+	)
+
+	return lipgloss.Place(
+		d.width,
+		d.height,
+		lipgloss.Center,
+		lipgloss.Center,
+		box,
+	)
+}
+
+func (d *FormDialog) focus(i int) {
+	if len(d.inputs) == 0 {
+		return
+	}
+	d.inputs[d.focused].Blur()
+	d.focused = (i + len(d.inputs)) % len(d.inputs)
+	d.inputs[d.focused].Focus()
+}
@@ -1,15 +1,32 @@
 package ui
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
 )
 
+// Tab is an entry of the header's tab bar.
+type Tab struct {
+	Title     string
+	Active    bool
+	Streaming bool
+	// Unseen marks a response that finished in the background.
+	Unseen bool
+}
+
 type Header struct {
 	width int
 	title string
 
+	// Open chat tabs; the tab bar is shown when there is more than one.
+	tabs []Tab
+
+	// Connection indicator: the server host and whether it is reachable.
+	host   string
+	online bool
+
 	// Main header content
 	showMain bool
 
@@ -21,6 +38,9 @@ func (h *Header) Height() int {
 	if !h.showMain {
 		return 1
 	}
+	if h.showTabs() {
+		return 4 // 1 row + tab bar + bottom border
+	}
 	return 3 // 1 row + bottom border
 }
 
@@ -49,6 +69,19 @@ func (h *Header) ShowToast(show bool) {
 	h.showToast = show
 }
 
+func (h *Header) SetTabs(tabs []Tab) {
+	h.tabs = tabs
+}
+
+func (h *Header) SetConnection(host string, online bool) {
+	h.host = host
+	h.online = online
+}
+
+func (h *Header) showTabs() bool {
+	return len(h.tabs) > 1
+}
+
 // Header View
 
 func (h *Header) View() string {
@@ -92,6 +125,8 @@ func (h *Header) View() string {
 	leftBlock := lipgloss.JoinHorizontal(
 		lipgloss.Left,
 		titleStyle.Render(h.title),
+		"  ",
+		h.renderConnection(),
 	)
 
 	rightBlock := lipgloss.JoinHorizontal(
@@ -136,6 +171,10 @@ func (h *Header) View() string {
 		rows = append(rows, mainRow)
 	}
 
+	if h.showMain && h.showTabs() {
+		rows = append(rows, h.renderTabs(innerWidth))
+	}
+
 	if h.showMain && h.showToast {
 		rows = append(rows, divider)
 	}
@@ -147,3 +186,71 @@ func (h *Header) View() string {
 	body := lipgloss.JoinVertical(lipgloss.Top, rows...)
 	return outerBorder.Render(container.Render(body))
 }
+
+// Connection Indicator
+
+var (
+	onlineStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("2"))
+
+	offlineStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("1"))
+
+	hostStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("240"))
+)
+
+func (h *Header) renderConnection() string {
+	if h.host == "" {
+		return ""
+	}
+	if h.online {
+		return onlineStyle.Render("● ") + hostStyle.Render(h.host)
+	}
+	return offlineStyle.Render("○ offline") + hostStyle.Render(" · "+h.host+" · retrying")
+}
+
+// Tab Bar
+
+var (
+	tabStyle = lipgloss.NewStyle().
+			Padding(0, 1).
+			Foreground(lipgloss.Color("245"))
+
+	tabActiveStyle = lipgloss.NewStyle().
+			Padding(0, 1).
+			Bold(true).
+			Background(lipgloss.Color("57")).
+			Foreground(lipgloss.Color("230"))
+
+	tabUnseenStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("2"))
+)
+
+// renderTabs renders the tab bar, shortening titles so all tabs fit.
+func (h *Header) renderTabs(width int) string {
+	// Each tab adds its number, a marker and padding to the title.
+	titleW := max(4, width/len(h.tabs)-10)
+
+	parts := make([]string, len(h.tabs))
+	for i, t := range h.tabs {
+		marker := ""
+		switch {
+		case t.Streaming:
+			marker = " …"
+		case t.Unseen:
+			marker = " " + tabUnseenStyle.Render("●")
+		}
+
+		label := fmt.Sprintf("%d %s", i+1, truncate(t.Title, titleW)) + marker
+		if t.Active {
+			parts[i] = tabActiveStyle.Render(label)
+		} else {
+			parts[i] = tabStyle.Render(label)
+		}
+	}
+
+	return lipgloss.NewStyle().
+		MaxWidth(width).
+		Render(lipgloss.JoinHorizontal(lipgloss.Top, parts...))
+}
@@ -2,11 +2,16 @@ package ui
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/aj-seven/llmverse/internal/clipboard"
 	"github.com/aj-seven/llmverse/internal/history"
+	"github.com/aj-seven/llmverse/pkg/keymap"
 	messages "github.com/aj-seven/llmverse/pkg/messages"
 
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -24,28 +29,70 @@ var (
 
 	dimStyle = lipgloss.NewStyle().
 		Foreground(lipgloss.Color("8"))
+
+	scopeTabStyle = lipgloss.NewStyle().
+		Padding(0, 1).
+		Foreground(lipgloss.Color("245"))
+
+	scopeTabActiveStyle = scopeTabStyle.
+		Bold(true).
+		Foreground(lipgloss.Color("230")).
+		Background(lipgloss.Color("57"))
+
+	tagStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color("6"))
 )
 
+const pinMark = "★ "
+
+var historyScopes = []struct {
+	scope history.Scope
+	label string
+}{
+	{history.ScopeActive, "Chats"},
+	{history.ScopeArchived, "Archive"},
+	{history.ScopeTrashed, "Trash"},
+}
+
 // History Model types
 
 type HistoryModel struct {
-	histories []history.History
+	historyManager *history.Manager
+
+	all       []history.History // everything in the current scope
+	histories []history.History // all, narrowed down by filter
+	scope     int               // index into historyScopes
 	cursor    int
 
+	filter      history.Filter
+	filterInput textinput.Model
+	filtering   bool
+
 	selectedHistoryID string
-	deletedHistoryID  string
 
 	confirm *ConfirmDialog
 
+	// prompt asks for a value such as tags or a folder name; onPrompt
+	// applies it to the selected history.
+	prompt   *InputDialog
+	onPrompt func(value string) tea.Cmd
+
 	width  int
 	height int
 }
 
 // Constructor
-func NewHistoryModel(h []history.History) *HistoryModel {
-	return &HistoryModel{
-		histories: append([]history.History{}, h...),
+func NewHistoryModel(hm *history.Manager) *HistoryModel {
+	fi := textinput.New()
+	fi.Prompt = "/ "
+	fi.Placeholder = "tag:x folder:y model:z host:h since:2006-01-02 until:2006-01-02 words"
+
+	m := &HistoryModel{
+		historyManager: hm,
+		filterInput:    fi,
 	}
+	m.reload()
+	return m
 }
 
 func (m *HistoryModel) SetSize(w, h int) {
@@ -65,18 +112,39 @@ func (m *HistoryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if m.confirm != nil {
 			m.confirm.Update(ws)
 		}
+		if m.prompt != nil {
+			m.prompt.SetSize(ws.Width, ws.Height)
+		}
 		return m, nil
 	}
 
+	if m.prompt != nil {
+		cmd := m.prompt.Update(msg)
+		if !m.prompt.Done() {
+			return m, cmd
+		}
+
+		if m.prompt.Value != nil && len(m.histories) > 0 {
+			cmd = m.onPrompt(*m.prompt.Value)
+		}
+		m.prompt, m.onPrompt = nil, nil
+		return m, cmd
+	}
+
+	if m.filtering {
+		return m, m.updateFilter(msg)
+	}
+
 	if m.confirm != nil {
 		m.confirm.Update(msg)
 
 		if m.confirm.Choice != nil {
+			var cmd tea.Cmd
 			if *m.confirm.Choice && len(m.histories) > 0 {
-				m.deletedHistoryID = m.histories[m.cursor].ID
-				m.deleteSelected()
+				cmd = m.deleteSelected()
 			}
 			m.confirm = nil
+			return m, cmd
 		}
 		return m, nil
 	}
@@ -85,10 +153,59 @@ func (m *HistoryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch k.String() {
 
 		case "esc":
+			if !m.filter.IsZero() {
+				m.setFilter("")
+				return m, nil
+			}
 			return m, func() tea.Msg {
 				return messages.GoBackMsg{}
 			}
 
+		case "/":
+			m.filtering = true
+			return m, m.filterInput.Focus()
+
+		case "p":
+			if m.editable() {
+				return m, m.togglePin()
+			}
+
+		case "r":
+			if m.editable() {
+				h := m.histories[m.cursor]
+				m.askPrompt("Rename chat", "", h.Title,
+					func(v string) tea.Cmd {
+						if strings.TrimSpace(v) == "" {
+							return nil
+						}
+						return m.edit(h.ID, "Chat renamed", func(h *history.History) {
+							h.Title = strings.TrimSpace(v)
+						})
+					})
+			}
+
+		case "t":
+			if m.editable() {
+				h := m.histories[m.cursor]
+				m.askPrompt("Tags", "Comma separated, e.g. work, sql", strings.Join(h.Tags, ", "),
+					func(v string) tea.Cmd {
+						return m.edit(h.ID, "Tags updated", func(h *history.History) {
+							h.Tags = history.ParseTags(v)
+						})
+					})
+			}
+
+		case "f":
+			if m.editable() {
+				h := m.histories[m.cursor]
+				m.askPrompt("Folder", "Leave empty to remove from folder", h.Folder,
+					func(v string) tea.Cmd {
+						return m.edit(h.ID, "Folder updated", func(h *history.History) {
+							h.Folder = strings.TrimSpace(v)
+						})
+					})
+			}
+
 		case "up", "k":
 			if m.cursor > 0 {
 				m.cursor--
@@ -99,23 +216,67 @@ func (m *HistoryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.cursor++
 			}
 
+		case "tab":
+			m.scope = (m.scope + 1) % len(historyScopes)
+			m.cursor = 0
+			m.reload()
+
+		case "shift+tab":
+			m.scope = (m.scope + len(historyScopes) - 1) % len(historyScopes)
+			m.cursor = 0
+			m.reload()
+
 		case "enter":
-			if len(m.histories) > 0 {
-				m.selectedHistoryID = m.histories[m.cursor].ID
-				return m, func() tea.Msg {
-					return messages.GoBackMsg{}
-				}
+			if len(m.histories) == 0 {
+				break
 			}
+			h := m.histories[m.cursor]
+
+			if m.currentScope() == history.ScopeTrashed {
+				return m, m.restoreSelected()
+			}
+			if m.currentScope() == history.ScopeArchived {
+				_ = m.historyManager.RestoreHistory(h.ID)
+			}
+
+			m.selectedHistoryID = h.ID
+			return m, func() tea.Msg {
+				return messages.GoBackMsg{}
+			}
+
+		case "a":
+			if len(m.histories) == 0 {
+				break
+			}
+			switch m.currentScope() {
+			case history.ScopeActive:
+				return m, m.archiveSelected()
+			case history.ScopeArchived:
+				return m, m.restoreSelected()
+			}
+
+		case "y":
+			if len(m.histories) == 0 {
+				break
+			}
+			id := m.histories[m.cursor].ID
+			if err := clipboard.Write(id); err != nil {
+				return m, ShowToast("Copy failed: "+err.Error(), 3*time.Second)
+			}
+			return m, ShowToast("Copied ID (llmv --resume "+id[:min(8, len(id))]+")", 3*time.Second)
 
 		case "ctrl+d":
-			if len(m.histories) > 0 {
-				m.confirm = NewConfirmDialog(
-					"Delete chat?",
-					"This action cannot be undone.",
-				)
-				m.confirm.width = m.width
-				m.confirm.height = m.height
+			if len(m.histories) == 0 {
+				break
+			}
+			if m.currentScope() != history.ScopeTrashed {
+				return m, m.trashSelected()
 			}
+			m.confirm = NewConfirmDialog(
+				"Delete chat forever?",
+				"This action cannot be undone.",
+			)
+			m.confirm.SetSize(m.width, m.height)
 		}
 	}
 
@@ -127,13 +288,18 @@ func (m *HistoryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m *HistoryModel) View() string {
 	var b strings.Builder
 
+	b.WriteString(m.renderScopeTabs())
+	b.WriteString("\n")
+	b.WriteString(m.renderFilterLine())
+	b.WriteString("\n")
+
 	if len(m.histories) == 0 {
-		b.WriteString(dimStyle.Render("No saved chats."))
+		b.WriteString(dimStyle.Render(" " + m.emptyText()))
 	} else {
 		// Header
 		b.WriteString(m.renderHeader())
 		b.WriteString("\n")
-		availableHeight := m.height
+		availableHeight := m.height - 2 // scope tabs
 		maxRows := max(1, availableHeight-4)
 
 		start := max(0, m.cursor-maxRows+1)
@@ -152,6 +318,10 @@ func (m *HistoryModel) View() string {
 
 	view := b.String()
 
+	if m.prompt != nil {
+		return m.prompt.View()
+	}
+
 	if m.confirm != nil {
 		confirmView := m.confirm.View()
 		return lipgloss.Place(
@@ -170,18 +340,20 @@ func (m *HistoryModel) View() string {
 func (m *HistoryModel) renderHeader() string {
 	indexW := 4
 	modelW := 12
+	folderW := 12
 	dateW := 18
-	sepW := 3 * 3
+	sepW := 3 * 4
 
-	used := indexW + modelW + dateW + sepW
+	used := indexW + modelW + folderW + dateW + sepW
 	titleW := max(10, m.width-used)
 
 	return lipgloss.NewStyle().
 		Bold(true).
 		Render(fmt.Sprintf(
-			" %*s │ %-*s │ %-*s │ %-*s",
+			" %*s │ %-*s │ %-*s │ %-*s │ %-*s",
 			indexW-1, "SNO",
 			modelW, "MODEL",
+			folderW, "FOLDER",
 			dateW, "DATE",
 			titleW, "TITLE",
 		))
@@ -190,26 +362,48 @@ func (m *HistoryModel) renderHeader() string {
 func (m *HistoryModel) renderRow(i int, h history.History) string {
 	indexW := 4
 	modelW := 12
+	folderW := 12
 	dateW := 18
-	sepW := 3 * 3
+	sepW := 3 * 4
 
-	used := indexW + modelW + dateW + sepW
+	used := indexW + modelW + folderW + dateW + sepW
 	titleW := max(10, m.width-used)
 
 	title := deriveTitle(h)
+	if h.Pinned {
+		title = pinMark + title
+	}
+	for _, t := range h.Tags {
+		title += " #" + t
+	}
+	if n := countAttachments(h); n > 0 {
+		title += fmt.Sprintf(" 📎%d", n)
+	}
 	title = truncate(title, titleW)
 
 	date := h.UpdatedAt.Format("2006-01-02 15:04")
+	if h.TrashedAt != nil {
+		date = h.TrashedAt.Format("2006-01-02 15:04")
+	}
 
 	return fmt.Sprintf(
-		"%*d │ %-*s │ %-*s │ %-*s",
+		"%*d │ %-*s │ %-*s │ %-*s │ %-*s",
 		indexW-1, i+1,
-		modelW, h.Model,
+		modelW, truncate(h.Model, modelW),
+		folderW, truncate(h.Folder, folderW),
 		dateW, date,
 		titleW, title,
 	)
 }
 
+func countAttachments(h history.History) int {
+	n := 0
+	for _, msg := range h.Messages {
+		n += len(msg.Attachments)
+	}
+	return n
+}
+
 func deriveTitle(h history.History) string {
 	if strings.TrimSpace(h.Title) != "" {
 		return truncate(h.Title, 50)
@@ -219,30 +413,184 @@ func deriveTitle(h history.History) string {
 		return truncate(h.Messages[0].Content, 50)
 	}
 
+	if h.Draft != "" {
+		return truncate("Draft: "+h.Draft, 50)
+	}
+
 	return "Empty Chat"
 }
 
-func (m *HistoryModel) deleteSelected() {
-	if len(m.histories) == 0 {
-		return
+func (m *HistoryModel) renderScopeTabs() string {
+	tabs := make([]string, 0, len(historyScopes))
+	for i, sc := range historyScopes {
+		if i == m.scope {
+			tabs = append(tabs, scopeTabActiveStyle.Render(sc.label))
+		} else {
+			tabs = append(tabs, scopeTabStyle.Render(sc.label))
+		}
 	}
+	return " " + lipgloss.JoinHorizontal(lipgloss.Top, tabs...)
+}
 
-	m.histories = append(
-		m.histories[:m.cursor],
-		m.histories[m.cursor+1:]...,
-	)
+func (m *HistoryModel) emptyText() string {
+	switch m.currentScope() {
+	case history.ScopeArchived:
+		return "No archived chats."
+	case history.ScopeTrashed:
+		return "Trash is empty."
+	default:
+		return "No saved chats."
+	}
+}
+
+// editable reports whether the selected chat may be renamed, pinned,
+// tagged or moved to a folder. Trashed chats can only be restored or
+// deleted.
+func (m *HistoryModel) editable() bool {
+	return len(m.histories) > 0 && m.currentScope() != history.ScopeTrashed
+}
+
+func (m *HistoryModel) currentScope() history.Scope {
+	return historyScopes[m.scope].scope
+}
+
+// reload refreshes the list for the current scope. Pinned chats sort to
+// the top, otherwise the storage order (most recent first) is kept.
+func (m *HistoryModel) reload() {
+	all, _ := m.historyManager.GetHistories(m.currentScope())
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].Pinned && !all[j].Pinned
+	})
+	m.all = all
+	m.applyFilter()
+}
+
+func (m *HistoryModel) applyFilter() {
+	m.histories = m.histories[:0]
+	for _, h := range m.all {
+		if m.filter.Match(h) {
+			m.histories = append(m.histories, h)
+		}
+	}
+
+	if m.cursor >= len(m.histories) {
+		m.cursor = max(0, len(m.histories)-1)
+	}
+}
+
+func (m *HistoryModel) setFilter(query string) {
+	m.filterInput.SetValue(query)
+	m.filter = history.ParseFilter(query)
+	m.applyFilter()
+}
+
+func (m *HistoryModel) updateFilter(msg tea.Msg) tea.Cmd {
+	if k, ok := msg.(tea.KeyMsg); ok {
+		switch k.String() {
+		case "enter", "up", "down":
+			m.filtering = false
+			m.filterInput.Blur()
+			return nil
+		case "esc":
+			m.filtering = false
+			m.filterInput.Blur()
+			m.setFilter("")
+			return nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.filterInput, cmd = m.filterInput.Update(msg)
+	m.filter = history.ParseFilter(m.filterInput.Value())
+	m.applyFilter()
+	return cmd
+}
+
+func (m *HistoryModel) renderFilterLine() string {
+	if m.filtering {
+		return " " + m.filterInput.View()
+	}
+	if m.filter.IsZero() {
+		return ""
+	}
+	return dimStyle.Render(fmt.Sprintf(
+		" / %s  (%d of %d)",
+		m.filterInput.Value(), len(m.histories), len(m.all),
+	))
+}
 
-	if m.cursor >= len(m.histories) && m.cursor > 0 {
-		m.cursor--
+func (m *HistoryModel) askPrompt(title, msg, value string, onPrompt func(string) tea.Cmd) {
+	m.prompt = NewInputDialog(title, msg, value)
+	m.prompt.SetSize(m.width, m.height)
+	m.onPrompt = onPrompt
+}
+
+func (m *HistoryModel) edit(id, done string, fn func(*history.History)) tea.Cmd {
+	if err := m.historyManager.EditHistory(id, fn); err != nil {
+		return ShowToast("Update failed: "+err.Error(), 3*time.Second)
+	}
+	m.reload()
+	return ShowToast(done, 2*time.Second)
+}
+
+func (m *HistoryModel) togglePin() tea.Cmd {
+	h := m.histories[m.cursor]
+	done := "Chat pinned"
+	if h.Pinned {
+		done = "Chat unpinned"
+	}
+	cmd := m.edit(h.ID, done, func(h *history.History) {
+		h.Pinned = !h.Pinned
+	})
+
+	// Keep the cursor on the chat that moved.
+	for i, x := range m.histories {
+		if x.ID == h.ID {
+			m.cursor = i
+		}
 	}
+	return cmd
+}
+
+func (m *HistoryModel) trashSelected() tea.Cmd {
+	if err := m.historyManager.TrashHistory(m.histories[m.cursor].ID); err != nil {
+		return ShowToast("Delete failed: "+err.Error(), 3*time.Second)
+	}
+	m.reload()
+	return ShowToast("Moved to trash", 2*time.Second)
+}
+
+func (m *HistoryModel) archiveSelected() tea.Cmd {
+	if err := m.historyManager.ArchiveHistory(m.histories[m.cursor].ID); err != nil {
+		return ShowToast("Archive failed: "+err.Error(), 3*time.Second)
+	}
+	m.reload()
+	return ShowToast("Chat archived", 2*time.Second)
+}
+
+func (m *HistoryModel) restoreSelected() tea.Cmd {
+	if err := m.historyManager.RestoreHistory(m.histories[m.cursor].ID); err != nil {
+		return ShowToast("Restore failed: "+err.Error(), 3*time.Second)
+	}
+	m.reload()
+	return ShowToast("Chat restored", 2*time.Second)
+}
+
+func (m *HistoryModel) deleteSelected() tea.Cmd {
+	if err := m.historyManager.DeleteHistory(m.histories[m.cursor].ID); err != nil {
+		return ShowToast("Delete failed: "+err.Error(), 3*time.Second)
+	}
+	m.reload()
+	return ShowToast("Chat deleted", 2*time.Second)
 }
 
 func truncate(s string, max int) string {
 	s = strings.TrimSpace(strings.ReplaceAll(s, "\n", " "))
-	if len(s) <= max {
+	runes := []rune(s)
+	if len(runes) <= max {
 		return s
 	}
-	return s[:max] + "..."
+	return string(runes[:max]) + "..."
 }
 
 // Public API
@@ -253,8 +601,55 @@ func (m *HistoryModel) SelectedHistoryID() string {
 	return id
 }
 
-func (m *HistoryModel) DeletedID() string {
-	id := m.deletedHistoryID
-	m.deletedHistoryID = ""
-	return id
+// Title returns the header title for the current scope.
+func (m *HistoryModel) Title() string {
+	if m.scope == 0 {
+		return "Chat History"
+	}
+	return "Chat History - " + historyScopes[m.scope].label
+}
+
+// Shortcuts returns the footer shortcuts for the current scope.
+func (m *HistoryModel) Shortcuts() []keymap.Shortcut {
+	switch m.currentScope() {
+	case history.ScopeArchived:
+		return []keymap.Shortcut{
+			{Key: "enter", Action: "Open"},
+			{Key: "r", Action: "Rename"},
+			{Key: "p", Action: "Pin"},
+			{Key: "t", Action: "Tags"},
+			{Key: "f", Action: "Folder"},
+			{Key: "a", Action: "Unarchive"},
+			{Key: "ctrl+d", Action: "Trash"},
+			{Key: "tab", Action: "Trash View"},
+			{Key: "esc", Action: "Back"},
+		}
+	case history.ScopeTrashed:
+		return []keymap.Shortcut{
+			{Key: "enter", Action: "Restore"},
+			{Key: "ctrl+d", Action: "Delete Forever"},
+			{Key: "tab", Action: "Chats"},
+			{Key: "esc", Action: "Back"},
+		}
+	default:
+		if m.filtering {
+			return []keymap.Shortcut{
+				{Key: "enter", Action: "Apply Filter"},
+				{Key: "esc", Action: "Clear Filter"},
+			}
+		}
+		return []keymap.Shortcut{
+			{Key: "enter", Action: "Open"},
+			{Key: "/", Action: "Filter"},
+			{Key: "r", Action: "Rename"},
+			{Key: "p", Action: "Pin"},
+			{Key: "t", Action: "Tags"},
+			{Key: "f", Action: "Folder"},
+			{Key: "a", Action: "Archive"},
+			{Key: "y", Action: "Copy ID"},
+			{Key: "ctrl+d", Action: "Trash"},
+			{Key: "tab", Action: "Archive View"},
+			{Key: "esc", Action: "Back"},
+		}
+	}
 }
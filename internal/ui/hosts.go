@@ -0,0 +1,198 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aj-seven/llmverse/internal/config"
+	aihub "github.com/aj-seven/llmverse/internal/providers/ollama"
+	"github.com/aj-seven/llmverse/pkg/keymap"
+	messages "github.com/aj-seven/llmverse/pkg/messages"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Messages
+
+// HostSelectedMsg makes the named endpoint the host of new chats.
+type HostSelectedMsg struct {
+	Name string
+}
+
+// openHostsMsg asks the root model to open the host switcher.
+type openHostsMsg struct{}
+
+// Hosts Model
+
+// HostsModel lists the configured Ollama endpoints with whether they are
+// reachable and how many models they serve, and switches between them.
+type HostsModel struct {
+	endpoints []config.Endpoint
+	online    map[string]bool
+	errs      map[string]error
+	counts    map[string]int
+	current   string
+	cursor    int
+
+	width  int
+	height int
+}
+
+// Constructor
+func NewHostsModel(
+	cfg *config.Config,
+	current string,
+	online map[string]bool,
+	errs map[string]error,
+	models []aihub.OllamaModel,
+) *HostsModel {
+	m := &HostsModel{current: current}
+	if cfg != nil {
+		m.endpoints = cfg.Endpoints()
+	}
+	for i, e := range m.endpoints {
+		if e.Name == current {
+			m.cursor = i
+		}
+	}
+	m.setStatus(online, errs, models)
+	return m
+}
+
+func (m *HostsModel) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+}
+
+func (m *HostsModel) Init() tea.Cmd { return nil }
+
+// setStatus updates the reachability, errors and model counts of the
+// hosts.
+func (m *HostsModel) setStatus(online map[string]bool, errs map[string]error, models []aihub.OllamaModel) {
+	m.online, m.errs = online, errs
+	m.counts = map[string]int{}
+	for _, model := range models {
+		m.counts[model.Host]++
+	}
+}
+
+// Update
+
+func (m *HostsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	k, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	switch k.String() {
+	case "esc":
+		return m, func() tea.Msg {
+			return messages.GoBackMsg{}
+		}
+
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+
+	case "down", "j":
+		if m.cursor < len(m.endpoints)-1 {
+			m.cursor++
+		}
+
+	case "enter":
+		if len(m.endpoints) > 0 {
+			name := m.endpoints[m.cursor].Name
+			return m, func() tea.Msg { return HostSelectedMsg{Name: name} }
+		}
+	}
+
+	return m, nil
+}
+
+// View
+
+func (m *HostsModel) View() string {
+	var b strings.Builder
+
+	b.WriteString(m.renderHeader())
+	b.WriteString("\n")
+	for i, e := range m.endpoints {
+		row := m.renderRow(e)
+		if i == m.cursor {
+			b.WriteString(selectedRowStyle.Render(row))
+		} else {
+			b.WriteString(rowStyle.Render(row))
+		}
+		b.WriteString("\n")
+	}
+
+	if len(m.endpoints) > 0 {
+		if err := m.errs[m.endpoints[m.cursor].Name]; err != nil {
+			b.WriteString("\n")
+			b.WriteString(dimStyle.Render(" " + truncate(err.Error(), max(m.width-2, 10))))
+			b.WriteString("\n")
+		}
+	}
+
+	if len(m.endpoints) < 2 {
+		b.WriteString("\n")
+		b.WriteString(dimStyle.Render(" Add more servers under hosts: in the config file, e.g.\n   - name: gpu\n     url: http://10.0.0.5:11434"))
+	}
+
+	return b.String()
+}
+
+// Helpers
+
+func (m *HostsModel) columns() (nameW, statusW, modelsW, urlW int) {
+	nameW, statusW, modelsW = 16, 9, 6
+	urlW = max(10, m.width-nameW-statusW-modelsW-3*4)
+	return
+}
+
+func (m *HostsModel) renderHeader() string {
+	nameW, statusW, modelsW, urlW := m.columns()
+	return lipgloss.NewStyle().
+		Bold(true).
+		Render(fmt.Sprintf(
+			" %-*s │ %-*s │ %*s │ %-*s",
+			nameW, "NAME",
+			statusW, "STATUS",
+			modelsW, "MODELS",
+			urlW, "URL",
+		))
+}
+
+func (m *HostsModel) renderRow(e config.Endpoint) string {
+	nameW, statusW, modelsW, urlW := m.columns()
+
+	name := e.Name
+	if name == m.current {
+		name = "● " + name
+	}
+	status, models := "offline", "-"
+	if m.online[e.Name] {
+		status, models = "online", fmt.Sprint(m.counts[e.Name])
+	}
+
+	return fmt.Sprintf(
+		"%-*s │ %-*s │ %*s │ %-*s",
+		nameW, truncate(name, nameW),
+		statusW, status,
+		modelsW, models,
+		urlW, truncate(e.URL, urlW),
+	)
+}
+
+// Public API
+
+// Shortcuts returns the footer shortcuts of the view.
+func (m *HostsModel) Shortcuts() []keymap.Shortcut {
+	return []keymap.Shortcut{
+		{Key: "↑/↓", Action: "Navigate"},
+		{Key: "enter", Action: "Switch"},
+		{Key: "esc", Action: "Back"},
+	}
+}
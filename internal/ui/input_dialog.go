@@ -0,0 +1,105 @@
+package ui
+
+import (
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// InputDialog types
+
+// InputDialog asks the user for a single line of text. Value is set once
+// the user submits; Cancelled is set if they dismiss the dialog instead.
+type InputDialog struct {
+	Title   string
+	Message string
+
+	input textinput.Model
+
+	width  int
+	height int
+
+	Value     *string
+	Cancelled bool
+}
+
+// InputDialog constructor
+func NewInputDialog(title, msg, value string) *InputDialog {
+	ti := textinput.New()
+	ti.Prompt = "❯ "
+	ti.Width = 40
+	ti.SetValue(value)
+	ti.CursorEnd()
+	ti.Focus()
+
+	return &InputDialog{
+		Title:   title,
+		Message: msg,
+		input:   ti,
+	}
+}
+
+func (d *InputDialog) SetSize(width int, height int) {
+	d.width = width
+	d.height = height
+}
+
+// Done reports whether the dialog was submitted or cancelled.
+func (d *InputDialog) Done() bool {
+	return d.Value != nil || d.Cancelled
+}
+
+// InputDialog methods
+// Update
+func (d *InputDialog) Update(msg tea.Msg) tea.Cmd {
+	switch msg := msg.(type) {
+
+	case tea.WindowSizeMsg:
+		d.width = msg.Width
+		d.height = msg.Height
+		return nil
+
+	case tea.KeyMsg:
+		switch msg.String() {
+
+		case "enter":
+			v := d.input.Value()
+			d.Value = &v
+			return nil
+
+		case "esc":
+			d.Cancelled = true
+			return nil
+		}
+	}
+
+	var cmd tea.Cmd
+	d.input, cmd = d.input.Update(msg)
+	return cmd
+}
+
+// View
+
+func (d *InputDialog) View() string {
+	body := ""
+	if d.Message != "" {
+		body = d.Message + "\n\n"
+	}
+
+	box := dialogStyle.Render(
+		lipgloss.NewStyle().Bold(true).Render(d.Title) +
+			"\n\n" +
+			body +
+			d.input.View() +
+			"\n\n" +
+			dimStyle.Render("enter = save • esc = cancel"),
+	)
+
+	return lipgloss.Place(
+		d.width,
+		d.height,
+		lipgloss.Center,
+		lipgloss.Center,
+		box,
+	)
+}
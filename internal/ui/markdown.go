@@ -0,0 +1,41 @@
+package ui
+
+import (
+	"github.com/charmbracelet/glamour"
+)
+
+const defaultMarkdownStyle = "dark"
+
+// markdownRenderer renders markdown with one glamour renderer, which is
+// only rebuilt when the wrap width or the style changes. Building a
+// renderer parses the whole style sheet, so doing it per message is slow.
+type markdownRenderer struct {
+	renderer *glamour.TermRenderer
+	width    int
+	style    string
+}
+
+// Render renders content wrapped at width in the given glamour style. An
+// empty style uses the dark one.
+func (r *markdownRenderer) Render(content, style string, width int) (string, error) {
+	if style == "" {
+		style = defaultMarkdownStyle
+	}
+	width = max(10, width)
+
+	if r.renderer == nil || r.width != width || r.style != style {
+		tr, err := glamour.NewTermRenderer(
+			glamour.WithStandardStyle(style),
+			glamour.WithWordWrap(width),
+		)
+		if err != nil {
+			tr, err = glamour.NewTermRenderer(glamour.WithWordWrap(width))
+			if err != nil {
+				return "", err
+			}
+		}
+		r.renderer, r.width, r.style = tr, width, style
+	}
+
+	return r.renderer.Render(content)
+}
@@ -1,10 +1,14 @@
 package ui
 
 import (
+	"github.com/aj-seven/llmverse/pkg/keymap"
 	"github.com/aj-seven/llmverse/pkg/messages"
 	aihub "github.com/aj-seven/llmverse/internal/providers/ollama"
+	"github.com/aj-seven/llmverse/internal/state"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -42,25 +46,105 @@ var (
 
 	infoValueStyle = lipgloss.NewStyle().
 		Foreground(lipgloss.Color("230"))
+
+	modelGroupStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("6"))
+
+	modelFavouriteStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color("3"))
 )
 
+
 // Messages
 
 type ModelSelectedMsg aihub.OllamaModel
 type ModelSelectionBackMsg struct{}
 
+// Sorting
+
+type modelSort int
+
+const (
+	sortByName modelSort = iota
+	sortBySize
+	sortByModified
+	sortByFamily
+	sortByRecent
+)
+
+var modelSortNames = []string{"name", "size", "modified", "family", "recent"}
+
+// Grouping
+
+type modelGroup int
+
+const (
+	groupNone modelGroup = iota
+	groupByFamily
+	groupByHost
+)
+
 // Model
 
 type ModelSelection struct {
 	models []aihub.OllamaModel
+
+	// rows is the filtered and sorted list, with group headers when
+	// grouped; cursor indexes it and always points at a model.
+	rows   []modelRow
 	cursor int
 
+	// marked holds the keys of the models picked for comparison, in
+	// marking order.
+	marked []string
+
+	filter  string
+	sortBy  modelSort
+	groupBy modelGroup
+
+	// multiHost is set when the models come from more than one host,
+	// which adds a host column.
+	multiHost bool
+
+	// favourites and recent (rank of last use, 0 being the latest) come
+	// from the persisted state.
+	state      *state.Store
+	favourites map[string]bool
+	recent     map[string]int
+
 	width  int
 	height int
 }
 
-func NewModelSelection(models []aihub.OllamaModel) *ModelSelection {
-	return &ModelSelection{models: models}
+// modelRow is a model in the list, or a group header if group is set.
+type modelRow struct {
+	group string
+	model aihub.OllamaModel
+	score int
+}
+
+// NewModelSelection creates the model picker with the cursor on current
+// of the given host, or on the last used model.
+func NewModelSelection(models []aihub.OllamaModel, current, host string, st *state.Store) *ModelSelection {
+	if st == nil {
+		st = state.NewMemory()
+	}
+	m := &ModelSelection{models: models, state: st}
+	m.loadState()
+	for _, model := range models {
+		if model.Host != models[0].Host {
+			m.multiHost = true
+			break
+		}
+	}
+
+	if recent := st.Get().RecentModels; current == "" && len(recent) > 0 {
+		current = recent[0]
+	}
+	m.refresh()
+	m.selectModel(current, host)
+	return m
 }
 
 func (m *ModelSelection) Init() tea.Cmd { return nil }
@@ -82,28 +166,84 @@ func (m *ModelSelection) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.String() {
 
-		case "up", "k":
-			if m.cursor > 0 {
-				m.cursor--
-			}
+		case "up":
+			m.moveCursor(-1)
 
-		case "down", "j":
-			if m.cursor < len(m.models)-1 {
-				m.cursor++
-			}
+		case "down":
+			m.moveCursor(1)
+
+		case "pgup":
+			m.moveCursor(-m.visibleRows())
+
+		case "pgdown":
+			m.moveCursor(m.visibleRows())
 
 		case "esc":
+			if m.filter != "" {
+				m.setFilter("")
+				return m, nil
+			}
 			return m, func() tea.Msg {
 				return messages.GoBackMsg{}
 			}
 
+		case "backspace":
+			if runes := []rune(m.filter); len(runes) > 0 {
+				m.setFilter(string(runes[:len(runes)-1]))
+			}
+
+		case " ", "space":
+			if model, ok := m.selected(); ok {
+				m.toggleMark(modelKey(model))
+			}
+
+		case "alt+c":
+			if len(m.marked) < 2 {
+				return m, ShowToast("Mark at least two models with space", 2*time.Second)
+			}
+			var models []aihub.OllamaModel
+			for _, key := range m.marked {
+				for _, model := range m.models {
+					if modelKey(model) == key {
+						models = append(models, model)
+					}
+				}
+			}
+			return m, func() tea.Msg {
+				return CompareModelsMsg{Models: models}
+			}
+
+		case "alt+f":
+			if model, ok := m.selected(); ok {
+				return m, m.toggleFavourite(model.Name)
+			}
+
+		case "alt+s":
+			m.sortBy = (m.sortBy + 1) % modelSort(len(modelSortNames))
+			m.refresh()
+
+		case "alt+g":
+			m.groupBy = (m.groupBy + 1) % 3
+			if m.groupBy == groupByHost && !m.multiHost {
+				m.groupBy = groupNone
+			}
+			m.refresh()
+
+		case "alt+h":
+			return m, func() tea.Msg { return openHostsMsg{} }
+
 		case "enter":
-			if len(m.models) > 0 {
-				selected := m.models[m.cursor]
+			if selected, ok := m.selected(); ok {
 				return m, func() tea.Msg {
 					return ModelSelectedMsg(selected)
 				}
 			}
+
+		default:
+			// Anything typed filters the list.
+			if msg.Type == tea.KeyRunes && !msg.Alt {
+				m.setFilter(m.filter + string(msg.Runes))
+			}
 		}
 	}
 
@@ -133,42 +273,83 @@ func (m *ModelSelection) View() string {
 	)
 }
 
-const visibleRows = 5
+// Column widths of the list, besides the name.
+const (
+	modelHostW     = 10
+	modelFamilyW   = 10
+	modelParamsW   = 7
+	modelQuantW    = 8
+	modelSizeW     = 6
+	modelModifiedW = 10
+)
 
 // List Rendering
 
-func (m *ModelSelection) renderList(_ int) string {
+func (m *ModelSelection) renderList(height int) string {
 	var b strings.Builder
 
-	total := len(m.models)
-	if total == 0 {
+	b.WriteString(m.renderFilterLine())
+	b.WriteString("\n")
+
+	if len(m.models) == 0 {
 		b.WriteString(modelDimStyle.Render(" No models found"))
 		return b.String()
 	}
-	
+	if len(m.rows) == 0 {
+		b.WriteString(modelDimStyle.Render(" No models match the filter"))
+		return b.String()
+	}
+
 	// Header
 	b.WriteString(m.renderHeader())
 	b.WriteString("\n")
 
-	nameW := max(12, m.width-2)
+	nameW := m.nameWidth()
 
-	// Fixed 5-row scroll window
-	start := m.cursor - visibleRows/2
-	if start < 0 {
-		start = 0
+	// Scroll window around the cursor
+	rows := m.visibleRows()
+	if height > 0 {
+		rows = max(1, height-2)
 	}
-	if start+visibleRows > total {
-		start = max(0, total-visibleRows)
+	start := max(0, m.cursor-rows/2)
+	if start+rows > len(m.rows) {
+		start = max(0, len(m.rows)-rows)
 	}
-	end := min(total, start+visibleRows)
+	end := min(len(m.rows), start+rows)
 
 	for i := start; i < end; i++ {
-		model := m.models[i]
+		r := m.rows[i]
+		if r.group != "" {
+			b.WriteString(modelGroupStyle.Render(" " + r.group))
+			b.WriteString("\n")
+			continue
+		}
+		model := r.model
+
+		mark := "  "
+		if m.isMarked(modelKey(model)) {
+			mark = "◉ "
+		}
+		fav := "  "
+		if m.favourites[model.Name] {
+			fav = "★ "
+		}
+
+		name := fmt.Sprintf("%-*s ", nameW, trim(model.Name, nameW))
+		if m.multiHost {
+			name += fmt.Sprintf("%-*s ", modelHostW, trim(model.Host, modelHostW))
+		}
 
 		row := fmt.Sprintf(
-			" %-*s",
-			nameW,
-			trim(model.Name, nameW),
+			" %s%s%s%-*s %-*s %-*s %*s  %-*s",
+			mark,
+			fav,
+			name,
+			modelFamilyW, trim(model.Details.Family, modelFamilyW),
+			modelParamsW, trim(model.Details.ParameterSize, modelParamsW),
+			modelQuantW, trim(model.Details.QuantizationLevel, modelQuantW),
+			modelSizeW, formatSize(model.Size),
+			modelModifiedW, model.ModifiedAt.Format("2006-01-02"),
 		)
 
 		if i == m.cursor {
@@ -178,6 +359,9 @@ func (m *ModelSelection) renderList(_ int) string {
 					Render(row),
 			)
 		} else {
+			if m.favourites[model.Name] {
+				row = strings.Replace(row, "★", modelFavouriteStyle.Render("★"), 1)
+			}
 			b.WriteString(
 				modelRowStyle.
 					Width(m.width).
@@ -191,26 +375,52 @@ func (m *ModelSelection) renderList(_ int) string {
 }
 
 func (m *ModelSelection) renderHeader() string {
-	nameW := max(12, m.width-2)
+	name := fmt.Sprintf("%-*s ", m.nameWidth(), "NAME")
+	if m.multiHost {
+		name += fmt.Sprintf("%-*s ", modelHostW, "HOST")
+	}
 
 	return lipgloss.NewStyle().
 		Bold(true).
 		Render(fmt.Sprintf(
-			" %-*s",
-			nameW,
-			"NAME",
+			"      %s%-*s %-*s %-*s %*s  %-*s",
+			name,
+			modelFamilyW, "FAMILY",
+			modelParamsW, "PARAMS",
+			modelQuantW, "QUANT",
+			modelSizeW, "SIZE",
+			modelModifiedW, "MODIFIED",
 		))
 }
 
+func (m *ModelSelection) renderFilterLine() string {
+	view := fmt.Sprintf("Sort: %s", modelSortNames[m.sortBy])
+	switch m.groupBy {
+	case groupByFamily:
+		view += " · grouped by family"
+	case groupByHost:
+		view += " · grouped by host"
+	}
+
+	if m.filter == "" {
+		hint := " Type to filter"
+		if m.multiHost {
+			hint += " (@host for a host)"
+		}
+		return modelDimStyle.Render(fmt.Sprintf("%s · %s", hint, view))
+	}
+	return " " + searchPromptStyle.Render("/ ") + m.filter + "▏" +
+		modelDimStyle.Render(fmt.Sprintf("  (%d of %d) · %s", m.count(), len(m.models), view))
+}
+
 // Info Box Rendering
 
 func (m *ModelSelection) renderInfoBox(height int) string {
-	if m.cursor < 0 || m.cursor >= len(m.models) {
+	model, ok := m.selected()
+	if !ok {
 		return ""
 	}
 
-	model := m.models[m.cursor]
-
 	body := fmt.Sprintf(
 		"%s %s\n%s %s\n%s %s\n%s %s\n%s %s",
 		infoLabelStyle.Render("Family:"),
@@ -229,9 +439,14 @@ func (m *ModelSelection) renderInfoBox(height int) string {
 		infoValueStyle.Render(model.ModifiedAt.Format("2006-01-02 15:04")),
 	)
 
+	title := "Model Info"
+	if m.multiHost {
+		title += " · " + model.Host
+	}
+
 	content := lipgloss.JoinVertical(
 		lipgloss.Left,
-		infoTitleStyle.Render("Model Info"),
+		infoTitleStyle.Render(title),
 		"",
 		body,
 	)
@@ -244,6 +459,305 @@ func (m *ModelSelection) renderInfoBox(height int) string {
 
 // Helpers
 
+func (m *ModelSelection) nameWidth() int {
+	fixed := 5 + modelFamilyW + modelParamsW + modelQuantW + modelSizeW + modelModifiedW + 7
+	if m.multiHost {
+		fixed += modelHostW + 1
+	}
+	return max(12, m.width-fixed-2)
+}
+
+// visibleRows is the default page size of the list.
+func (m *ModelSelection) visibleRows() int {
+	return max(5, m.height-9-1-2)
+}
+
+func (m *ModelSelection) setFilter(filter string) {
+	m.filter = filter
+	m.refresh()
+	if m.filter != "" {
+		// Put the cursor on the best match.
+		m.cursor = 0
+		m.moveCursor(0)
+	}
+}
+
+// refresh rebuilds the rows from the models, keeping the cursor on the
+// same model if it is still listed.
+func (m *ModelSelection) refresh() {
+	current, _ := m.selected()
+
+	// "@gpu" in the filter narrows the list down to hosts named like gpu.
+	filter, host, _ := strings.Cut(m.filter, "@")
+	host = strings.ToLower(strings.TrimSpace(host))
+
+	var rows []modelRow
+	for _, model := range m.models {
+		if host != "" && !strings.Contains(strings.ToLower(model.Host), host) {
+			continue
+		}
+		score, ok := fuzzyModelScore(model.Name, strings.TrimSpace(filter))
+		if ok {
+			rows = append(rows, modelRow{model: model, score: score})
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return m.less(rows[i], rows[j])
+	})
+
+	if m.groupBy != groupNone {
+		rows = m.group(rows)
+	}
+
+	m.rows = rows
+	m.cursor = 0
+	m.selectModel(current.Name, current.Host)
+}
+
+// less orders favourites first, then the best matches of the filter,
+// then by the chosen sort.
+func (m *ModelSelection) less(a, b modelRow) bool {
+	if fa, fb := m.favourites[a.model.Name], m.favourites[b.model.Name]; fa != fb {
+		return fa
+	}
+	if a.score != b.score {
+		return a.score > b.score
+	}
+
+	switch m.sortBy {
+	case sortBySize:
+		if a.model.Size != b.model.Size {
+			return a.model.Size > b.model.Size
+		}
+	case sortByModified:
+		if !a.model.ModifiedAt.Equal(b.model.ModifiedAt) {
+			return a.model.ModifiedAt.After(b.model.ModifiedAt)
+		}
+	case sortByFamily:
+		if a.model.Details.Family != b.model.Details.Family {
+			return a.model.Details.Family < b.model.Details.Family
+		}
+	case sortByRecent:
+		ra, oka := m.recent[a.model.Name]
+		rb, okb := m.recent[b.model.Name]
+		if oka != okb {
+			return oka
+		}
+		if ra != rb {
+			return ra < rb
+		}
+	}
+	if a.model.Name != b.model.Name {
+		return a.model.Name < b.model.Name
+	}
+	return a.model.Host < b.model.Host
+}
+
+// group puts rows under headers: favourites first, then by family or
+// host.
+func (m *ModelSelection) group(rows []modelRow) []modelRow {
+	const favourites = "★ Favourites"
+
+	var names []string
+	groups := map[string][]modelRow{}
+	for _, r := range rows {
+		name := r.model.Details.Family
+		if m.groupBy == groupByHost {
+			name = r.model.Host
+		}
+		switch {
+		case m.favourites[r.model.Name]:
+			name = favourites
+		case name == "":
+			name = "other"
+		}
+		if _, ok := groups[name]; !ok {
+			names = append(names, name)
+		}
+		groups[name] = append(groups[name], r)
+	}
+
+	sort.SliceStable(names, func(i, j int) bool {
+		if (names[i] == favourites) != (names[j] == favourites) {
+			return names[i] == favourites
+		}
+		return names[i] < names[j]
+	})
+
+	var out []modelRow
+	for _, name := range names {
+		out = append(out, modelRow{group: fmt.Sprintf("%s (%d)", name, len(groups[name]))})
+		out = append(out, groups[name]...)
+	}
+	return out
+}
+
+// moveCursor moves the cursor by delta rows, skipping group headers.
+func (m *ModelSelection) moveCursor(delta int) {
+	if len(m.rows) == 0 {
+		return
+	}
+	step := 1
+	if delta < 0 {
+		step = -1
+	}
+
+	cursor := max(0, min(len(m.rows)-1, m.cursor+delta))
+	for i := cursor; i >= 0 && i < len(m.rows); i += step {
+		if m.rows[i].group == "" {
+			m.cursor = i
+			return
+		}
+	}
+	// Only headers that way; take the nearest model the other way.
+	for i := cursor; i >= 0 && i < len(m.rows); i -= step {
+		if m.rows[i].group == "" {
+			m.cursor = i
+			return
+		}
+	}
+}
+
+// selectModel puts the cursor on the named model, on host if it is
+// listed there.
+func (m *ModelSelection) selectModel(name, host string) {
+	found := -1
+	for i, r := range m.rows {
+		if r.group != "" || r.model.Name != name {
+			continue
+		}
+		if r.model.Host == host {
+			m.cursor = i
+			return
+		}
+		if found < 0 {
+			found = i
+		}
+	}
+	if found >= 0 {
+		m.cursor = found
+		return
+	}
+	m.moveCursor(0)
+}
+
+func (m *ModelSelection) selected() (aihub.OllamaModel, bool) {
+	if m.cursor < 0 || m.cursor >= len(m.rows) || m.rows[m.cursor].group != "" {
+		return aihub.OllamaModel{}, false
+	}
+	return m.rows[m.cursor].model, true
+}
+
+// count returns the number of models listed.
+func (m *ModelSelection) count() int {
+	n := 0
+	for _, r := range m.rows {
+		if r.group == "" {
+			n++
+		}
+	}
+	return n
+}
+
+func (m *ModelSelection) loadState() {
+	st := m.state.Get()
+	m.favourites = map[string]bool{}
+	for _, name := range st.FavouriteModels {
+		m.favourites[name] = true
+	}
+	m.recent = map[string]int{}
+	for i, name := range st.RecentModels {
+		m.recent[name] = i
+	}
+}
+
+func (m *ModelSelection) toggleFavourite(name string) tea.Cmd {
+	favourite, err := m.state.ToggleFavourite(name)
+	if err != nil {
+		return ShowToast("Failed to save favourites: "+err.Error(), 3*time.Second)
+	}
+	m.loadState()
+	m.refresh()
+
+	if favourite {
+		return ShowToast(name+" added to favourites", 2*time.Second)
+	}
+	return ShowToast(name+" removed from favourites", 2*time.Second)
+}
+
+func (m *ModelSelection) toggleMark(key string) {
+	for i, k := range m.marked {
+		if k == key {
+			m.marked = append(m.marked[:i], m.marked[i+1:]...)
+			return
+		}
+	}
+	m.marked = append(m.marked, key)
+}
+
+func (m *ModelSelection) isMarked(key string) bool {
+	for _, k := range m.marked {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// modelKey tells apart models of the same name on different hosts.
+func modelKey(model aihub.OllamaModel) string {
+	return model.Host + "/" + model.Name
+}
+
+// Shortcuts returns the shortcuts of the model picker.
+func (m *ModelSelection) Shortcuts() []keymap.Shortcut {
+	return []keymap.Shortcut{
+		{Key: "type", Action: "Filter"},
+		{Key: "↑/↓", Action: "Navigate"},
+		{Key: "enter", Action: "Select"},
+		{Key: "space", Action: "Mark"},
+		{Key: "alt+c", Action: "Compare Marked"},
+		{Key: "alt+f", Action: "Favourite"},
+		{Key: "alt+s", Action: "Sort"},
+		{Key: "alt+g", Action: "Group"},
+		{Key: "alt+h", Action: "Hosts"},
+		{Key: "esc", Action: "Back"},
+	}
+}
+
+// fuzzyModelScore matches query as a case-insensitive subsequence of a
+// model name. Consecutive matches and matches at the start of a name part
+// (after ':', '/', '-', '.' or '_') rank higher.
+func fuzzyModelScore(name, query string) (int, bool) {
+	if query == "" {
+		return 0, true
+	}
+
+	n := []rune(strings.ToLower(name))
+	q := []rune(strings.ToLower(query))
+
+	score, qi, prev := 0, 0, -2
+	for i := 0; i < len(n) && qi < len(q); i++ {
+		if n[i] != q[qi] {
+			continue
+		}
+		switch {
+		case i == prev+1:
+			score += 5
+		case i == 0 || strings.ContainsRune(":/-._", n[i-1]):
+			score += 3
+		default:
+			score++
+		}
+		prev = i
+		qi++
+	}
+	if qi < len(q) {
+		return 0, false
+	}
+	return score, true
+}
+
 func trim(s string, w int) string {
 	if lipgloss.Width(s) <= w {
 		return s
@@ -0,0 +1,139 @@
+package ui
+
+import (
+	"errors"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ErrPassphraseCancelled is returned when the user dismisses the prompt.
+var ErrPassphraseCancelled = errors.New("passphrase prompt cancelled")
+
+var passphraseErrStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("1"))
+
+// PassphraseModel asks for the history passphrase before the main program
+// starts. When creating a new key it asks twice to catch typos.
+type PassphraseModel struct {
+	inputs  []textinput.Model
+	focused int
+	errMsg  string
+
+	width  int
+	height int
+
+	value     string
+	cancelled bool
+}
+
+// PromptPassphrase runs a small full-screen program asking for the
+// history passphrase. errMsg is shown above the input, e.g. after a wrong
+// attempt; confirm asks for the passphrase a second time.
+func PromptPassphrase(errMsg string, confirm bool) (string, error) {
+	m := newPassphraseModel(errMsg, confirm)
+
+	final, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
+	if err != nil {
+		return "", err
+	}
+
+	pm := final.(*PassphraseModel)
+	if pm.cancelled {
+		return "", ErrPassphraseCancelled
+	}
+	return pm.value, nil
+}
+
+func newPassphraseModel(errMsg string, confirm bool) *PassphraseModel {
+	count := 1
+	if confirm {
+		count = 2
+	}
+
+	inputs := make([]textinput.Model, count)
+	for i := range inputs {
+		ti := textinput.New()
+		ti.Prompt = "❯ "
+		ti.EchoMode = textinput.EchoPassword
+		ti.EchoCharacter = '•'
+		ti.Width = 40
+		inputs[i] = ti
+	}
+	inputs[0].Placeholder = "Passphrase"
+	if confirm {
+		inputs[1].Placeholder = "Repeat passphrase"
+	}
+	inputs[0].Focus()
+
+	return &PassphraseModel{
+		inputs: inputs,
+		errMsg: errMsg,
+	}
+}
+
+func (m *PassphraseModel) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (m *PassphraseModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+
+	case tea.KeyMsg:
+		switch msg.String() {
+
+		case "esc", "ctrl+c", "ctrl+q":
+			m.cancelled = true
+			return m, tea.Quit
+
+		case "tab", "down", "up", "shift+tab":
+			m.focusNext()
+			return m, textinput.Blink
+
+		case "enter":
+			if m.inputs[m.focused].Value() == "" {
+				return m, nil
+			}
+			if m.focused < len(m.inputs)-1 {
+				m.focusNext()
+				return m, textinput.Blink
+			}
+			if len(m.inputs) > 1 && m.inputs[0].Value() != m.inputs[1].Value() {
+				m.errMsg = "Passphrases do not match."
+				m.inputs[1].Reset()
+				return m, nil
+			}
+			m.value = m.inputs[0].Value()
+			return m, tea.Quit
+		}
+	}
+
+	var cmd tea.Cmd
+	m.inputs[m.focused], cmd = m.inputs[m.focused].Update(msg)
+	return m, cmd
+}
+
+func (m *PassphraseModel) View() string {
+	body := ""
+	if m.errMsg != "" {
+		body += passphraseErrStyle.Render(m.errMsg) + "\n\n"
+	}
+	for _, in := range m.inputs {
+		body += in.View() + "\n"
+	}
+	body += "\nenter = unlock • esc = quit"
+
+	popup := NewPopup("Chat history is encrypted", body, 50)
+	return popupCentered(m.width, m.height, popup.View())
+}
+
+func (m *PassphraseModel) focusNext() {
+	m.inputs[m.focused].Blur()
+	m.focused = (m.focused + 1) % len(m.inputs)
+	m.inputs[m.focused].Focus()
+}
@@ -0,0 +1,177 @@
+package ui
+
+import (
+	"time"
+
+	"github.com/aj-seven/llmverse/pkg/keymap"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Input Recall
+//
+// Prompts sent from any chat are kept in an input history. up and down
+// step through it like a shell when the input is empty, and ctrl+r
+// searches it backwards. The unsent input of each chat is kept as its
+// draft.
+
+// inputSearch is an open reverse search through the input history.
+type inputSearch struct {
+	query    string
+	match    int    // index of the shown prompt, or -1
+	original string // input to restore on cancel
+}
+
+var searchPromptStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("5")).
+	Bold(true)
+
+// canRecall reports whether up and down should step through the input
+// history rather than scroll the chat.
+func (m *ChatModel) canRecall() bool {
+	return m.inputs != nil && m.textarea.Focused() && !m.streaming &&
+		(m.recall >= 0 || m.textarea.Value() == "")
+}
+
+// recallInput shows the prompt delta steps older (negative) or newer
+// (positive) than the shown one. Stepping past the newest prompt brings
+// back the text typed before recall started.
+func (m *ChatModel) recallInput(delta int) tea.Cmd {
+	if m.recall < 0 {
+		if delta > 0 {
+			return nil
+		}
+		m.pending = m.textarea.Value()
+		m.recall = m.inputs.Len()
+	}
+
+	i := m.recall + delta
+	switch {
+	case i < 0:
+		return nil
+	case i >= m.inputs.Len():
+		m.recall = -1
+		m.setInput(m.pending)
+	default:
+		m.recall = i
+		m.setInput(m.inputs.At(i))
+	}
+	return nil
+}
+
+func (m *ChatModel) startSearch() tea.Cmd {
+	if m.inputs == nil || m.streaming {
+		return nil
+	}
+	m.search = &inputSearch{match: -1, original: m.textarea.Value()}
+	m.completion.clear()
+	return tea.Batch(
+		m.FocusInput(),
+		func() tea.Msg { return chatModeMsg{} },
+	)
+}
+
+// stopSearch closes the search, putting text in the input.
+func (m *ChatModel) stopSearch(text string) tea.Cmd {
+	m.search = nil
+	m.recall = -1
+	m.setInput(text)
+	return func() tea.Msg { return chatModeMsg{} }
+}
+
+// updateSearch handles keys while searching the input history.
+func (m *ChatModel) updateSearch(msg tea.KeyMsg) tea.Cmd {
+	s := m.search
+
+	switch msg.Type {
+	case tea.KeyEsc, tea.KeyCtrlG:
+		return m.stopSearch(s.original)
+
+	case tea.KeyCtrlR:
+		if s.query == "" || s.match < 0 {
+			return nil
+		}
+		if i := m.inputs.Search(s.query, s.match); i >= 0 {
+			s.match = i
+			return nil
+		}
+		return ShowToast("No older match", 2*time.Second)
+
+	case tea.KeyBackspace:
+		runes := []rune(s.query)
+		if len(runes) == 0 {
+			return nil
+		}
+		s.query = string(runes[:len(runes)-1])
+
+	case tea.KeyRunes, tea.KeySpace:
+		s.query += string(msg.Runes)
+
+	default:
+		// Any other key takes the match, like enter.
+		if s.match < 0 {
+			return m.stopSearch(s.original)
+		}
+		return m.stopSearch(m.inputs.At(s.match))
+	}
+
+	s.match = -1
+	if s.query != "" {
+		s.match = m.inputs.Search(s.query, m.inputs.Len())
+	}
+	return nil
+}
+
+// renderSearch renders the search line shown in place of the input,
+// fitted into width.
+func (m *ChatModel) renderSearch(width int) string {
+	label := "(reverse-i-search)"
+	if m.search.query != "" && m.search.match < 0 {
+		label = "(failed reverse-i-search)"
+	}
+
+	prompt := label + "`" + m.search.query + "': "
+	line := searchPromptStyle.Render(prompt)
+	if m.search.match >= 0 {
+		line += truncate(m.inputs.At(m.search.match), max(width-len([]rune(prompt))-3, 0))
+	}
+	return line
+}
+
+// setInput replaces the input text, keeping the draft in sync.
+func (m *ChatModel) setInput(text string) {
+	m.textarea.SetValue(text)
+	m.textarea.CursorEnd()
+	m.resizeTextarea()
+	m.syncDraft()
+}
+
+// syncDraft records the input as the draft of the chat. Editing a
+// recalled prompt turns it into a new one.
+func (m *ChatModel) syncDraft() {
+	value := m.textarea.Value()
+	if m.recall >= 0 && value != m.inputs.At(m.recall) {
+		m.recall = -1
+	}
+	if value == m.draft || m.historyManager == nil {
+		return
+	}
+	m.draft = value
+	m.historyManager.SetDraft(m.historyID, value)
+}
+
+// Searching reports whether the input history is being searched.
+func (m *ChatModel) Searching() bool {
+	return m.search != nil
+}
+
+// SearchShortcuts returns the shortcuts of the input history search.
+func (m *ChatModel) SearchShortcuts() []keymap.Shortcut {
+	return []keymap.Shortcut{
+		{Key: "type", Action: "Search"},
+		{Key: "ctrl+r", Action: "Older"},
+		{Key: "enter", Action: "Use"},
+		{Key: "esc", Action: "Cancel"},
+	}
+}
@@ -0,0 +1,275 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aj-seven/llmverse/internal/attach"
+	"github.com/aj-seven/llmverse/internal/clipboard"
+	"github.com/aj-seven/llmverse/pkg/chat"
+	"github.com/aj-seven/llmverse/pkg/keymap"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Messages
+
+// chatModeMsg tells the root model that the chat entered or left a mode
+// with its own shortcuts.
+type chatModeMsg struct{}
+
+// Message Selection
+//
+// In selection mode the arrow keys move between message bubbles instead of
+// the input, and the selected message or one of its numbered code blocks
+// can be copied or saved.
+
+var bubbleSelected = lipgloss.NewStyle().
+	Padding(0, 1).
+	Border(lipgloss.RoundedBorder()).
+	BorderForeground(lipgloss.Color("5"))
+
+func (m *ChatModel) startSelection() tea.Cmd {
+	messages := m.Messages()
+	if len(messages) == 0 {
+		return ShowToast("No messages to select", 2*time.Second)
+	}
+
+	m.selecting = true
+	m.selected = len(messages) - 1
+	m.block = 0
+	m.lockScroll = true
+	m.blurInput()
+	m.completion.clear()
+	m.updateViewport(false)
+	m.scrollToSelected()
+
+	return func() tea.Msg { return chatModeMsg{} }
+}
+
+func (m *ChatModel) stopSelection() tea.Cmd {
+	m.selecting = false
+	m.updateViewport(false)
+	return tea.Batch(
+		m.FocusInput(),
+		func() tea.Msg { return chatModeMsg{} },
+	)
+}
+
+// updateSelection handles keys in selection mode.
+func (m *ChatModel) updateSelection(msg tea.KeyMsg) tea.Cmd {
+	messages := m.Messages()
+	if len(messages) == 0 {
+		return m.stopSelection()
+	}
+	m.selected = min(m.selected, len(messages)-1)
+	blocks := chat.CodeBlocks(messages[m.selected].Content)
+
+	switch key := msg.String(); key {
+	case "esc", "ctrl+s":
+		return m.stopSelection()
+
+	case "up", "k":
+		m.selectMessage(m.selected - 1)
+
+	case "down", "j":
+		m.selectMessage(m.selected + 1)
+
+	case "home", "g":
+		m.selectMessage(0)
+
+	case "end", "G":
+		m.selectMessage(len(messages) - 1)
+
+	case "tab":
+		if len(blocks) > 0 {
+			m.block = (m.block + 1) % len(blocks)
+			m.updateViewport(false)
+		}
+
+	case "shift+tab":
+		if len(blocks) > 0 {
+			m.block = (m.block + len(blocks) - 1) % len(blocks)
+			m.updateViewport(false)
+		}
+
+	case "y":
+		return copyText(messages[m.selected].Content, "Message copied")
+
+	case "e":
+		// Edit a copy of the message as the next prompt, below any unsent
+		// input so it is not lost.
+		text := messages[m.selected].Content
+		if draft := strings.TrimSpace(m.textarea.Value()); draft != "" {
+			text = draft + "\n\n" + text
+		}
+		return tea.Sequence(m.stopSelection(), openEditor(text))
+
+	case "c":
+		if len(blocks) == 0 {
+			return ShowToast("No code in this message", 2*time.Second)
+		}
+		return copyText(blocks[m.block].Code, fmt.Sprintf("Code block %d copied", m.block+1))
+
+	case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+		n := int(key[0] - '1')
+		if n >= len(blocks) {
+			return ShowToast(fmt.Sprintf("No code block %d", n+1), 2*time.Second)
+		}
+		m.block = n
+		m.updateViewport(false)
+		return copyText(blocks[n].Code, fmt.Sprintf("Code block %d copied", n+1))
+
+	case "s":
+		if len(blocks) == 0 {
+			return ShowToast("No code in this message", 2*time.Second)
+		}
+		m.saveDialog = NewInputDialog(
+			fmt.Sprintf("Save code block %d", m.block+1),
+			"File name, relative to "+m.workDir,
+			"snippet"+attach.Extension(blocks[m.block].Language),
+		)
+		m.saveDialog.SetSize(m.width, m.height)
+		m.saveCode = blocks[m.block].Code
+	}
+
+	return nil
+}
+
+// updateSaveDialog handles the dialog asking where to save a code block.
+func (m *ChatModel) updateSaveDialog(msg tea.Msg) tea.Cmd {
+	cmd := m.saveDialog.Update(msg)
+	if !m.saveDialog.Done() {
+		return cmd
+	}
+
+	name, code := m.saveDialog.Value, m.saveCode
+	m.saveDialog, m.saveCode = nil, ""
+	if name == nil || strings.TrimSpace(*name) == "" {
+		return nil
+	}
+	return m.saveCodeBlock(strings.TrimSpace(*name), code)
+}
+
+// saveCodeBlock writes code to a new file. Existing files are never
+// overwritten.
+func (m *ChatModel) saveCodeBlock(name, code string) tea.Cmd {
+	path := name
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(m.workDir, path)
+	}
+	if !strings.HasSuffix(code, "\n") {
+		code += "\n"
+	}
+
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err == nil {
+		var f *os.File
+		f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, err = f.WriteString(code)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}
+	}
+
+	switch {
+	case errors.Is(err, fs.ErrExist):
+		return ShowToast(name+" already exists", 3*time.Second)
+	case err != nil:
+		return ShowToast("Save failed: "+err.Error(), 3*time.Second)
+	}
+	return ShowToast("Saved to "+name, 2*time.Second)
+}
+
+func (m *ChatModel) selectMessage(i int) {
+	n := len(m.Messages())
+	if n == 0 {
+		return
+	}
+	m.selected = max(0, min(i, n-1))
+	m.block = 0
+	m.updateViewport(false)
+	m.scrollToSelected()
+}
+
+// scrollToSelected scrolls the selected message into view.
+func (m *ChatModel) scrollToSelected() {
+	if m.selected+1 >= len(m.offsets) {
+		return
+	}
+	top, bottom := m.offsets[m.selected], m.offsets[m.selected+1]
+	if top < m.viewport.YOffset || bottom > m.viewport.YOffset+m.viewport.Height {
+		m.viewport.SetYOffset(top)
+	}
+}
+
+// selectedBlock returns the highlighted code block of messages[i], or -1
+// if the message is not selected.
+func (m *ChatModel) selectedBlock(i int) int {
+	if !m.selecting || i != m.selected {
+		return -1
+	}
+	return m.block
+}
+
+// numberCodeBlocks puts a numbered label above each code block of a
+// Markdown text, so blocks can be picked by number. The label of block
+// current is highlighted.
+func numberCodeBlocks(content string, current int) string {
+	blocks := chat.CodeBlocks(content)
+	if len(blocks) == 0 {
+		return content
+	}
+
+	lines := strings.Split(content, "\n")
+	var b strings.Builder
+	next := 0
+	for i, line := range lines {
+		if next < len(blocks) && blocks[next].Line == i {
+			indent := line[:len(line)-len(strings.TrimLeft(line, " "))]
+			label := fmt.Sprintf("[%d] %s", next+1, blocks[next].Language)
+			if next == current {
+				fmt.Fprintf(&b, "%s**▶ %s**\n", indent, strings.TrimSpace(label))
+			} else {
+				fmt.Fprintf(&b, "%s*%s*\n", indent, strings.TrimSpace(label))
+			}
+			next++
+		}
+		b.WriteString(line)
+		if i < len(lines)-1 {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+func copyText(text, toast string) tea.Cmd {
+	if err := clipboard.Write(text); err != nil {
+		return ShowToast("Copy failed: "+err.Error(), 3*time.Second)
+	}
+	return ShowToast(toast, 2*time.Second)
+}
+
+// SelectionShortcuts returns the footer shortcuts of selection mode.
+func (m *ChatModel) SelectionShortcuts() []keymap.Shortcut {
+	return []keymap.Shortcut{
+		{Key: "↑/↓", Action: "Select"},
+		{Key: "y", Action: "Copy Message"},
+		{Key: "tab", Action: "Next Code Block"},
+		{Key: "c/1-9", Action: "Copy Code"},
+		{Key: "s", Action: "Save Code"},
+		{Key: "e", Action: "Edit as Prompt"},
+		{Key: "esc", Action: "Done"},
+	}
+}
+
+// Selecting reports whether the chat is in selection mode.
+func (m *ChatModel) Selecting() bool { return m.selecting }
@@ -0,0 +1,101 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aj-seven/llmverse/internal/history"
+	"github.com/aj-seven/llmverse/internal/state"
+)
+
+// Sessions
+//
+// The chats open when llmv exits, with their scroll positions, are kept in
+// the state file so the next run can pick up where this one stopped.
+
+// Resume opens the stored chat whose ID is id or starts with it in place
+// of the empty chat llmv starts with.
+func (m *Model) Resume(id string) error {
+	id, err := m.historyManager.ResolveID(id)
+	if err != nil {
+		return err
+	}
+	if _, err := m.historyManager.LoadHistory(id, false); err != nil {
+		if errors.Is(err, history.ErrLeased) {
+			return fmt.Errorf("chat %s is open in another llmv instance", id)
+		}
+		return err
+	}
+
+	m.syncTabs()
+	m.updateFooterContent()
+	return nil
+}
+
+// Continue reopens the chats of the last session. Chats that were deleted
+// or are open in another instance since are skipped.
+func (m *Model) Continue() {
+	session := m.state.Get().Session
+	if session == nil {
+		return
+	}
+
+	restored := map[string]state.Tab{}
+	for _, tab := range session.Tabs {
+		if len(restored) > 0 {
+			placeholder := m.historyManager.NewTab(m.currentModel)
+			if _, err := m.historyManager.LoadHistory(tab.ID, false); err != nil {
+				m.historyManager.CloseTab(placeholder.ID)
+				continue
+			}
+		} else if _, err := m.historyManager.LoadHistory(tab.ID, false); err != nil {
+			continue
+		}
+		restored[tab.ID] = tab
+	}
+	if _, ok := restored[session.Active]; ok {
+		m.historyManager.SwitchTab(session.Active)
+	}
+
+	m.syncTabs()
+	for id, tab := range restored {
+		if chat := m.tab(id); chat != nil && !tab.Bottom {
+			chat.restoreScroll(tab.Scroll)
+		}
+	}
+	m.updateFooterContent()
+}
+
+// SaveSession records the open chats and the current model for the next
+// run. Chats with nothing saved are left out.
+func (m *Model) SaveSession() error {
+	session := &state.Session{}
+	for _, chat := range m.tabs {
+		h := chat.chatHistory()
+		if h == nil || h.IsEmpty() {
+			continue
+		}
+		session.Tabs = append(session.Tabs, state.Tab{
+			ID:     h.ID,
+			Scroll: chat.viewport.YOffset,
+			Bottom: chat.viewport.AtBottom(),
+		})
+		if chat == m.chat {
+			session.Active = h.ID
+		}
+	}
+	if len(session.Tabs) == 0 {
+		session = nil
+	}
+	return m.state.SaveSession(m.currentModel, session)
+}
+
+// restoreScroll scrolls the chat to line y, once it is sized.
+func (m *ChatModel) restoreScroll(y int) {
+	m.lockScroll = true
+	if m.ready {
+		m.viewport.SetYOffset(y)
+		return
+	}
+	m.scrollTo = y
+}
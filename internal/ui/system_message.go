@@ -1,29 +1,71 @@
 package ui
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/aj-seven/llmverse/internal/config"
+	"github.com/aj-seven/llmverse/internal/history"
 	messages "github.com/aj-seven/llmverse/pkg/messages"
 
 	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// defaultPersonaName is the built-in persona backed by the global
+// system message in the config.
+const defaultPersonaName = "Default"
+
+// Messages
+
+// personaPickedMsg is sent when a persona is chosen for a new chat.
+type personaPickedMsg struct {
+	name string
+}
+
+type systemMode int
+
+const (
+	systemList systemMode = iota
+	systemEdit
+)
+
+// editTarget says what the edit form saves to.
+type editTarget int
+
+const (
+	editNewPersona editTarget = iota
+	editPersona
+	editDefault
+	editChat
 )
 
 // System Model
 type SystemModel struct {
 	codePopup    bool
+	mode         systemMode
+	picking      bool // choosing a persona for a new chat
+	cursor       int
+	nameInput    textinput.Model
 	codeTextarea textarea.Model
+	focusName    bool
+	target       editTarget
+	editing      string // name of the persona being edited
 	width        int
 	height       int
 	config       *config.Config
 
+	historyManager *history.Manager
+
 	toastMessage string
 	toastUntil   time.Time
 }
 
 // Constructor
-func NewSystemModel(cfg *config.Config) *SystemModel {
+func NewSystemModel(cfg *config.Config, hm *history.Manager) *SystemModel {
 	ta := textarea.New()
 	ta.Placeholder = "Input System message here..."
 	ta.Prompt = ""
@@ -32,9 +74,16 @@ func NewSystemModel(cfg *config.Config) *SystemModel {
 	ta.SetWidth(60)
 	ta.Blur()
 
+	ti := textinput.New()
+	ti.Prompt = "Name: "
+	ti.Placeholder = "e.g. code reviewer"
+	ti.Width = 50
+
 	return &SystemModel{
-		codeTextarea: ta,
-		config:       cfg,
+		codeTextarea:   ta,
+		nameInput:      ti,
+		config:         cfg,
+		historyManager: hm,
 	}
 }
 
@@ -49,22 +98,94 @@ func (m *SystemModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
-	if key, ok := msg.(tea.KeyMsg); ok {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, m.updateInputs(msg)
+	}
+
+	if m.mode == systemEdit {
 		switch key.String() {
 		case "esc":
-			m.codePopup = false
-			m.codeTextarea.Blur()
-			return m, func() tea.Msg {
-				return messages.SystemPopupStatusMsg{IsOpen: false}
+			if m.target == editChat {
+				return m, m.close()
 			}
+			m.mode = systemList
+			return m, nil
+		case "tab":
+			if m.showsName() {
+				m.setFocusName(!m.focusName)
+			}
+			return m, nil
 		case "ctrl+s":
 			return m.saveSystemMessage()
 		}
+		return m, m.updateInputs(msg)
 	}
 
-	var cmd tea.Cmd
-	m.codeTextarea, cmd = m.codeTextarea.Update(msg)
-	return m, cmd
+	names := m.personaNames()
+
+	switch key.String() {
+	case "esc":
+		return m, m.close()
+
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+
+	case "down", "j":
+		if m.cursor < len(names)-1 {
+			m.cursor++
+		}
+
+	case "enter":
+		name := names[m.cursor]
+		if m.picking {
+			return m, tea.Batch(m.close(), func() tea.Msg {
+				return personaPickedMsg{name: name}
+			})
+		}
+		applyPersona(m.historyManager, m.config, name)
+		return m, tea.Batch(
+			m.close(),
+			ShowToast(fmt.Sprintf("Using persona %s", name), 2*time.Second),
+		)
+
+	case "n":
+		m.openEditor(editNewPersona, "", "")
+
+	case "e":
+		name := names[m.cursor]
+		if name == defaultPersonaName {
+			m.openEditor(editDefault, name, m.config.Assistant.Message)
+		} else if p, ok := m.config.FindPersona(name); ok {
+			m.openEditor(editPersona, name, p.Prompt)
+		}
+
+	case "c":
+		if !m.picking {
+			prompt := ""
+			if h := m.historyManager.GetCurrentHistory(); h != nil {
+				prompt = h.SystemPrompt
+			}
+			m.openEditor(editChat, "", prompt)
+		}
+
+	case "d", "ctrl+d":
+		name := names[m.cursor]
+		if name == defaultPersonaName {
+			return m, ShowToast("The default persona cannot be deleted", 2*time.Second)
+		}
+		if err := m.config.DeletePersona(name); err != nil {
+			return m, ShowToast("Delete failed: "+err.Error(), 3*time.Second)
+		}
+		if m.cursor >= len(m.personaNames()) {
+			m.cursor--
+		}
+		return m, ShowToast(fmt.Sprintf("Persona %s deleted", name), 2*time.Second)
+	}
+
+	return m, nil
 }
 
 // View
@@ -73,7 +194,12 @@ func (m *SystemModel) View() string {
 		return ""
 	}
 
-	body := m.codeTextarea.View() + "\n\nctrl+s = save • esc = cancel"
+	var title, body string
+	if m.mode == systemEdit {
+		title, body = m.editView()
+	} else {
+		title, body = m.listView()
+	}
 
 	// Render toast if active
 	if m.toastMessage != "" && time.Now().Before(m.toastUntil) {
@@ -83,7 +209,7 @@ func (m *SystemModel) View() string {
 	}
 
 	popup := NewPopup(
-		"System Message",
+		title,
 		body,
 		64,
 	)
@@ -98,33 +224,182 @@ func (m *SystemModel) SetSize(w, h int) {
 	m.height = h
 }
 
-// Focus
+// Focus opens the persona list for the current chat.
 func (m *SystemModel) Focus() tea.Cmd {
+	return m.open(false)
+}
+
+// OpenPicker opens the persona list to choose one for a new chat.
+func (m *SystemModel) OpenPicker() tea.Cmd {
+	return m.open(true)
+}
+
+// Helpers
+
+func (m *SystemModel) open(picking bool) tea.Cmd {
 	m.codePopup = true
+	m.mode = systemList
+	m.picking = picking
+	m.cursor = 0
 
-	if m.config != nil {
-		m.codeTextarea.SetValue(m.config.Assistant.Message)
-	} else {
-		m.codeTextarea.SetValue("")
+	if h := m.historyManager.GetCurrentHistory(); h != nil && !picking {
+		for i, name := range m.personaNames() {
+			if name == h.Persona {
+				m.cursor = i
+			}
+		}
 	}
 
-	m.codeTextarea.CursorEnd()
-	m.codeTextarea.Focus()
-
 	return func() tea.Msg {
 		return messages.SystemPopupStatusMsg{IsOpen: true}
 	}
 }
 
-// Helpers
+func (m *SystemModel) close() tea.Cmd {
+	m.codePopup = false
+	m.mode = systemList
+	m.codeTextarea.Blur()
+	m.nameInput.Blur()
+	return func() tea.Msg {
+		return messages.SystemPopupStatusMsg{IsOpen: false}
+	}
+}
+
+func (m *SystemModel) openEditor(target editTarget, name, prompt string) {
+	m.mode = systemEdit
+	m.target = target
+	m.editing = name
+
+	m.nameInput.SetValue(name)
+	m.nameInput.CursorEnd()
+	m.codeTextarea.SetValue(prompt)
+	m.codeTextarea.CursorEnd()
+
+	m.setFocusName(target == editNewPersona)
+}
+
+func (m *SystemModel) setFocusName(focus bool) {
+	m.focusName = focus
+	if focus {
+		m.codeTextarea.Blur()
+		m.nameInput.Focus()
+	} else {
+		m.nameInput.Blur()
+		m.codeTextarea.Focus()
+	}
+}
+
+func (m *SystemModel) showsName() bool {
+	return m.target == editNewPersona || m.target == editPersona
+}
+
+func (m *SystemModel) updateInputs(msg tea.Msg) tea.Cmd {
+	if m.mode != systemEdit {
+		return nil
+	}
+
+	var cmd tea.Cmd
+	if m.focusName {
+		m.nameInput, cmd = m.nameInput.Update(msg)
+	} else {
+		m.codeTextarea, cmd = m.codeTextarea.Update(msg)
+	}
+	return cmd
+}
+
+func (m *SystemModel) personaNames() []string {
+	names := []string{defaultPersonaName}
+	if m.config != nil {
+		for _, p := range m.config.Personas {
+			names = append(names, p.Name)
+		}
+	}
+	return names
+}
+
+func (m *SystemModel) listView() (string, string) {
+	current := ""
+	if h := m.historyManager.GetCurrentHistory(); h != nil {
+		current = h.Persona
+	}
+
+	var b strings.Builder
+	for i, name := range m.personaNames() {
+		line := "  " + name
+		if name == current && !m.picking {
+			line += dimStyle.Render(" (current)")
+		}
+		if i == m.cursor {
+			line = lipgloss.NewStyle().Bold(true).Render("❯ " + name)
+			if name == current && !m.picking {
+				line += dimStyle.Render(" (current)")
+			}
+		}
+		b.WriteString(line + "\n")
+	}
+
+	if m.picking {
+		b.WriteString("\nenter = start chat • n = new • e = edit • esc = cancel")
+		return "New Chat - Pick a Persona", b.String()
+	}
+
+	b.WriteString("\nenter = use • n = new • e = edit • d = delete\nc = custom prompt for this chat • esc = close")
+	return "System Prompt", b.String()
+}
+
+func (m *SystemModel) editView() (string, string) {
+	body := ""
+	if m.showsName() {
+		body = m.nameInput.View() + "\n\n"
+	}
+	body += m.codeTextarea.View()
+
+	switch m.target {
+	case editNewPersona:
+		return "New Persona", body + "\n\ntab = switch field • ctrl+s = save • esc = back"
+	case editPersona:
+		return "Edit Persona", body + "\n\ntab = switch field • ctrl+s = save • esc = back"
+	case editChat:
+		return "System Prompt for This Chat", body + "\n\nctrl+s = save • esc = cancel"
+	default:
+		return "Default System Message", body + "\n\nUsed for new chats.\nctrl+s = save • esc = back"
+	}
+}
+
 func (m *SystemModel) saveSystemMessage() (tea.Model, tea.Cmd) {
 	if m.config == nil {
 		return m, nil
 	}
 
 	code := m.codeTextarea.Value()
-	_ = m.config.SetSystemMessage(code)
 
+	switch m.target {
+	case editChat:
+		m.historyManager.SetSystemPrompt("", code)
+		return m, tea.Batch(
+			m.close(),
+			ShowToast("System prompt saved for this chat.", 2*time.Second),
+		)
+
+	case editDefault:
+		if err := m.config.SetSystemMessage(code); err != nil {
+			return m, ShowToast("Save failed: "+err.Error(), 3*time.Second)
+		}
+
+	default:
+		name := strings.TrimSpace(m.nameInput.Value())
+		if name == "" || name == defaultPersonaName {
+			return m, ShowToast("Pick another persona name", 2*time.Second)
+		}
+		if _, exists := m.config.FindPersona(name); exists && name != m.editing {
+			return m, ShowToast("A persona with that name exists", 2*time.Second)
+		}
+		if err := m.config.SavePersona(m.editing, config.Persona{Name: name, Prompt: code}); err != nil {
+			return m, ShowToast("Save failed: "+err.Error(), 3*time.Second)
+		}
+	}
+
+	m.mode = systemList
 	return m, tea.Batch(
 		ShowToast("System Message saved.", 2*time.Second),
 		func() tea.Msg {
@@ -132,3 +407,36 @@ func (m *SystemModel) saveSystemMessage() (tea.Model, tea.Cmd) {
 		},
 	)
 }
+
+// applyPersona sets the system prompt of the current chat from the named
+// persona. The default persona uses the global system message.
+func applyPersona(hm *history.Manager, cfg *config.Config, name string) {
+	if cfg == nil {
+		return
+	}
+
+	if name == defaultPersonaName || name == "" {
+		if cfg.Assistant.Message == "" {
+			hm.SetSystemPrompt("", "")
+			return
+		}
+		hm.SetSystemPrompt(defaultPersonaName, cfg.Assistant.Message)
+		return
+	}
+
+	if p, ok := cfg.FindPersona(name); ok {
+		hm.SetSystemPrompt(p.Name, p.Prompt)
+	}
+}
+
+// personaLabel describes the system prompt of a chat for the footer.
+func personaLabel(h *history.History) string {
+	switch {
+	case h == nil || h.SystemPrompt == "":
+		return "None"
+	case h.Persona == "":
+		return "Custom"
+	default:
+		return h.Persona
+	}
+}
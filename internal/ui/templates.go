@@ -0,0 +1,212 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aj-seven/llmverse/internal/templates"
+	messages "github.com/aj-seven/llmverse/pkg/messages"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Messages
+
+// templateRenderedMsg carries a rendered template ready to be sent in the
+// chat it was picked in.
+type templateRenderedMsg struct {
+	historyID string
+	text      string
+	err       error
+}
+
+// Template Model
+
+// TemplateModel is the popup for picking a prompt template and filling in
+// its variables.
+type TemplateModel struct {
+	open      bool
+	templates []templates.Template
+	cursor    int
+	loadErr   error
+	// skipped are the template files that failed to parse.
+	skipped []error
+
+	form     *FormDialog
+	selected templates.Template
+	// historyID is the chat the picker was opened in.
+	historyID string
+
+	width  int
+	height int
+}
+
+// Constructor
+func NewTemplateModel() *TemplateModel {
+	return &TemplateModel{}
+}
+
+func (m *TemplateModel) SetSize(w, h int) {
+	m.width = w
+	m.height = h
+	if m.form != nil {
+		m.form.SetSize(w, h)
+	}
+}
+
+// Open reloads the templates from disk and shows the picker for the chat
+// with the given history ID.
+func (m *TemplateModel) Open(historyID string) tea.Cmd {
+	m.historyID = historyID
+	m.templates, m.skipped, m.loadErr = templates.Load()
+	m.cursor = 0
+	m.form = nil
+	m.open = true
+	return func() tea.Msg {
+		return messages.SystemPopupStatusMsg{IsOpen: true}
+	}
+}
+
+// OpenNamed reloads the templates and starts the one with the given name,
+// skipping the picker.
+func (m *TemplateModel) OpenNamed(historyID, name string) tea.Cmd {
+	cmd := m.Open(historyID)
+	if m.loadErr != nil {
+		return cmd
+	}
+
+	t, ok := templates.Find(m.templates, name)
+	if !ok {
+		m.open = false
+		return ShowToast(fmt.Sprintf("No template named %s", name), 2*time.Second)
+	}
+	return tea.Batch(cmd, m.start(t))
+}
+
+// IsOpen reports whether the popup is visible.
+func (m *TemplateModel) IsOpen() bool {
+	return m.open
+}
+
+// Update
+func (m *TemplateModel) Update(msg tea.Msg) tea.Cmd {
+	if !m.open {
+		return nil
+	}
+
+	if m.form != nil {
+		cmd := m.form.Update(msg)
+		if !m.form.Done() {
+			return cmd
+		}
+		values := m.form.Values
+		m.form = nil
+		if values == nil {
+			return nil
+		}
+		return tea.Batch(m.close(), renderTemplate(m.historyID, m.selected, values))
+	}
+
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return nil
+	}
+
+	switch key.String() {
+	case "esc":
+		return m.close()
+
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+
+	case "down", "j":
+		if m.cursor < len(m.templates)-1 {
+			m.cursor++
+		}
+
+	case "enter":
+		if len(m.templates) > 0 {
+			return m.start(m.templates[m.cursor])
+		}
+	}
+	return nil
+}
+
+// View
+func (m *TemplateModel) View() string {
+	if !m.open {
+		return ""
+	}
+	if m.form != nil {
+		return m.form.View()
+	}
+
+	var b strings.Builder
+	switch {
+	case m.loadErr != nil:
+		b.WriteString(m.loadErr.Error() + "\n")
+	case len(m.templates) == 0 && len(m.skipped) == 0:
+		dir, _ := templates.Dir()
+		b.WriteString(dimStyle.Render("No templates found.\nAdd *.tmpl files to "+dir) + "\n")
+	}
+
+	for i, t := range m.templates {
+		line := "  " + t.Name
+		if i == m.cursor {
+			line = lipgloss.NewStyle().Bold(true).Render("❯ " + t.Name)
+		}
+		if t.Description != "" {
+			line += dimStyle.Render(" - " + t.Description)
+		}
+		b.WriteString(line + "\n")
+	}
+	for _, err := range m.skipped {
+		b.WriteString(dimStyle.Render("Skipped "+err.Error()) + "\n")
+	}
+	b.WriteString("\nenter = use • esc = close")
+
+	popup := NewPopup("Prompt Templates", b.String(), 64)
+	return popupCentered(m.width, m.height, popup.View())
+}
+
+// Helpers
+
+func (m *TemplateModel) start(t templates.Template) tea.Cmd {
+	m.selected = t
+	if len(t.Vars) == 0 {
+		return tea.Batch(m.close(), renderTemplate(m.historyID, t, nil))
+	}
+
+	fields := make([]FormField, len(t.Vars))
+	for i, v := range t.Vars {
+		label := v.Prompt
+		if label == "" {
+			label = v.Name
+		}
+		fields[i] = FormField{Name: v.Name, Label: label, Value: v.Default}
+	}
+	m.form = NewFormDialog(t.Name, fields...)
+	m.form.SetSize(m.width, m.height)
+	return nil
+}
+
+func (m *TemplateModel) close() tea.Cmd {
+	m.open = false
+	m.form = nil
+	return func() tea.Msg {
+		return messages.SystemPopupStatusMsg{IsOpen: false}
+	}
+}
+
+// renderTemplate renders t in the background, since templates may read
+// files or run shell commands.
+func renderTemplate(historyID string, t templates.Template, values map[string]string) tea.Cmd {
+	return func() tea.Msg {
+		text, err := t.Render(values)
+		return templateRenderedMsg{historyID: historyID, text: text, err: err}
+	}
+}
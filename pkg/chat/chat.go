@@ -1,6 +1,85 @@
 package chat
 
+import (
+	"fmt"
+	"strings"
+)
+
 type Message struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+
+	// Attachments are files mentioned with @path in a user message. They
+	// are kept apart from Content so the chat shows what was typed, and
+	// are inlined only when the message is sent to a model.
+	Attachments []Attachment `json:"attachments,omitempty"`
+
+	// Sources are document chunks retrieved for a user message. They are
+	// sent as context with the message and cited under the answer.
+	Sources []Source `json:"sources,omitempty"`
+
+	// Model and Host name the model that wrote an answer, which differs
+	// from the chat's model when a fallback answered.
+	Model string `json:"model,omitempty"`
+	Host  string `json:"host,omitempty"`
+}
+
+// Attachment is a file attached to a message.
+type Attachment struct {
+	Path     string `json:"path"`
+	Language string `json:"language,omitempty"`
+	Content  string `json:"content"`
+}
+
+// Source is a chunk of an indexed document.
+type Source struct {
+	Collection string  `json:"collection"`
+	Path       string  `json:"path"`
+	Line       int     `json:"line"`
+	Text       string  `json:"text"`
+	Score      float32 `json:"score"`
+}
+
+// Expanded returns the message with its sources and attachments inlined
+// into the content, ready to be sent to a model. Only the role and content
+// are kept; the other fields are llmv's own and not part of the API.
+func (m Message) Expanded() Message {
+	if len(m.Attachments) == 0 && len(m.Sources) == 0 {
+		return Message{Role: m.Role, Content: m.Content}
+	}
+
+	var b strings.Builder
+	if len(m.Sources) > 0 {
+		b.WriteString("Use the numbered context below to answer and cite it as [n] where relevant.\n\n")
+		for i, src := range m.Sources {
+			fmt.Fprintf(&b, "[%d] %s:%d\n%s\n\n", i+1, src.Path, src.Line, strings.TrimSpace(src.Text))
+		}
+		b.WriteString("Question: ")
+	}
+	b.WriteString(m.Content)
+	for _, a := range m.Attachments {
+		fence := codeFence(a.Content)
+		fmt.Fprintf(&b, "\n\nFile: %s\n%s%s\n%s", a.Path, fence, a.Language, a.Content)
+		if !strings.HasSuffix(a.Content, "\n") {
+			b.WriteString("\n")
+		}
+		b.WriteString(fence)
+	}
+
+	return Message{Role: m.Role, Content: b.String()}
+}
+
+// codeFence returns a backtick fence longer than any backtick run in s, so
+// attached Markdown files cannot close the block early.
+func codeFence(s string) string {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
 }
@@ -0,0 +1,84 @@
+package chat
+
+import "strings"
+
+// CodeBlock is a fenced code block in a Markdown text.
+type CodeBlock struct {
+	Language string
+	Code     string
+
+	// Line is the index of the line that opens the block.
+	Line int
+}
+
+// CodeBlocks returns the fenced code blocks of a Markdown text in order.
+// A block that is not closed, e.g. in an answer that is still streaming,
+// runs to the end of the text.
+func CodeBlocks(content string) []CodeBlock {
+	var blocks []CodeBlock
+
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		fence, info, indent, ok := openingFence(lines[i])
+		if !ok {
+			continue
+		}
+
+		block := CodeBlock{Language: info, Line: i}
+		var code []string
+		for i++; i < len(lines); i++ {
+			if isClosingFence(lines[i], fence) {
+				break
+			}
+			code = append(code, unindent(lines[i], indent))
+		}
+		block.Code = strings.Join(code, "\n")
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// openingFence parses a line opening a code block, returning the fence,
+// the first word of the info string and how far the fence is indented.
+func openingFence(line string) (fence, info string, indent int, ok bool) {
+	trimmed := strings.TrimLeft(line, " ")
+	indent = len(line) - len(trimmed)
+	if indent > 3 || len(trimmed) < 3 {
+		return "", "", 0, false
+	}
+
+	c := trimmed[0]
+	if c != '`' && c != '~' {
+		return "", "", 0, false
+	}
+	n := len(trimmed) - len(strings.TrimLeft(trimmed, string(c)))
+	if n < 3 {
+		return "", "", 0, false
+	}
+
+	rest := strings.TrimSpace(trimmed[n:])
+	if c == '`' && strings.Contains(rest, "`") {
+		return "", "", 0, false
+	}
+	if fields := strings.Fields(rest); len(fields) > 0 {
+		info = fields[0]
+	}
+	return trimmed[:n], info, indent, true
+}
+
+// unindent removes up to indent leading spaces from a line of code, as
+// the code of an indented fence is indented along with it.
+func unindent(line string, indent int) string {
+	for i := 0; i < indent && strings.HasPrefix(line, " "); i++ {
+		line = line[1:]
+	}
+	return line
+}
+
+// isClosingFence reports whether line closes a block opened with fence.
+func isClosingFence(line, fence string) bool {
+	trimmed := strings.TrimSpace(line)
+	return len(line)-len(strings.TrimLeft(line, " ")) <= 3 &&
+		strings.HasPrefix(trimmed, fence) &&
+		strings.Trim(trimmed, fence[:1]) == ""
+}
@@ -0,0 +1,89 @@
+package chat
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCodeBlocks(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []CodeBlock
+	}{
+		{
+			name:    "no blocks",
+			content: "just text with `inline` code",
+		},
+		{
+			name:    "backticks",
+			content: "```go\nfmt.Println()\n```",
+			want:    []CodeBlock{{Language: "go", Code: "fmt.Println()", Line: 0}},
+		},
+		{
+			name:    "info string",
+			content: "``` python title=\"x.py\"\nprint()\n```",
+			want:    []CodeBlock{{Language: "python", Code: "print()", Line: 0}},
+		},
+		{
+			name:    "tildes",
+			content: "~~~sh\nls\n~~~",
+			want:    []CodeBlock{{Language: "sh", Code: "ls", Line: 0}},
+		},
+		{
+			name:    "several blocks",
+			content: "a\n```\n1\n```\nb\n~~~js\n2\n\n3\n~~~",
+			want: []CodeBlock{
+				{Code: "1", Line: 1},
+				{Language: "js", Code: "2\n\n3", Line: 5},
+			},
+		},
+		{
+			name:    "backticks nested in tildes",
+			content: "~~~md\n```go\nx\n```\n~~~",
+			want:    []CodeBlock{{Language: "md", Code: "```go\nx\n```", Line: 0}},
+		},
+		{
+			name:    "nested in a longer fence",
+			content: "````md\n```go\nx\n```\n````",
+			want:    []CodeBlock{{Language: "md", Code: "```go\nx\n```", Line: 0}},
+		},
+		{
+			name:    "closing fence with info string",
+			content: "```\nx\n``` go\n```",
+			want:    []CodeBlock{{Code: "x\n``` go", Line: 0}},
+		},
+		{
+			name:    "backticks in info string",
+			content: "```a`b\nx\n```",
+			want:    []CodeBlock{{Line: 2}},
+		},
+		{
+			name:    "indented",
+			content: "  ```go\n  x := 1\n      y\n z\n   ```",
+			want:    []CodeBlock{{Language: "go", Code: "x := 1\n    y\nz", Line: 0}},
+		},
+		{
+			name:    "indented four spaces",
+			content: "    ```go\n    x\n    ```",
+		},
+		{
+			name:    "unterminated",
+			content: "text\n```go\nx\ny",
+			want:    []CodeBlock{{Language: "go", Code: "x\ny", Line: 1}},
+		},
+		{
+			name:    "unterminated empty",
+			content: "```",
+			want:    []CodeBlock{{Line: 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CodeBlocks(tt.content); !slices.Equal(got, tt.want) {
+				t.Errorf("CodeBlocks() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}